@@ -0,0 +1,67 @@
+// Command mockserver chạy mock X API v2 server để test offline.
+//
+//	go run ./cmd/mockserver -addr :8081 -fixtures mockserver/fixtures
+//
+// Sau đó chạy backend với TWITTER_API_BASE_URL=http://localhost:8081. Để thử
+// /auth/login, đặt thêm TWITTER_OAUTH2_CLIENT_ID (bất kỳ), TWITTER_OAUTH2_REDIRECT_URL
+// và TWITTER_OAUTH2_AUTH_URL=http://localhost:8081/i/oauth2/authorize.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+	"time"
+	"x-twitter-backend/mockserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "địa chỉ lắng nghe")
+	fixturesDir := flag.String("fixtures", "mockserver/fixtures", "thư mục chứa users.json, tweets.json, relations.json")
+	token := flag.String("token", "", "nếu khác rỗng, chỉ chấp nhận Bearer token này")
+	tokens := flag.String("tokens", "", "các Bearer token khác được chấp nhận, phân tách bằng dấu phẩy (thử TWITTER_BEARER_TOKENS)")
+	accessToken := flag.String("access-token", "", "nếu khác rỗng, chỉ chấp nhận OAuth 1.0a oauth_token này")
+	clientID := flag.String("oauth2-client-id", "", "nếu khác rỗng, chỉ chấp nhận OAuth 2.0 client_id này")
+	clientSecret := flag.String("oauth2-client-secret", "", "nếu khác rỗng, yêu cầu client xác thực bằng HTTP Basic (confidential client)")
+	oauth2User := flag.String("oauth2-user", "", "username đăng nhập qua /i/oauth2/authorize (mặc định authenticated user của fixtures)")
+	tokenTTL := flag.Duration("oauth2-token-ttl", 2*time.Hour, "thời gian sống của access token OAuth 2.0")
+	window := flag.Duration("rate-limit-window", 15*time.Minute, "độ dài cửa sổ rate limit")
+	flag.Parse()
+
+	fixtures, err := mockserver.LoadFixtures(*fixturesDir)
+	if err != nil {
+		log.WithError(err).Fatal("❌ Không thể load fixtures")
+	}
+
+	var bearerTokens []string
+	for _, t := range strings.Split(*tokens, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			bearerTokens = append(bearerTokens, t)
+		}
+	}
+
+	server := mockserver.New(fixtures, mockserver.Options{
+		BearerToken:     *token,
+		BearerTokens:    bearerTokens,
+		AccessToken:     *accessToken,
+		RateLimitWindow: *window,
+
+		OAuth2ClientID:     *clientID,
+		OAuth2ClientSecret: *clientSecret,
+		OAuth2User:         *oauth2User,
+		OAuth2TokenTTL:     *tokenTTL,
+	})
+
+	log.WithFields(log.Fields{
+		"address":  *addr,
+		"users":    len(fixtures.Users),
+		"tweets":   len(fixtures.Tweets),
+		"fixtures": *fixturesDir,
+	}).Info("🧪 Mock X API server đang lắng nghe...")
+
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.WithError(err).Fatal("❌ Lỗi khi start mock server")
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKey là một API key được phép gọi /api. Key được lưu dạng plaintext (Key) hoặc
+// SHA-256 hex (KeySHA256) để file cấu hình không chứa key thật.
+type APIKey struct {
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+	KeySHA256 string `json:"key_sha256,omitempty"`
+	// Scopes là các route scope được phép, ví dụ tweets:read, users:read, admin; "*" là tất cả
+	Scopes []string `json:"scopes"`
+	// PerMinute và PerDay là số request tối đa mỗi phút, mỗi ngày (UTC); 0 = không giới hạn
+	PerMinute int `json:"per_minute"`
+	PerDay    int `json:"per_day"`
+}
+
+// loadAPIKeys đọc API keys từ file JSON (API_KEYS_FILE) và JSON inline (API_KEYS),
+// cả hai là mảng APIKey, rồi kiểm tra tên và key không trùng nhau
+func loadAPIKeys(file, inline string) ([]APIKey, error) {
+	var keys []APIKey
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("không thể đọc API_KEYS_FILE: %w", err)
+		}
+		var fromFile []APIKey
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("API_KEYS_FILE %s không phải mảng JSON hợp lệ: %w", file, err)
+		}
+		keys = append(keys, fromFile...)
+	}
+	if strings.TrimSpace(inline) != "" {
+		var fromEnv []APIKey
+		if err := json.Unmarshal([]byte(inline), &fromEnv); err != nil {
+			return nil, fmt.Errorf("API_KEYS không phải mảng JSON hợp lệ: %w", err)
+		}
+		keys = append(keys, fromEnv...)
+	}
+
+	names := make(map[string]bool)
+	secrets := make(map[string]bool)
+	for i, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key #%d thiếu name", i+1)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("API key %q bị khai báo hai lần", key.Name)
+		}
+		names[key.Name] = true
+
+		if (key.Key == "") == (key.KeySHA256 == "") {
+			return nil, fmt.Errorf("API key %q cần đúng một trong key hoặc key_sha256", key.Name)
+		}
+		if key.KeySHA256 != "" {
+			if raw, err := hex.DecodeString(key.KeySHA256); err != nil || len(raw) != 32 {
+				return nil, fmt.Errorf("API key %q: key_sha256 phải là 64 ký tự hex", key.Name)
+			}
+			keys[i].KeySHA256 = strings.ToLower(key.KeySHA256)
+		}
+		secret := key.Key + "|" + keys[i].KeySHA256
+		if secrets[secret] {
+			return nil, fmt.Errorf("API key %q trùng key với một key khác", key.Name)
+		}
+		secrets[secret] = true
+
+		if len(key.Scopes) == 0 {
+			return nil, fmt.Errorf("API key %q cần ít nhất một scope", key.Name)
+		}
+		if key.PerMinute < 0 || key.PerDay < 0 {
+			return nil, fmt.Errorf("API key %q: per_minute và per_day phải >= 0", key.Name)
+		}
+	}
+	return keys, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
@@ -13,6 +15,33 @@ import (
 type Config struct {
 	// Twitter API
 	TwitterBearerToken string
+	// TwitterBearerTokens là Bearer Token của các X app khác, dùng luân phiên với
+	// TwitterBearerToken để cộng dồn rate limit (xem BearerTokens)
+	TwitterBearerTokens []string
+	// TwitterTokenQuarantine là thời gian bỏ qua một Bearer Token sau khi X API trả
+	// 401/403 cho token đó (0 = không cách ly)
+	TwitterTokenQuarantine time.Duration
+	// OAuth 1.0a user context (consumer key/secret của app và access token/secret của
+	// user) cho các API cần authenticated user: blocking, muting, hide reply,
+	// reposts_of_me, users/me. Bỏ trống cả bốn nếu chỉ dùng Bearer Token.
+	TwitterConsumerKey       string
+	TwitterConsumerSecret    string
+	TwitterAccessToken       string
+	TwitterAccessTokenSecret string
+	// TwitterAPIBaseURL cho phép trỏ client sang server khác (ví dụ mock server)
+	TwitterAPIBaseURL string
+	// TwitterHTTPMode: live (mặc định), record (ghi responses vào cassettes)
+	// hoặc replay (chỉ phục vụ từ cassettes, không gọi network)
+	TwitterHTTPMode    string
+	TwitterCassetteDir string
+	// Retry khi X API lỗi tạm thời (429, 5xx, lỗi network); MaxAttempts tính cả lần gọi đầu
+	TwitterRetryMaxAttempts int
+	TwitterRetryBaseDelay   time.Duration
+	TwitterRetryMaxDelay    time.Duration
+	// Circuit breaker theo endpoint family: mở sau BreakerThreshold lỗi liên tiếp
+	// (0 = tắt), thử lại sau BreakerCooldown
+	TwitterBreakerThreshold int
+	TwitterBreakerCooldown  time.Duration
 
 	// Server
 	ServerPort string
@@ -25,6 +54,94 @@ type Config struct {
 	// Rate Limiting
 	MaxTweetsPerRequest  int
 	DefaultTweetsCount   int
+
+	// Response cache: CacheBackend là memory (mỗi process một LRU, CacheMaxEntries = 0
+	// tắt cache) hoặc redis (dùng chung giữa các replicas); TTL = 0 tắt cache cho nhóm đó
+	CacheBackend     string
+	CacheMaxEntries  int
+	CacheTTLProfile  time.Duration
+	CacheTTLTimeline time.Duration
+	CacheTTLSearch   time.Duration
+	CacheTTLCounts   time.Duration
+
+	// Kết nối Redis (hoặc server tương thích Redis protocol) khi CacheBackend = redis
+	CacheRedisAddr      string
+	CacheRedisPassword  string
+	CacheRedisDB        int
+	CacheRedisKeyPrefix string
+	CacheRedisTimeout   time.Duration
+	CacheRedisPoolSize  int
+
+	// Cache username -> user ID cho các method theo username; TTL = 0 tắt cache.
+	// Profile trong cache này không sống lâu hơn CacheTTLProfile.
+	UserCacheMaxEntries int
+	UserCacheTTL        time.Duration
+
+	// Gộp các call giống hệt nhau đang chạy đồng thời thành một upstream request
+	CoalesceRequests bool
+
+	// Auto-pagination (all=true): số items tối đa một request được gộp (cũng là
+	// max_items mặc định) và thời gian tối đa trước khi trả về cursor để gọi tiếp;
+	// PaginateStreamTimeout áp dụng cho response NDJSON
+	PaginateMaxItems      int
+	PaginateTimeout       time.Duration
+	PaginateStreamTimeout time.Duration
+
+	// OAuth 2.0 Authorization Code with PKCE cho end users (/auth/login): để trống
+	// OAuth2ClientID thì tắt. ClientSecret chỉ cần với confidential client.
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	OAuth2RedirectURL  string
+	OAuth2Scopes       string
+	OAuth2AuthURL      string
+	OAuth2TokenURL     string
+	OAuth2RevokeURL    string
+
+	// Session lưu access/refresh token phía server (cùng backend với response cache);
+	// access token được refresh khi còn ít hơn SessionRefreshBefore trước khi hết hạn
+	SessionTTL           time.Duration
+	SessionRefreshBefore time.Duration
+	SessionMaxEntries    int
+	SessionCookieName    string
+	SessionCookieSecure  bool
+
+	// API keys cho /api (API_KEYS_FILE và/hoặc API_KEYS): để trống thì /api không yêu
+	// cầu xác thực. Key được gửi qua header APIKeyHeader hoặc query APIKeyQueryParam
+	// (đặt API_KEY_QUERY_PARAM= rỗng để không nhận key qua query).
+	APIKeys          []APIKey
+	APIKeyHeader     string
+	APIKeyQueryParam string
+
+	// JWT cho /api (JWT_JWKS_FILE hoặc JWT_JWKS_URL): client gửi Authorization: Bearer
+	// <JWT> ký bằng RS256/ES256, cùng với API keys (request không có Bearer token dùng
+	// API key) hoặc thay cho API keys
+	JWTJWKSFile string
+	JWTJWKSURL  string
+	// JWTJWKSRefresh là chu kỳ tải lại JWKS từ JWTJWKSURL
+	JWTJWKSRefresh time.Duration
+	JWTIssuer      string
+	JWTAudience    string
+	// JWTLeeway là độ lệch đồng hồ cho phép khi kiểm tra exp và nbf
+	JWTLeeway time.Duration
+	// JWTScopeClaim là claim chứa scopes (chuỗi phân tách bằng khoảng trắng hoặc mảng)
+	JWTScopeClaim string
+	// JWTScopeMap ánh xạ giá trị trong JWTScopeClaim sang route scope (JWT_SCOPE_MAP,
+	// ví dụ "x-fetch.read=tweets:read,x-fetch.read=users:read"); giá trị trùng tên
+	// route scope được dùng trực tiếp
+	JWTScopeMap map[string][]string
+
+	// CORSAllowedOrigins là các origin được gọi API từ trình duyệt; "*" cho phép mọi origin
+	CORSAllowedOrigins []string
+
+	// Giới hạn request vào /api theo từng nhóm route (RATE_LIMIT_DEFAULT, _SEARCH, _WRITE,
+	// _ADMIN), tính riêng cho mỗi API key/JWT subject hoặc mỗi client IP
+	RateLimitDefault RateLimit
+	RateLimitSearch  RateLimit
+	RateLimitWrite   RateLimit
+	RateLimitAdmin   RateLimit
+	// TrustedProxies là IP/CIDR của reverse proxy được tin X-Forwarded-For; request từ
+	// địa chỉ khác dùng RemoteAddr làm client IP
+	TrustedProxies []string
 }
 
 var AppConfig *Config
@@ -58,18 +175,200 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := &Config{
-		TwitterBearerToken:  getEnv("TWITTER_BEARER_TOKEN", ""),
-		ServerPort:          getEnv("SERVER_PORT", "8080"),
-		ServerHost:          serverHost,
-		AppEnv:              getEnv("APP_ENV", "development"),
-		LogLevel:            getEnv("LOG_LEVEL", "info"),
-		MaxTweetsPerRequest: getEnvAsInt("MAX_TWEETS_PER_REQUEST", 100),
-		DefaultTweetsCount:  getEnvAsInt("DEFAULT_TWEETS_COUNT", 10),
+		TwitterBearerToken:      getEnv("TWITTER_BEARER_TOKEN", ""),
+		TwitterBearerTokens:     getEnvAsList("TWITTER_BEARER_TOKENS"),
+		TwitterTokenQuarantine:  getEnvAsDuration("TWITTER_TOKEN_QUARANTINE", 15*time.Minute),
+		TwitterConsumerKey:       getEnv("TWITTER_CONSUMER_KEY", ""),
+		TwitterConsumerSecret:    getEnv("TWITTER_CONSUMER_SECRET", ""),
+		TwitterAccessToken:       getEnv("TWITTER_ACCESS_TOKEN", ""),
+		TwitterAccessTokenSecret: getEnv("TWITTER_ACCESS_TOKEN_SECRET", ""),
+		TwitterAPIBaseURL:       getEnv("TWITTER_API_BASE_URL", "https://api.twitter.com"),
+		TwitterHTTPMode:         getEnv("TWITTER_HTTP_MODE", "live"),
+		TwitterCassetteDir:      getEnv("TWITTER_CASSETTE_DIR", "testdata/cassettes"),
+		TwitterRetryMaxAttempts: getEnvAsInt("TWITTER_RETRY_MAX_ATTEMPTS", 3),
+		TwitterRetryBaseDelay:   getEnvAsDuration("TWITTER_RETRY_BASE_DELAY", 500*time.Millisecond),
+		TwitterRetryMaxDelay:    getEnvAsDuration("TWITTER_RETRY_MAX_DELAY", 10*time.Second),
+		TwitterBreakerThreshold: getEnvAsInt("TWITTER_BREAKER_THRESHOLD", 5),
+		TwitterBreakerCooldown:  getEnvAsDuration("TWITTER_BREAKER_COOLDOWN", 30*time.Second),
+		ServerPort:              getEnv("SERVER_PORT", "8080"),
+		ServerHost:              serverHost,
+		AppEnv:                  getEnv("APP_ENV", "development"),
+		LogLevel:                getEnv("LOG_LEVEL", "info"),
+		MaxTweetsPerRequest:     getEnvAsInt("MAX_TWEETS_PER_REQUEST", 100),
+		DefaultTweetsCount:      getEnvAsInt("DEFAULT_TWEETS_COUNT", 10),
+		CacheBackend:            getEnv("CACHE_BACKEND", "memory"),
+		CacheMaxEntries:         getEnvAsInt("CACHE_MAX_ENTRIES", 1000),
+		CacheTTLProfile:         getEnvAsDuration("CACHE_TTL_PROFILE", 5*time.Minute),
+		CacheTTLTimeline:        getEnvAsDuration("CACHE_TTL_TIMELINE", time.Minute),
+		CacheTTLSearch:          getEnvAsDuration("CACHE_TTL_SEARCH", 30*time.Second),
+		CacheTTLCounts:          getEnvAsDuration("CACHE_TTL_COUNTS", 5*time.Minute),
+		CacheRedisAddr:          getEnv("CACHE_REDIS_ADDR", "localhost:6379"),
+		CacheRedisPassword:      getEnv("CACHE_REDIS_PASSWORD", ""),
+		CacheRedisDB:            getEnvAsInt("CACHE_REDIS_DB", 0),
+		CacheRedisKeyPrefix:     getEnv("CACHE_REDIS_KEY_PREFIX", "x-fetch:"),
+		CacheRedisTimeout:       getEnvAsDuration("CACHE_REDIS_TIMEOUT", 500*time.Millisecond),
+		CacheRedisPoolSize:      getEnvAsInt("CACHE_REDIS_POOL_SIZE", 10),
+		UserCacheMaxEntries:     getEnvAsInt("USER_CACHE_MAX_ENTRIES", 10000),
+		UserCacheTTL:            getEnvAsDuration("USER_CACHE_TTL", 24*time.Hour),
+		CoalesceRequests:        getEnvAsBool("COALESCE_REQUESTS", true),
+		PaginateMaxItems:        getEnvAsInt("PAGINATE_MAX_ITEMS", 10000),
+		PaginateTimeout:         getEnvAsDuration("PAGINATE_TIMEOUT", 10*time.Second),
+		PaginateStreamTimeout:   getEnvAsDuration("PAGINATE_STREAM_TIMEOUT", 5*time.Minute),
+		OAuth2ClientID:          getEnv("TWITTER_OAUTH2_CLIENT_ID", ""),
+		OAuth2ClientSecret:      getEnv("TWITTER_OAUTH2_CLIENT_SECRET", ""),
+		OAuth2RedirectURL:       getEnv("TWITTER_OAUTH2_REDIRECT_URL", ""),
+		OAuth2Scopes:            getEnv("TWITTER_OAUTH2_SCOPES", "tweet.read users.read block.read mute.read tweet.moderate.write offline.access"),
+		OAuth2AuthURL:           getEnv("TWITTER_OAUTH2_AUTH_URL", "https://twitter.com/i/oauth2/authorize"),
+		SessionTTL:              getEnvAsDuration("SESSION_TTL", 30*24*time.Hour),
+		SessionRefreshBefore:    getEnvAsDuration("SESSION_REFRESH_BEFORE", 5*time.Minute),
+		SessionMaxEntries:       getEnvAsInt("SESSION_MAX_ENTRIES", 10000),
+		SessionCookieName:       getEnv("SESSION_COOKIE_NAME", "x_session"),
+		APIKeyHeader:            getEnv("API_KEY_HEADER", "X-API-Key"),
+		APIKeyQueryParam:        os.Getenv("API_KEY_QUERY_PARAM"),
+		CORSAllowedOrigins:      getEnvAsList("CORS_ALLOWED_ORIGINS"),
+		JWTJWKSFile:             os.Getenv("JWT_JWKS_FILE"),
+		JWTJWKSURL:              os.Getenv("JWT_JWKS_URL"),
+		JWTJWKSRefresh:          getEnvAsDuration("JWT_JWKS_REFRESH", time.Hour),
+		JWTIssuer:               os.Getenv("JWT_ISSUER"),
+		JWTAudience:             os.Getenv("JWT_AUDIENCE"),
+		JWTLeeway:               getEnvAsDuration("JWT_LEEWAY", 30*time.Second),
+		JWTScopeClaim:           getEnv("JWT_SCOPE_CLAIM", "scope"),
+	}
+	if _, ok := os.LookupEnv("API_KEY_QUERY_PARAM"); !ok {
+		config.APIKeyQueryParam = "api_key"
 	}
+	if len(config.CORSAllowedOrigins) == 0 {
+		config.CORSAllowedOrigins = []string{"*"}
+	}
+
+	// Token và revoke endpoint mặc định đi theo TWITTER_API_BASE_URL để mock server
+	// đóng vai authorization server khi chạy offline
+	apiBaseURL := strings.TrimRight(config.TwitterAPIBaseURL, "/")
+	config.OAuth2TokenURL = getEnv("TWITTER_OAUTH2_TOKEN_URL", apiBaseURL+"/2/oauth2/token")
+	config.OAuth2RevokeURL = getEnv("TWITTER_OAUTH2_REVOKE_URL", apiBaseURL+"/2/oauth2/revoke")
+	// Cookie Secure mặc định bật ngoài development (local thường chạy http)
+	config.SessionCookieSecure = getEnvAsBool("SESSION_COOKIE_SECURE", config.AppEnv != "development")
 
 	// Validate required fields
+	if len(config.BearerTokens()) == 0 {
+		return nil, fmt.Errorf("TWITTER_BEARER_TOKEN hoặc TWITTER_BEARER_TOKENS là bắt buộc")
+	}
 	if config.TwitterBearerToken == "" {
-		return nil, fmt.Errorf("TWITTER_BEARER_TOKEN là bắt buộc")
+		config.TwitterBearerToken = config.TwitterBearerTokens[0]
+	}
+	if config.TwitterTokenQuarantine < 0 {
+		return nil, fmt.Errorf("TWITTER_TOKEN_QUARANTINE không được âm")
+	}
+
+	// OAuth 1.0a cần đủ cả bốn giá trị; thiếu một phần thường là lỗi cấu hình
+	userContext := []struct{ key, value string }{
+		{"TWITTER_CONSUMER_KEY", config.TwitterConsumerKey},
+		{"TWITTER_CONSUMER_SECRET", config.TwitterConsumerSecret},
+		{"TWITTER_ACCESS_TOKEN", config.TwitterAccessToken},
+		{"TWITTER_ACCESS_TOKEN_SECRET", config.TwitterAccessTokenSecret},
+	}
+	var missing []string
+	for _, env := range userContext {
+		if env.value == "" {
+			missing = append(missing, env.key)
+		}
+	}
+	if len(missing) > 0 && len(missing) < len(userContext) {
+		return nil, fmt.Errorf("OAuth 1.0a user context cần đủ TWITTER_CONSUMER_KEY, TWITTER_CONSUMER_SECRET, TWITTER_ACCESS_TOKEN và TWITTER_ACCESS_TOKEN_SECRET, còn thiếu: %s", strings.Join(missing, ", "))
+	}
+
+	switch config.TwitterHTTPMode {
+	case "live", "record", "replay":
+	default:
+		return nil, fmt.Errorf("TWITTER_HTTP_MODE không hợp lệ: %s (chỉ hỗ trợ live, record, replay)", config.TwitterHTTPMode)
+	}
+
+	if config.TwitterRetryMaxAttempts < 1 {
+		return nil, fmt.Errorf("TWITTER_RETRY_MAX_ATTEMPTS phải >= 1: %d", config.TwitterRetryMaxAttempts)
+	}
+
+	if config.TwitterRetryBaseDelay < 0 {
+		return nil, fmt.Errorf("TWITTER_RETRY_BASE_DELAY phải >= 0: %s", config.TwitterRetryBaseDelay)
+	}
+
+	if config.TwitterRetryMaxDelay < 0 {
+		return nil, fmt.Errorf("TWITTER_RETRY_MAX_DELAY phải >= 0: %s", config.TwitterRetryMaxDelay)
+	}
+
+	if config.TwitterRetryBaseDelay > config.TwitterRetryMaxDelay {
+		return nil, fmt.Errorf("TWITTER_RETRY_BASE_DELAY (%s) không được lớn hơn TWITTER_RETRY_MAX_DELAY (%s)", config.TwitterRetryBaseDelay, config.TwitterRetryMaxDelay)
+	}
+
+	switch config.CacheBackend {
+	case "memory":
+	case "redis":
+		if config.CacheRedisAddr == "" {
+			return nil, fmt.Errorf("CACHE_REDIS_ADDR là bắt buộc khi CACHE_BACKEND=redis")
+		}
+		if config.CacheRedisTimeout <= 0 {
+			return nil, fmt.Errorf("CACHE_REDIS_TIMEOUT phải > 0: %s", config.CacheRedisTimeout)
+		}
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND không hợp lệ: %s (chỉ hỗ trợ memory, redis)", config.CacheBackend)
+	}
+
+	if config.PaginateMaxItems < 1 {
+		return nil, fmt.Errorf("PAGINATE_MAX_ITEMS phải >= 1: %d", config.PaginateMaxItems)
+	}
+
+	if config.PaginateTimeout <= 0 {
+		return nil, fmt.Errorf("PAGINATE_TIMEOUT phải > 0: %s", config.PaginateTimeout)
+	}
+
+	if config.PaginateStreamTimeout <= 0 {
+		return nil, fmt.Errorf("PAGINATE_STREAM_TIMEOUT phải > 0: %s", config.PaginateStreamTimeout)
+	}
+
+	if config.HasOAuth2() {
+		if config.OAuth2RedirectURL == "" {
+			return nil, fmt.Errorf("TWITTER_OAUTH2_REDIRECT_URL là bắt buộc khi cấu hình TWITTER_OAUTH2_CLIENT_ID")
+		}
+		if config.SessionTTL <= 0 {
+			return nil, fmt.Errorf("SESSION_TTL phải > 0: %s", config.SessionTTL)
+		}
+		if config.SessionRefreshBefore < 0 {
+			return nil, fmt.Errorf("SESSION_REFRESH_BEFORE phải >= 0: %s", config.SessionRefreshBefore)
+		}
+		if config.CacheBackend == "memory" && config.SessionMaxEntries < 1 {
+			return nil, fmt.Errorf("SESSION_MAX_ENTRIES phải >= 1: %d", config.SessionMaxEntries)
+		}
+	}
+
+	apiKeys, err := loadAPIKeys(os.Getenv("API_KEYS_FILE"), os.Getenv("API_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	config.APIKeys = apiKeys
+
+	if err := loadRateLimits(config); err != nil {
+		return nil, err
+	}
+
+	scopeMap, err := parseScopeMap(getEnvAsList("JWT_SCOPE_MAP"))
+	if err != nil {
+		return nil, err
+	}
+	config.JWTScopeMap = scopeMap
+
+	if config.JWTJWKSFile != "" && config.JWTJWKSURL != "" {
+		return nil, fmt.Errorf("chỉ cấu hình một trong JWT_JWKS_FILE hoặc JWT_JWKS_URL")
+	}
+	if config.HasJWT() {
+		// Không kiểm tra iss/aud thì mọi JWT do cùng key ký (cho service khác) đều được nhận
+		if config.JWTIssuer == "" || config.JWTAudience == "" {
+			return nil, fmt.Errorf("JWT_ISSUER và JWT_AUDIENCE là bắt buộc khi cấu hình JWT_JWKS_FILE hoặc JWT_JWKS_URL")
+		}
+		if config.JWTLeeway < 0 {
+			return nil, fmt.Errorf("JWT_LEEWAY phải >= 0: %s", config.JWTLeeway)
+		}
+		if config.JWTJWKSRefresh <= 0 {
+			return nil, fmt.Errorf("JWT_JWKS_REFRESH phải > 0: %s", config.JWTJWKSRefresh)
+		}
 	}
 
 	AppConfig = config
@@ -99,6 +398,116 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsDuration đọc environment variable dạng duration (ví dụ 500ms, 10s)
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		log.Warnf("Không thể parse %s thành duration, sử dụng giá trị mặc định: %s", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsBool đọc environment variable dạng boolean (true/false, 1/0)
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Warnf("Không thể parse %s thành boolean, sử dụng giá trị mặc định: %t", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsList đọc environment variable dạng danh sách phân tách bằng dấu phẩy,
+// bỏ khoảng trắng và phần tử rỗng
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// BearerTokens trả về TwitterBearerToken và TwitterBearerTokens theo thứ tự, bỏ trùng
+func (c *Config) BearerTokens() []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, token := range append([]string{c.TwitterBearerToken}, c.TwitterBearerTokens...) {
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// HasUserContext cho biết đã cấu hình OAuth 1.0a user context hay chưa
+func (c *Config) HasUserContext() bool {
+	return c.TwitterConsumerKey != "" && c.TwitterConsumerSecret != "" &&
+		c.TwitterAccessToken != "" && c.TwitterAccessTokenSecret != ""
+}
+
+// parseScopeMap parse các cặp claim=scope của JWT_SCOPE_MAP; một giá trị claim có thể
+// xuất hiện nhiều lần để được nhiều scope
+func parseScopeMap(pairs []string) (map[string][]string, error) {
+	scopeMap := make(map[string][]string)
+	for _, pair := range pairs {
+		value, scope, ok := strings.Cut(pair, "=")
+		value, scope = strings.TrimSpace(value), strings.TrimSpace(scope)
+		if !ok || value == "" || scope == "" {
+			return nil, fmt.Errorf("JWT_SCOPE_MAP không hợp lệ: %q (cần dạng claim=scope)", pair)
+		}
+		scopeMap[value] = append(scopeMap[value], scope)
+	}
+	return scopeMap, nil
+}
+
+// HasJWT cho biết /api có nhận JWT hay không
+func (c *Config) HasJWT() bool {
+	return c.JWTJWKSFile != "" || c.JWTJWKSURL != ""
+}
+
+// RateLimits trả về giới hạn của các nhóm route theo tên nhóm (default, search, write, admin)
+func (c *Config) RateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		"default": c.RateLimitDefault,
+		"search":  c.RateLimitSearch,
+		"write":   c.RateLimitWrite,
+		"admin":   c.RateLimitAdmin,
+	}
+}
+
+// RateLimitEnabled cho biết có nhóm route nào bị giới hạn request hay không
+func (c *Config) RateLimitEnabled() bool {
+	for _, limit := range c.RateLimits() {
+		if limit.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAPIKeys cho biết /api có yêu cầu API key hay không
+func (c *Config) HasAPIKeys() bool {
+	return len(c.APIKeys) > 0
+}
+
+// HasOAuth2 cho biết đã cấu hình đăng nhập OAuth 2.0 cho end users hay chưa
+func (c *Config) HasOAuth2() bool {
+	return c.OAuth2ClientID != ""
+}
+
 // GetAddress trả về địa chỉ server đầy đủ
 func (c *Config) GetAddress() string {
 	return fmt.Sprintf("%s:%s", c.ServerHost, c.ServerPort)
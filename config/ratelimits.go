@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit là giới hạn token bucket cho request vào /api: mỗi client có tối đa Burst
+// request liên tiếp, được nạp lại Requests request mỗi Period. Requests = 0 là không
+// giới hạn.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Enabled cho biết nhóm route có bị giới hạn hay không
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0
+}
+
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s:%d", l.Requests, l.Period, l.Burst)
+}
+
+// getEnvAsRateLimit đọc giới hạn dạng "N/period[:burst]", ví dụ "60/m", "10/1s:20";
+// period là s, m, h hoặc duration; burst mặc định bằng N. "off" hoặc "0" tắt giới hạn.
+func getEnvAsRateLimit(key, defaultValue string) (RateLimit, error) {
+	value := strings.TrimSpace(getEnv(key, defaultValue))
+	if value == "off" || value == "0" {
+		return RateLimit{}, nil
+	}
+
+	spec, burstStr, hasBurst := strings.Cut(value, ":")
+	requestsStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%s không hợp lệ: %q (cần dạng N/period[:burst], ví dụ 60/m)", key, value)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsStr))
+	if err != nil || requests < 1 {
+		return RateLimit{}, fmt.Errorf("%s: số request phải là số nguyên >= 1: %q", key, requestsStr)
+	}
+
+	periodStr = strings.TrimSpace(periodStr)
+	switch periodStr {
+	case "s", "m", "h":
+		periodStr = "1" + periodStr
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("%s: period không hợp lệ: %q", key, periodStr)
+	}
+
+	burst := requests
+	if hasBurst {
+		burst, err = strconv.Atoi(strings.TrimSpace(burstStr))
+		if err != nil || burst < 1 {
+			return RateLimit{}, fmt.Errorf("%s: burst phải là số nguyên >= 1: %q", key, burstStr)
+		}
+	}
+	return RateLimit{Requests: requests, Period: period, Burst: burst}, nil
+}
+
+// loadRateLimits đọc giới hạn của từng nhóm route và danh sách trusted proxies.
+// Giới hạn request là opt-in: RATE_LIMIT_DEFAULT mặc định là off, và các nhóm search,
+// write, admin chỉ có giới hạn mặc định khi RATE_LIMIT_DEFAULT được bật; đặt riêng
+// từng biến vẫn luôn có hiệu lực.
+func loadRateLimits(c *Config) error {
+	var err error
+	if c.RateLimitDefault, err = getEnvAsRateLimit("RATE_LIMIT_DEFAULT", "off"); err != nil {
+		return err
+	}
+
+	limits := []struct {
+		key, defaultValue string
+		dst               *RateLimit
+	}{
+		{"RATE_LIMIT_SEARCH", "30/m", &c.RateLimitSearch},
+		{"RATE_LIMIT_WRITE", "30/m", &c.RateLimitWrite},
+		{"RATE_LIMIT_ADMIN", "60/m", &c.RateLimitAdmin},
+	}
+	for _, limit := range limits {
+		defaultValue := limit.defaultValue
+		if !c.RateLimitDefault.Enabled() {
+			defaultValue = "off"
+		}
+		if *limit.dst, err = getEnvAsRateLimit(limit.key, defaultValue); err != nil {
+			return err
+		}
+	}
+
+	c.TrustedProxies = getEnvAsList("TRUSTED_PROXIES")
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err == nil {
+			continue
+		}
+		if net.ParseIP(proxy) == nil {
+			return fmt.Errorf("TRUSTED_PROXIES: %q không phải IP hoặc CIDR", proxy)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PrincipalAPIKey là Principal.Kind của request xác thực bằng API key
+const PrincipalAPIKey = "api_key"
+
+var apiKeyRequests = metrics.NewCounter("x_api_key_requests_total",
+	"Số request /api theo API key và kết quả xác thực (ok, unauthorized, forbidden, quota_exceeded)",
+	"key", "result")
+
+// quotaWindow đếm request trong một cửa sổ cố định (phút hoặc ngày UTC)
+type quotaWindow struct {
+	limit int // 0 = không giới hạn
+	start time.Time
+	count int
+}
+
+// quotaStatus là trạng thái sau khi tính request hiện tại vào cửa sổ
+type quotaStatus struct {
+	limit     int
+	remaining int
+	resetAt   time.Time
+}
+
+// apiKey là API key đã cấu hình cùng bộ đếm quota của nó
+type apiKey struct {
+	principal *Principal
+
+	mu     sync.Mutex
+	minute quotaWindow
+	day    quotaWindow
+}
+
+// take tính request vào quota phút và ngày; exceeded là cửa sổ đã hết ("minute" hoặc
+// "day", request không được tính) hoặc rỗng nếu request được phép
+func (k *apiKey) take(now time.Time) (minute, day quotaStatus, exceeded string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	minuteStart := now.UTC().Truncate(time.Minute)
+	dayStart := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	if !k.minute.start.Equal(minuteStart) {
+		k.minute.start, k.minute.count = minuteStart, 0
+	}
+	if !k.day.start.Equal(dayStart) {
+		k.day.start, k.day.count = dayStart, 0
+	}
+
+	minute = quotaStatus{limit: k.minute.limit, resetAt: minuteStart.Add(time.Minute)}
+	day = quotaStatus{limit: k.day.limit, resetAt: dayStart.AddDate(0, 0, 1)}
+	switch {
+	case day.limit > 0 && k.day.count >= day.limit:
+		exceeded = "day"
+	case minute.limit > 0 && k.minute.count >= minute.limit:
+		exceeded = "minute"
+	default:
+		k.minute.count++
+		k.day.count++
+	}
+	minute.remaining = max(minute.limit-k.minute.count, 0)
+	day.remaining = max(day.limit-k.day.count, 0)
+	return minute, day, exceeded
+}
+
+// APIKeyAuth yêu cầu API key hợp lệ cho các route /api (trừ /api/docs), kiểm tra
+// route scope và quota mỗi phút/mỗi ngày của key. Quota được đếm trong bộ nhớ của
+// từng instance.
+type APIKeyAuth struct {
+	keys       map[string]*apiKey // SHA-256 hex của key -> key
+	header     string
+	queryParam string
+	now        func() time.Time
+}
+
+// NewAPIKeyAuth tạo APIKeyAuth từ keys đã cấu hình; header và queryParam là nơi client
+// gửi key (queryParam rỗng = chỉ nhận qua header)
+func NewAPIKeyAuth(keys []config.APIKey, header, queryParam string) (*APIKeyAuth, error) {
+	a := &APIKeyAuth{
+		keys:       make(map[string]*apiKey, len(keys)),
+		header:     header,
+		queryParam: queryParam,
+		now:        time.Now,
+	}
+	for _, key := range keys {
+		for _, scope := range key.Scopes {
+			if !knownScopes[scope] {
+				return nil, fmt.Errorf("API key %q có scope không hợp lệ: %q", key.Name, scope)
+			}
+		}
+		hash := key.KeySHA256
+		if hash == "" {
+			hash = hashAPIKey(key.Key)
+		}
+		a.keys[hash] = &apiKey{
+			principal: &Principal{Kind: PrincipalAPIKey, Name: key.Name, Scopes: key.Scopes},
+			minute:    quotaWindow{limit: key.PerMinute},
+			day:       quotaWindow{limit: key.PerDay},
+		}
+	}
+	return a, nil
+}
+
+// GrantsScope cho biết có API key nào được gọi route cần scope không
+func (a *APIKeyAuth) GrantsScope(scope string) bool {
+	for _, key := range a.keys {
+		if key.principal.Allows(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware xác thực request bằng API key
+func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, r := a.extractKey(r)
+
+		// Request đã được xác thực bằng cơ chế khác (JWT) thì không cần API key
+		scope := routeScope(r)
+		if r.Method == http.MethodOptions || scope == "" || PrincipalFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if raw == "" {
+			apiKeyRequests.Inc("", "unauthorized")
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`APIKey header="%s"`, a.header))
+			writeError(w, r, apiError{
+				status:  http.StatusUnauthorized,
+				code:    "API_KEY_REQUIRED",
+				message: a.missingKeyMessage(),
+			})
+			return
+		}
+
+		key, ok := a.keys[hashAPIKey(raw)]
+		if !ok {
+			apiKeyRequests.Inc("", "unauthorized")
+			log.WithField("request_id", RequestIDFromContext(r.Context())).Warn("API key không hợp lệ")
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`APIKey header="%s", error="invalid_key"`, a.header))
+			writeError(w, r, apiError{
+				status:  http.StatusUnauthorized,
+				code:    "INVALID_API_KEY",
+				message: "API key không hợp lệ",
+			})
+			return
+		}
+
+		principal := key.principal
+		addLogFields(r.Context(), log.Fields{"api_key": principal.Name})
+
+		if !principal.Allows(scope) {
+			apiKeyRequests.Inc(principal.Name, "forbidden")
+			writeError(w, r, apiError{
+				status:  http.StatusForbidden,
+				code:    "INSUFFICIENT_SCOPE",
+				message: fmt.Sprintf("API key %s không có scope %s cho route này", principal.Name, scope),
+			})
+			return
+		}
+
+		now := a.now()
+		minute, day, exceeded := key.take(now)
+		setQuotaHeaders(w.Header(), "Minute", minute)
+		setQuotaHeaders(w.Header(), "Day", day)
+		if exceeded != "" {
+			apiKeyRequests.Inc(principal.Name, "quota_exceeded")
+			window, status := "mỗi phút", minute
+			if exceeded == "day" {
+				window, status = "mỗi ngày", day
+			}
+			retryAfter := int(status.resetAt.Sub(now).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, r, apiError{
+				status:  http.StatusTooManyRequests,
+				code:    "QUOTA_EXCEEDED",
+				message: fmt.Sprintf("API key %s đã dùng hết quota %s (%d requests), thử lại sau %d giây", principal.Name, window, status.limit, retryAfter),
+			})
+			return
+		}
+
+		apiKeyRequests.Inc(principal.Name, "ok")
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+// extractKey đọc key từ header, sau đó query. Key trong query bị xóa khỏi URL của
+// request (và khỏi query trong log) để không lọt vào cache key, error response hay logs.
+func (a *APIKeyAuth) extractKey(r *http.Request) (string, *http.Request) {
+	key := r.Header.Get(a.header)
+	if a.queryParam == "" {
+		return key, r
+	}
+
+	query := r.URL.Query()
+	if !query.Has(a.queryParam) {
+		return key, r
+	}
+	if key == "" {
+		key = query.Get(a.queryParam)
+	}
+	query.Del(a.queryParam)
+
+	out := r.Clone(r.Context())
+	out.URL.RawQuery = query.Encode()
+	out.RequestURI = out.URL.RequestURI()
+	addLogFields(r.Context(), log.Fields{"query": out.URL.RawQuery})
+	return key, out
+}
+
+func (a *APIKeyAuth) missingKeyMessage() string {
+	if a.queryParam == "" {
+		return fmt.Sprintf("Thiếu API key: gửi key qua header %s", a.header)
+	}
+	return fmt.Sprintf("Thiếu API key: gửi key qua header %s hoặc query parameter %s", a.header, a.queryParam)
+}
+
+// setQuotaHeaders gắn X-RateLimit-Limit-<window> và X-RateLimit-Remaining-<window>
+// khi key có giới hạn cho cửa sổ đó
+func setQuotaHeaders(h http.Header, window string, status quotaStatus) {
+	if status.limit <= 0 {
+		return
+	}
+	h.Set("X-RateLimit-Limit-"+window, strconv.Itoa(status.limit))
+	h.Set("X-RateLimit-Remaining-"+window, strconv.Itoa(status.remaining))
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/models"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// logCapture giữ lại các log entries để kiểm tra fields
+type logCapture struct {
+	mu      sync.Mutex
+	entries []*log.Entry
+}
+
+func (c *logCapture) Levels() []log.Level { return log.AllLevels }
+
+func (c *logCapture) Fire(e *log.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, e)
+	return nil
+}
+
+func (c *logCapture) requestLogs() []log.Fields {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var fields []log.Fields
+	for _, e := range c.entries {
+		if e.Message == "HTTP Request" {
+			fields = append(fields, e.Data)
+		}
+	}
+	return fields
+}
+
+// newAPIKeyTestRouter là router test với APIKeyAuth; now cố định để quota không phụ
+// thuộc vào thời điểm chạy test
+func newAPIKeyTestRouter(t *testing.T, now *time.Time, keys ...config.APIKey) *mux.Router {
+	t.Helper()
+	auth, err := NewAPIKeyAuth(keys, "X-API-Key", "api_key")
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuth: %v", err)
+	}
+	auth.now = func() time.Time { return *now }
+
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	router.HandleFunc("/api/docs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"query": r.URL.RawQuery})
+	}).Methods("GET")
+	router.Use(LoggingMiddleware)
+	router.Use(auth.Middleware)
+	return router
+}
+
+func serveWithKey(router http.Handler, target, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body không phải ErrorResponse: %s", rec.Body.String())
+	}
+	return body.Error
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router := newAPIKeyTestRouter(t, &now,
+		config.APIKey{Name: "reader", Key: "reader-key", Scopes: []string{ScopeTweetsRead, ScopeUsersRead}},
+		config.APIKey{Name: "ops", KeySHA256: hashAPIKey("ops-key"), Scopes: []string{ScopeAll}},
+	)
+
+	tests := []struct {
+		name   string
+		target string
+		key    string
+		status int
+		code   string
+	}{
+		{"thiếu key", "/api/user/alice", "", http.StatusUnauthorized, "API_KEY_REQUIRED"},
+		{"key sai", "/api/user/alice", "wrong-key", http.StatusUnauthorized, "INVALID_API_KEY"},
+		{"users:read", "/api/user/alice", "reader-key", http.StatusOK, ""},
+		{"tweets:read", "/api/user/alice/tweets", "reader-key", http.StatusOK, ""},
+		{"thiếu admin", "/api/ratelimits", "reader-key", http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"key_sha256 với *", "/api/ratelimits", "ops-key", http.StatusOK, ""},
+		{"key qua query", "/api/user/alice?api_key=reader-key", "", http.StatusOK, ""},
+		{"docs công khai", "/api/docs", "", http.StatusOK, ""},
+		{"ngoài /api", "/health", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithKey(router, tt.target, tt.key)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.code != "" && errorCode(t, rec) != tt.code {
+				t.Errorf("error = %s, want %s", errorCode(t, rec), tt.code)
+			}
+		})
+	}
+
+	// Scope write khác scope read
+	req := httptest.NewRequest("PUT", "/api/tweets/100/hidden?hidden=true", nil)
+	req.Header.Set("X-API-Key", "reader-key")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("PUT hidden với reader: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestAPIKeyQueryParamIsStripped(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router := newAPIKeyTestRouter(t, &now, config.APIKey{Name: "reader", Key: "secret-key", Scopes: []string{ScopeAll}})
+	capture := &logCapture{}
+	log.AddHook(capture)
+	defer log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+
+	rec := serveWithKey(router, "/api/docs?x=1&api_key=secret-key", "")
+	if strings.Contains(rec.Body.String(), "secret-key") {
+		t.Errorf("handler vẫn thấy api_key: %s", rec.Body.String())
+	}
+	rec = serveWithKey(router, "/api/user/alice?api_key=secret-key", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	logs := capture.requestLogs()
+	if len(logs) != 2 {
+		t.Fatalf("có %d dòng log HTTP Request, want 2", len(logs))
+	}
+	for _, fields := range logs {
+		if strings.Contains(fields["query"].(string), "secret-key") {
+			t.Errorf("log chứa API key: %v", fields["query"])
+		}
+	}
+	if logs[1]["api_key"] != "reader" {
+		t.Errorf("log api_key = %v, want reader", logs[1]["api_key"])
+	}
+}
+
+func TestAPIKeyQuota(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+	router := newAPIKeyTestRouter(t, &now,
+		config.APIKey{Name: "minute", Key: "minute-key", Scopes: []string{ScopeAll}, PerMinute: 2},
+		config.APIKey{Name: "day", Key: "day-key", Scopes: []string{ScopeAll}, PerMinute: 10, PerDay: 3},
+	)
+
+	for i := 0; i < 2; i++ {
+		if rec := serveWithKey(router, "/api/user/alice", "minute-key"); rec.Code != http.StatusOK {
+			t.Fatalf("request #%d: status = %d", i+1, rec.Code)
+		}
+	}
+	rec := serveWithKey(router, "/api/user/alice", "minute-key")
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "QUOTA_EXCEEDED" {
+		t.Fatalf("vượt quota phút: %d %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "31" {
+		t.Errorf("Retry-After = %q, want 31", got)
+	}
+	if rec.Header().Get("X-RateLimit-Limit-Minute") != "2" || rec.Header().Get("X-RateLimit-Remaining-Minute") != "0" {
+		t.Errorf("quota headers = %v", rec.Header())
+	}
+	// Key khác có quota riêng
+	if rec := serveWithKey(router, "/api/user/alice", "day-key"); rec.Code != http.StatusOK {
+		t.Fatalf("day-key bị ảnh hưởng bởi quota của minute-key: %d", rec.Code)
+	}
+
+	// Sang phút mới thì quota phút được reset
+	now = now.Add(time.Minute)
+	if rec := serveWithKey(router, "/api/user/alice", "minute-key"); rec.Code != http.StatusOK {
+		t.Fatalf("phút mới: status = %d", rec.Code)
+	}
+
+	// Quota ngày: đã dùng 1, còn 2
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Minute)
+		rec := serveWithKey(router, "/api/user/alice", "day-key")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("day-key #%d: status = %d", i+2, rec.Code)
+		}
+		if i == 1 && rec.Header().Get("X-RateLimit-Remaining-Day") != "0" {
+			t.Errorf("X-RateLimit-Remaining-Day = %q, want 0", rec.Header().Get("X-RateLimit-Remaining-Day"))
+		}
+	}
+	rec = serveWithKey(router, "/api/user/alice", "day-key")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("vượt quota ngày: status = %d", rec.Code)
+	}
+	midnight := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if got, want := rec.Header().Get("Retry-After"), int(midnight.Sub(now).Seconds())+1; got != strconv.Itoa(want) {
+		t.Errorf("Retry-After = %s, want %d (tới 00:00 UTC)", got, want)
+	}
+
+	now = midnight
+	if rec := serveWithKey(router, "/api/user/alice", "day-key"); rec.Code != http.StatusOK {
+		t.Errorf("ngày mới: status = %d", rec.Code)
+	}
+}
+
+func TestNewAPIKeyAuthRejectsUnknownScope(t *testing.T) {
+	_, err := NewAPIKeyAuth([]config.APIKey{{Name: "typo", Key: "k", Scopes: []string{"tweet:read"}}}, "X-API-Key", "")
+	if err == nil {
+		t.Fatal("scope gõ sai phải bị từ chối")
+	}
+}
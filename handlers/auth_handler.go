@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SessionCookie cấu hình cookie chứa session ID
+type SessionCookie struct {
+	Name   string
+	Secure bool
+}
+
+// AuthHandler xử lý đăng nhập OAuth 2.0 (Authorization Code with PKCE) cho end users
+// và gắn access token của session vào các request /api
+type AuthHandler struct {
+	sessions       *services.SessionManager
+	twitterService services.TwitterAPI
+	cookie         SessionCookie
+}
+
+// NewAuthHandler tạo AuthHandler; twitterService được dùng để lấy user vừa đăng nhập
+func NewAuthHandler(sessions *services.SessionManager, twitterService services.TwitterAPI, cookie SessionCookie) *AuthHandler {
+	return &AuthHandler{
+		sessions:       sessions,
+		twitterService: twitterService,
+		cookie:         cookie,
+	}
+}
+
+// stateCookiePath giới hạn cookie chứa hash của OAuth state trong các route /auth
+const stateCookiePath = "/auth"
+
+// Login bắt đầu đăng nhập: lưu state/code verifier, gắn hash của state vào cookie và
+// redirect tới trang authorize của X
+// GET /auth/login[?redirect=/path]
+func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	redirect := r.URL.Query().Get("redirect")
+	if redirect != "" && !localRedirect(redirect) {
+		writeError(w, r, apiError{
+			status:        http.StatusBadRequest,
+			code:          "INVALID_REDIRECT",
+			message:       "redirect phải là đường dẫn trong cùng site, ví dụ /test",
+			invalidParams: []models.InvalidParam{{Name: "redirect", Reason: "phải bắt đầu bằng / và không chứa host"}},
+		})
+		return
+	}
+
+	authURL, state, err := a.sessions.BeginLogin(r.Context(), redirect)
+	if err != nil {
+		writeServiceError(w, r, err, "Không thể bắt đầu đăng nhập")
+		return
+	}
+
+	// Gắn state với browser bắt đầu đăng nhập: callback từ browser khác bị từ chối, để
+	// không ai đăng nhập được nạn nhân vào tài khoản X của mình (login CSRF)
+	a.writeCookie(w, a.stateCookieName(), stateCookiePath, stateHash(state), a.sessions.LoginTTL())
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback nhận redirect từ X sau khi user đồng ý, đổi code lấy token và tạo session
+// GET /auth/callback?code=xxx&state=yyy
+func (a *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	stateOK := a.stateMatches(r, state)
+	// State chỉ dùng một lần nên cookie không còn cần sau callback
+	a.writeCookie(w, a.stateCookieName(), stateCookiePath, "", -1)
+
+	if errCode := query.Get("error"); errCode != "" {
+		if stateOK {
+			a.sessions.CancelLogin(r.Context(), state)
+		}
+		log.WithFields(log.Fields{
+			"error":       errCode,
+			"description": query.Get("error_description"),
+		}).Warn("Đăng nhập OAuth 2.0 bị từ chối")
+		writeError(w, r, apiError{
+			status:         http.StatusForbidden,
+			code:           "OAUTH_ACCESS_DENIED",
+			message:        "Đăng nhập không thành công: user đã từ chối hoặc X trả về lỗi",
+			upstreamDetail: strings.TrimSpace(errCode + " " + query.Get("error_description")),
+		})
+		return
+	}
+
+	var missing []models.InvalidParam
+	for _, name := range []string{"code", "state"} {
+		if query.Get(name) == "" {
+			missing = append(missing, requiredParam(name))
+		}
+	}
+	if len(missing) > 0 {
+		writeError(w, r, apiError{
+			status:        http.StatusBadRequest,
+			code:          "INVALID_OAUTH_CALLBACK",
+			message:       "Callback thiếu code hoặc state",
+			invalidParams: missing,
+		})
+		return
+	}
+
+	if !stateOK {
+		log.WithField("request_id", RequestIDFromContext(r.Context())).Warn("OAuth callback không khớp state cookie của browser")
+		writeError(w, r, apiError{
+			status:  http.StatusBadRequest,
+			code:    "INVALID_OAUTH_STATE",
+			message: "State không khớp với browser đã bắt đầu đăng nhập, hãy đăng nhập lại qua /auth/login",
+		})
+		return
+	}
+
+	token, redirect, ok, err := a.sessions.CompleteLogin(r.Context(), state, query.Get("code"))
+	if err != nil {
+		writeServiceError(w, r, err, "Không thể hoàn tất đăng nhập")
+		return
+	}
+	if !ok {
+		writeError(w, r, apiError{
+			status:  http.StatusBadRequest,
+			code:    "INVALID_OAUTH_STATE",
+			message: "State không hợp lệ hoặc đã hết hạn, hãy đăng nhập lại qua /auth/login",
+		})
+		return
+	}
+
+	// Token chưa gắn với user nào: hỏi X xem token thuộc về ai
+	ctx := services.WithUserToken(r.Context(), services.UserToken{AccessToken: token.AccessToken})
+	user, err := a.twitterService.GetMe(ctx)
+	if err != nil {
+		writeServiceError(w, r, err, "Không thể lấy thông tin user vừa đăng nhập")
+		return
+	}
+
+	sess, err := a.sessions.Create(r.Context(), token, user.ID, user.Username)
+	if err != nil {
+		writeServiceError(w, r, err, "Không thể tạo session")
+		return
+	}
+
+	a.setCookie(w, sess.ID, a.sessions.TTL())
+	w.Header().Set("Cache-Control", "no-store")
+	if redirect != "" {
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.SessionResponse{
+		User:      user,
+		Scope:     sess.Scope,
+		ExpiresAt: sess.ExpiresAt,
+	})
+}
+
+// Logout xóa session, thu hồi token và xóa cookie
+// POST /auth/logout
+func (a *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(a.cookie.Name); err == nil && cookie.Value != "" {
+		if err := a.sessions.Delete(r.Context(), cookie.Value); err != nil {
+			writeServiceError(w, r, err, "Không thể đăng xuất")
+			return
+		}
+	}
+
+	a.setCookie(w, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SessionMiddleware đọc session từ cookie (refresh access token nếu sắp hết hạn) và
+// gắn token của end user vào context; request không có session dùng Bearer Token của app
+func (a *AuthHandler) SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Response phụ thuộc vào user đăng nhập
+		w.Header().Add("Vary", "Cookie")
+
+		cookie, err := r.Cookie(a.cookie.Name)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sess, err := a.sessions.Get(r.Context(), cookie.Value)
+		if err != nil {
+			if apiErr, ok := services.AsAPIError(err); ok && apiErr.Code == services.CodeSessionExpired {
+				a.setCookie(w, "", -1)
+			}
+			writeServiceError(w, r, err, "Không thể đọc session")
+			return
+		}
+		if sess == nil {
+			// Session đã hết hạn hoặc bị xóa: bỏ cookie cũ và tiếp tục như chưa đăng nhập
+			a.setCookie(w, "", -1)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"request_id": RequestIDFromContext(r.Context()),
+			"user_id":    sess.UserID,
+		}).Debug("Request chạy bằng token của end user")
+		next.ServeHTTP(w, r.WithContext(services.WithUserToken(r.Context(), sess.UserToken())))
+	})
+}
+
+// setCookie ghi cookie session; maxAge < 0 xóa cookie
+func (a *AuthHandler) setCookie(w http.ResponseWriter, value string, maxAge time.Duration) {
+	a.writeCookie(w, a.cookie.Name, "/", value, maxAge)
+}
+
+// stateCookieName là tên cookie chứa hash của OAuth state giữa login và callback
+func (a *AuthHandler) stateCookieName() string {
+	return a.cookie.Name + "_state"
+}
+
+// stateMatches kiểm tra state trong callback khớp với cookie do /auth/login ghi
+func (a *AuthHandler) stateMatches(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(a.stateCookieName())
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateHash(state))) == 1
+}
+
+// stateHash là giá trị lưu trong cookie thay cho state
+func stateHash(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeCookie ghi cookie HttpOnly, SameSite=Lax (vẫn được gửi khi X redirect về
+// callback); maxAge < 0 xóa cookie
+func (a *AuthHandler) writeCookie(w http.ResponseWriter, name, path, value string, maxAge time.Duration) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		HttpOnly: true,
+		Secure:   a.cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(maxAge.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// localRedirect chỉ cho phép đường dẫn tương đối trong cùng site để tránh open redirect
+func localRedirect(redirect string) bool {
+	return strings.HasPrefix(redirect, "/") &&
+		!strings.HasPrefix(redirect, "//") &&
+		!strings.HasPrefix(redirect, "/\\") &&
+		!strings.ContainsAny(redirect, "\r\n")
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/mockserver"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+const testSessionCookie = "x_session"
+
+// newAuthTestServer chạy backend với AuthHandler và TwitterService thật, cả hai trỏ
+// tới mock server đóng vai X API và authorization server; user đăng nhập là bobtran
+func newAuthTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	fixtures, err := mockserver.LoadFixtures("../mockserver/fixtures")
+	if err != nil {
+		t.Fatalf("không load được fixtures: %v", err)
+	}
+	upstream := httptest.NewServer(mockserver.New(fixtures, mockserver.Options{
+		BearerToken:        "app-bearer-token",
+		OAuth2ClientID:     "test-client",
+		OAuth2ClientSecret: "test-secret",
+		OAuth2User:         "bobtran",
+	}))
+	t.Cleanup(upstream.Close)
+
+	var router http.Handler
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(backend.Close)
+
+	cfg := &config.Config{
+		TwitterBearerToken:      "app-bearer-token",
+		TwitterAPIBaseURL:       upstream.URL,
+		TwitterHTTPMode:         "live",
+		TwitterRetryMaxAttempts: 1,
+		MaxTweetsPerRequest:     100,
+		DefaultTweetsCount:      10,
+		CacheBackend:            "memory",
+		OAuth2ClientID:          "test-client",
+		OAuth2ClientSecret:      "test-secret",
+		OAuth2RedirectURL:       backend.URL + "/auth/callback",
+		OAuth2Scopes:            "tweet.read users.read offline.access",
+		OAuth2AuthURL:           upstream.URL + "/i/oauth2/authorize",
+		OAuth2TokenURL:          upstream.URL + "/2/oauth2/token",
+		OAuth2RevokeURL:         upstream.URL + "/2/oauth2/revoke",
+		SessionTTL:              time.Hour,
+		SessionRefreshBefore:    time.Minute,
+		SessionMaxEntries:       10,
+	}
+	svc, err := services.NewTwitterService(cfg)
+	if err != nil {
+		t.Fatalf("NewTwitterService: %v", err)
+	}
+	sessions, err := services.NewSessionManager(cfg, services.NewOAuth2Client(cfg))
+	if err != nil {
+		t.Fatalf("NewSessionManager: %v", err)
+	}
+
+	auth := NewAuthHandler(sessions, svc, SessionCookie{Name: testSessionCookie})
+	tweets := NewTweetsHandler(svc)
+
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
+	r.HandleFunc("/auth/login", auth.Login).Methods("GET")
+	r.HandleFunc("/auth/callback", auth.Callback).Methods("GET")
+	r.HandleFunc("/auth/logout", auth.Logout).Methods("POST")
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(auth.SessionMiddleware)
+	api.HandleFunc("/users/me", tweets.GetMe).Methods("GET")
+	router = r
+
+	return backend
+}
+
+func newBrowser(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+func getMe(t *testing.T, client *http.Client, baseURL string) (int, string) {
+	t.Helper()
+	res, err := client.Get(baseURL + "/api/users/me")
+	if err != nil {
+		t.Fatalf("GET /api/users/me: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body models.ErrorResponse
+		json.NewDecoder(res.Body).Decode(&body)
+		return res.StatusCode, body.Error
+	}
+	var user models.User
+	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+		t.Fatalf("decode user: %v", err)
+	}
+	return res.StatusCode, user.Username
+}
+
+func TestAuthLoginFlow(t *testing.T) {
+	backend := newAuthTestServer(t)
+	browser := newBrowser(t)
+
+	// Chưa đăng nhập: users/me chạy bằng Bearer Token của app
+	if status, code := getMe(t, browser, backend.URL); status != http.StatusForbidden || code != services.CodeUserContextRequired {
+		t.Fatalf("trước khi đăng nhập: %d %s, want 403 %s", status, code, services.CodeUserContextRequired)
+	}
+
+	// login -> authorize (mock) -> callback -> redirect
+	res, err := browser.Get(backend.URL + "/auth/login?redirect=/api/users/me")
+	if err != nil {
+		t.Fatalf("GET /auth/login: %v", err)
+	}
+	var me models.User
+	json.NewDecoder(res.Body).Decode(&me)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || me.Username != "bobtran" {
+		t.Fatalf("sau khi đăng nhập: %d %q, want 200 bobtran", res.StatusCode, me.Username)
+	}
+	if res.Request.URL.Path != "/api/users/me" {
+		t.Errorf("redirect cuối tới %s, want /api/users/me", res.Request.URL.Path)
+	}
+
+	backendURL, _ := url.Parse(backend.URL)
+	cookies := browser.Jar.Cookies(backendURL)
+	if len(cookies) != 1 || cookies[0].Name != testSessionCookie {
+		t.Fatalf("cookies = %v, want %s", cookies, testSessionCookie)
+	}
+
+	// Browser khác (không có cookie) vẫn không có user context
+	if status, _ := getMe(t, newBrowser(t), backend.URL); status != http.StatusForbidden {
+		t.Errorf("browser khác: status = %d, want 403", status)
+	}
+
+	// Logout xóa session và cookie
+	res, err = browser.Post(backend.URL+"/auth/logout", "", nil)
+	if err != nil {
+		t.Fatalf("POST /auth/logout: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("logout status = %d, want 204", res.StatusCode)
+	}
+	if cookies := browser.Jar.Cookies(backendURL); len(cookies) != 0 {
+		t.Errorf("cookie vẫn còn sau logout: %v", cookies)
+	}
+	if status, _ := getMe(t, browser, backend.URL); status != http.StatusForbidden {
+		t.Errorf("sau logout: status = %d, want 403", status)
+	}
+}
+
+func TestAuthCallbackReturnsSessionJSON(t *testing.T) {
+	backend := newAuthTestServer(t)
+	browser := newBrowser(t)
+
+	res, err := browser.Get(backend.URL + "/auth/login")
+	if err != nil {
+		t.Fatalf("GET /auth/login: %v", err)
+	}
+	defer res.Body.Close()
+
+	var body models.SessionResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || body.User == nil || body.User.Username != "bobtran" || body.ExpiresAt.IsZero() {
+		t.Errorf("callback = %d %+v", res.StatusCode, body)
+	}
+	if res.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", res.Header.Get("Cache-Control"))
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	backend := newAuthTestServer(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"open redirect", "/auth/login?redirect=//evil.example", http.StatusBadRequest, "INVALID_REDIRECT"},
+		{"absolute redirect", "/auth/login?redirect=https://evil.example/", http.StatusBadRequest, "INVALID_REDIRECT"},
+		{"user từ chối", "/auth/callback?error=access_denied&state=abc", http.StatusForbidden, "OAUTH_ACCESS_DENIED"},
+		{"thiếu code", "/auth/callback?state=abc", http.StatusBadRequest, "INVALID_OAUTH_CALLBACK"},
+		{"state lạ", "/auth/callback?code=abc&state=forged", http.StatusBadRequest, "INVALID_OAUTH_STATE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.Get(backend.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			var body models.ErrorResponse
+			json.NewDecoder(res.Body).Decode(&body)
+			if res.StatusCode != tt.status || body.Error != tt.code {
+				t.Errorf("status = %d %s, want %d %s", res.StatusCode, body.Error, tt.status, tt.code)
+			}
+		})
+	}
+
+	// Login hợp lệ redirect tới authorize với PKCE S256
+	res, err := client.Get(backend.URL + "/auth/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	location := res.Header.Get("Location")
+	if res.StatusCode != http.StatusFound || !strings.Contains(location, "code_challenge_method=S256") || !strings.Contains(location, "/i/oauth2/authorize?") {
+		t.Errorf("login = %d %s", res.StatusCode, location)
+	}
+}
+
+func TestAuthCallbackRequiresStateCookie(t *testing.T) {
+	backend := newAuthTestServer(t)
+	noRedirect := func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	// Browser bắt đầu đăng nhập nhận cookie chứa hash của state
+	starter := newBrowser(t)
+	starter.CheckRedirect = noRedirect
+	res, err := starter.Get(backend.URL + "/auth/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	setCookie := res.Header.Get("Set-Cookie")
+	if !strings.HasPrefix(setCookie, testSessionCookie+"_state=") || !strings.Contains(setCookie, "HttpOnly") || !strings.Contains(setCookie, "SameSite=Lax") {
+		t.Errorf("Set-Cookie = %q, want state cookie HttpOnly SameSite=Lax", setCookie)
+	}
+
+	// Đi qua trang authorize (mock) để lấy callback URL có code và state
+	res, err = starter.Get(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	callback := res.Header.Get("Location")
+	if !strings.HasPrefix(callback, backend.URL+"/auth/callback?") {
+		t.Fatalf("authorize redirect tới %q", callback)
+	}
+
+	// Login CSRF: browser khác mở callback URL của người bắt đầu đăng nhập
+	other := newBrowser(t)
+	other.CheckRedirect = noRedirect
+	res, err = other.Get(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body models.ErrorResponse
+	json.NewDecoder(res.Body).Decode(&body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest || body.Error != "INVALID_OAUTH_STATE" {
+		t.Errorf("callback từ browser khác = %d %s, want 400 INVALID_OAUTH_STATE", res.StatusCode, body.Error)
+	}
+	backendURL, _ := url.Parse(backend.URL)
+	if cookies := other.Jar.Cookies(backendURL); len(cookies) != 0 {
+		t.Errorf("browser khác nhận cookie %v", cookies)
+	}
+
+	// Callback bị từ chối không làm mất state của browser bắt đầu đăng nhập
+	res, err = starter.Get(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session models.SessionResponse
+	json.NewDecoder(res.Body).Decode(&session)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || session.User == nil || session.User.Username != "bobtran" {
+		t.Errorf("callback từ browser đăng nhập = %d %+v", res.StatusCode, session)
+	}
+}
+
+func TestSessionMiddlewareIgnoresUnknownSession(t *testing.T) {
+	backend := newAuthTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodGet, backend.URL+"/api/users/me", nil)
+	req.AddCookie(&http.Cookie{Name: testSessionCookie, Value: "does-not-exist"})
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// Session không tồn tại: cookie bị xóa, request chạy như chưa đăng nhập
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", res.StatusCode)
+	}
+	if setCookie := res.Header.Get("Set-Cookie"); !strings.Contains(setCookie, testSessionCookie+"=;") || !strings.Contains(setCookie, "Max-Age=0") {
+		t.Errorf("Set-Cookie = %q, want xóa cookie", setCookie)
+	}
+	if vary := res.Header.Values("Vary"); len(vary) == 0 || !strings.Contains(strings.Join(vary, ","), "Cookie") {
+		t.Errorf("Vary = %v, want Cookie", vary)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// cacheCategory là nhóm endpoint quyết định max-age của header Cache-Control,
+// giống các nhóm TTL của response cache
+type cacheCategory int
+
+const (
+	cacheNone cacheCategory = iota
+	cacheProfile
+	cacheTimeline
+	cacheSearch
+	cacheCounts
+)
+
+// CacheMaxAges là max-age của header Cache-Control theo nhóm endpoint; 0 = no-cache
+type CacheMaxAges struct {
+	Profile  time.Duration
+	Timeline time.Duration
+	Search   time.Duration
+	Counts   time.Duration
+}
+
+// DefaultCacheMaxAges khớp với TTL mặc định của response cache (CACHE_TTL_*)
+var DefaultCacheMaxAges = CacheMaxAges{
+	Profile:  5 * time.Minute,
+	Timeline: time.Minute,
+	Search:   30 * time.Second,
+	Counts:   5 * time.Minute,
+}
+
+func (m CacheMaxAges) forCategory(category cacheCategory) time.Duration {
+	switch category {
+	case cacheProfile:
+		return m.Profile
+	case cacheTimeline:
+		return m.Timeline
+	case cacheSearch:
+		return m.Search
+	case cacheCounts:
+		return m.Counts
+	default:
+		return 0
+	}
+}
+
+// respondCacheable gửi JSON response kèm Cache-Control theo nhóm endpoint và
+// Last-Modified nếu payload có mốc thời gian tự nhiên. ETag do ConditionalGetMiddleware tính.
+func (h *TweetsHandler) respondCacheable(w http.ResponseWriter, category cacheCategory, payload interface{}) {
+	if maxAge := h.maxAges.forCategory(category); maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if modified := lastModified(payload); !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	h.respondWithJSON(w, http.StatusOK, payload)
+}
+
+// lastModified trả về mốc thời gian tự nhiên của payload: created_at của tweet,
+// hoặc của tweet mới nhất trong timeline, mentions và kết quả search
+func lastModified(payload interface{}) time.Time {
+	switch p := payload.(type) {
+	case *models.TweetDetailResponse:
+		return p.Tweet.CreatedAt
+	case *models.TweetsResponse:
+		return newestTweet(p.Tweets)
+	case *models.MentionsResponse:
+		return newestTweet(p.Tweets)
+	case *models.SearchTweetsResponse:
+		return newestTweet(p.Tweets)
+	default:
+		return time.Time{}
+	}
+}
+
+func newestTweet(tweets []models.Tweet) time.Time {
+	var newest time.Time
+	for _, t := range tweets {
+		if t.CreatedAt.After(newest) {
+			newest = t.CreatedAt
+		}
+	}
+	return newest
+}
+
+// ConditionalGetMiddleware tính strong ETag từ body của các response 200 cho GET và
+// trả về 304 Not Modified khi ETag khớp If-None-Match. Response chưa có Cache-Control
+// được gắn no-cache để client luôn revalidate. If-Modified-Since không được dùng vì
+// metrics của tweet thay đổi mà không làm đổi Last-Modified.
+func ConditionalGetMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferedWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(bw, r)
+		if bw.streaming {
+			return
+		}
+
+		if bw.statusCode != http.StatusOK {
+			w.WriteHeader(bw.statusCode)
+			w.Write(bw.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(bw.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write(bw.body.Bytes())
+	})
+}
+
+// etagMatches so sánh If-None-Match với etag theo weak comparison (RFC 9110 13.1.2)
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter giữ lại body để tính ETag trước khi gửi. Handler gọi Flush
+// (streaming) chuyển writer sang ghi thẳng, khi đó response không có ETag.
+type bufferedWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	streaming   bool
+	body        bytes.Buffer
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.streaming {
+		bw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if !bw.wroteHeader {
+		bw.wroteHeader = true
+		bw.statusCode = code
+	}
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.streaming {
+		return bw.ResponseWriter.Write(b)
+	}
+	bw.wroteHeader = true
+	return bw.body.Write(b)
+}
+
+func (bw *bufferedWriter) Flush() {
+	if !bw.streaming {
+		bw.streaming = true
+		bw.ResponseWriter.WriteHeader(bw.statusCode)
+		bw.ResponseWriter.Write(bw.body.Bytes())
+		bw.body.Reset()
+	}
+	http.NewResponseController(bw.ResponseWriter).Flush()
+}
+
+func (bw *bufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
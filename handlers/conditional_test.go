@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/services"
+)
+
+func TestConditionalGetETagAnd304(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	for _, target := range []string{"/api/tweets/100", "/api/user/alice/tweets", "/health"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || len(etag) < 3 || etag[0] != '"' {
+			t.Fatalf("GET %s: status = %d, ETag = %q", target, rec.Code, etag)
+		}
+
+		// Cùng dữ liệu thì cùng ETag
+		again := httptest.NewRecorder()
+		router.ServeHTTP(again, httptest.NewRequest("GET", target, nil))
+		if again.Header().Get("ETag") != etag {
+			t.Errorf("GET %s: ETag đổi giữa hai lần gọi: %q, %q", target, etag, again.Header().Get("ETag"))
+		}
+
+		for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			req := httptest.NewRequest("GET", target, nil)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Errorf("GET %s If-None-Match %s: status = %d, body = %q; want 304 rỗng", target, ifNoneMatch, rec.Code, rec.Body.String())
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("GET %s: 304 thiếu ETag", target)
+			}
+		}
+
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("If-None-Match", `"stale"`)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("GET %s If-None-Match stale: status = %d; want 200", target, rec.Code)
+		}
+	}
+}
+
+func TestConditionalGetSkipsErrors(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	req := httptest.NewRequest("GET", "/api/tweets/999", nil)
+	req.Header.Set("If-None-Match", "*")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+		t.Errorf("status = %d, ETag = %q; want 404 không có ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestCacheControlByEndpointType(t *testing.T) {
+	h := NewTweetsHandler(newTestFake())
+	h.SetCacheMaxAges(CacheMaxAges{Profile: 10 * time.Minute, Timeline: time.Minute, Search: 0, Counts: time.Hour})
+	router := newTestRouter(h)
+
+	for _, tc := range []struct {
+		target string
+		want   string
+	}{
+		{"/api/user/alice", "private, max-age=600"},
+		{"/api/user/alice/tweets", "private, max-age=60"},
+		{"/api/tweets/search?q=golang", "no-cache"},
+		{"/api/tweets/counts/recent?q=golang", "private, max-age=3600"},
+		{"/api/user/alice/blocking", "no-cache"},
+		{"/health", "no-cache"},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+		if got := rec.Header().Get("Cache-Control"); got != tc.want {
+			t.Errorf("GET %s: Cache-Control = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestLastModifiedFromTweetTimestamps(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		target string
+		want   string
+	}{
+		{"/api/tweets/100", created.Format(http.TimeFormat)},
+		{"/api/tweets/101", created.Add(time.Minute).Format(http.TimeFormat)},
+		{"/api/user/alice/mentions", created.Add(time.Minute).Format(http.TimeFormat)},
+		{"/api/user/alice", ""},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+		if got := rec.Header().Get("Last-Modified"); got != tc.want {
+			t.Errorf("GET %s: Last-Modified = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestConditionalGetKeepsCacheStatus(t *testing.T) {
+	cached := services.NewCachedTwitterService(newTestFake(), services.NewMemoryCacheStore(100), &config.Config{
+		CacheTTLProfile:     time.Minute,
+		CacheTTLTimeline:    time.Minute,
+		CacheTTLSearch:      time.Minute,
+		CacheTTLCounts:      time.Minute,
+		DefaultTweetsCount:  10,
+		MaxTweetsPerRequest: 100,
+	})
+	router := newTestRouter(NewTweetsHandler(cached))
+
+	for _, want := range []string{services.CacheMiss, services.CacheHit} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tweets/100", nil))
+		if rec.Header().Get("X-Cache") != want {
+			t.Errorf("X-Cache = %q, want %q", rec.Header().Get("X-Cache"), want)
+		}
+	}
+}
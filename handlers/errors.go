@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// problemContentType là media type của error response theo RFC 7807
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix là tiền tố của problem type URI, theo sau là mã lỗi dạng kebab-case
+const problemTypePrefix = "urn:x-twitter-backend:problem:"
+
+// apiError là thông tin lỗi chung, được render thành ErrorResponse hoặc ProblemDetails
+type apiError struct {
+	status         int
+	code           string
+	message        string
+	upstreamDetail string
+	invalidParams  []models.InvalidParam
+}
+
+// writeError gửi error response. Client gửi Accept: application/problem+json sẽ nhận
+// ProblemDetails (RFC 7807), các client khác nhận ErrorResponse như trước.
+func writeError(w http.ResponseWriter, r *http.Request, e apiError) {
+	requestID := RequestIDFromContext(r.Context())
+
+	var payload interface{}
+	contentType := "application/json"
+	if wantsProblemJSON(r) {
+		contentType = problemContentType
+		payload = models.ProblemDetails{
+			Type:           problemTypePrefix + strings.ReplaceAll(strings.ToLower(e.code), "_", "-"),
+			Title:          http.StatusText(e.status),
+			Status:         e.status,
+			Detail:         e.message,
+			Instance:       r.URL.RequestURI(),
+			Code:           e.code,
+			RequestID:      requestID,
+			UpstreamDetail: e.upstreamDetail,
+			InvalidParams:  e.invalidParams,
+		}
+	} else {
+		payload = models.ErrorResponse{
+			Error:     e.code,
+			Message:   e.message,
+			Detail:    e.upstreamDetail,
+			Code:      e.status,
+			RequestID: requestID,
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(e.status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.WithError(err).Error("Lỗi khi encode error response")
+	}
+}
+
+// wantsProblemJSON kiểm tra header Accept có chấp nhận application/problem+json không
+func wantsProblemJSON(r *http.Request) bool {
+	return accepts(r, problemContentType)
+}
+
+// accepts kiểm tra header Accept có liệt kê contentType với q > 0 không
+func accepts(r *http.Request, contentType string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != contentType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+		return true
+	}
+	return false
+}
+
+// statusForKind ánh xạ loại lỗi của service sang HTTP status code
+func statusForKind(kind services.ErrorKind) int {
+	switch kind {
+	case services.ErrorKindInvalidRequest:
+		return http.StatusBadRequest
+	case services.ErrorKindUnauthorized:
+		return http.StatusUnauthorized
+	case services.ErrorKindForbidden:
+		return http.StatusForbidden
+	case services.ErrorKindNotFound:
+		return http.StatusNotFound
+	case services.ErrorKindRateLimited:
+		return http.StatusTooManyRequests
+	case services.ErrorKindUnavailable:
+		return http.StatusServiceUnavailable
+	case services.ErrorKindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// respondWithError gửi error response; invalidParams liệt kê các parameter không hợp lệ
+func (h *TweetsHandler) respondWithError(w http.ResponseWriter, r *http.Request, statusCode int, message, code string, invalidParams ...models.InvalidParam) {
+	writeError(w, r, apiError{
+		status:        statusCode,
+		code:          code,
+		message:       message,
+		invalidParams: invalidParams,
+	})
+}
+
+// respondWithServiceError gửi error response cho lỗi trả về từ TwitterAPI.
+// Lỗi có kiểu (*services.APIError) được ánh xạ sang status/mã lỗi tương ứng;
+// các lỗi khác chỉ được log, client nhận 500 INTERNAL_ERROR với message.
+func (h *TweetsHandler) respondWithServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	writeServiceError(w, r, err, message)
+}
+
+// writeServiceError là phần chung của respondWithServiceError cho mọi handler
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	apiErr, ok := services.AsAPIError(err)
+	if !ok {
+		log.WithFields(log.Fields{
+			"request_id": RequestIDFromContext(r.Context()),
+		}).WithError(err).Error(message)
+		writeError(w, r, apiError{status: http.StatusInternalServerError, code: "INTERNAL_ERROR", message: message})
+		return
+	}
+
+	if seconds := apiErr.RetryAfterSeconds(); seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	writeError(w, r, serviceError(apiErr))
+}
+
+// serviceError ánh xạ lỗi có kiểu của TwitterAPI sang status/mã lỗi tương ứng
+func serviceError(apiErr *services.APIError) apiError {
+	return apiError{
+		status:         statusForKind(apiErr.Kind),
+		code:           apiErr.Code,
+		message:        upperFirst(apiErr.Message),
+		upstreamDetail: apiErr.Detail,
+	}
+}
+
+// requiredParam tạo InvalidParam cho parameter bắt buộc bị thiếu
+func requiredParam(name string) models.InvalidParam {
+	return models.InvalidParam{Name: name, Reason: "là bắt buộc"}
+}
+
+// upperFirst viết hoa ký tự đầu tiên của message
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
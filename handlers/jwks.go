@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// jwksMinRefetch là khoảng cách tối thiểu giữa hai lần tải lại JWKS URL, để token giả
+// mạo với kid ngẫu nhiên hoặc JWKS URL đang lỗi không biến mỗi request /api thành một
+// request tới JWKS URL
+const jwksMinRefetch = time.Minute
+
+// jwk là một key trong JWKS (RFC 7517); chỉ đọc các field của RSA và EC
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verifyKey là public key đã parse cùng thuật toán ký duy nhất được dùng với nó
+type verifyKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// parseJWKS đọc các key RS256 và ES256 (P-256) dùng để ký từ JWKS; các key khác bị bỏ qua
+func parseJWKS(data []byte) ([]verifyKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("JWKS không phải JSON hợp lệ: %w", err)
+	}
+
+	var keys []verifyKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.verifyKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", k.Kid, err)
+		}
+		if key == nil {
+			log.WithFields(log.Fields{"kid": k.Kid, "kty": k.Kty, "alg": k.Alg}).Debug("Bỏ qua JWKS key không hỗ trợ")
+			continue
+		}
+		keys = append(keys, *key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS không có key RS256 hoặc ES256 nào")
+	}
+	return keys, nil
+}
+
+// verifyKey parse jwk; nil nếu thuật toán không được hỗ trợ
+func (k jwk) verifyKey() (*verifyKey, error) {
+	switch {
+	case k.Kty == "RSA" && (k.Alg == "" || k.Alg == "RS256"):
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n không hợp lệ: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("e không hợp lệ")
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key phải có ít nhất 2048 bit")
+		}
+		return &verifyKey{kid: k.Kid, alg: "RS256", key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case k.Kty == "EC" && k.Crv == "P-256" && (k.Alg == "" || k.Alg == "ES256"):
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x không hợp lệ: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y không hợp lệ: %w", err)
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("điểm không nằm trên P-256")
+		}
+		return &verifyKey{kid: k.Kid, alg: "ES256", key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("rỗng")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// JWKS là tập public keys dùng để kiểm tra chữ ký JWT, đọc từ file (một lần) hoặc URL
+// (tải lại sau mỗi chu kỳ refresh và khi gặp kid chưa biết, ví dụ sau khi xoay key)
+type JWKS struct {
+	file    string
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu        sync.RWMutex
+	keys      []verifyKey
+	fetchedAt time.Time
+
+	// fetchMu chỉ cho một request tải lại JWKS tại một thời điểm
+	fetchMu     sync.Mutex
+	lastAttempt time.Time
+}
+
+// NewJWKS tải JWKS từ file hoặc url; lỗi nếu lần tải đầu tiên thất bại để cấu hình sai
+// được phát hiện khi khởi động
+func NewJWKS(file, url string, refresh time.Duration) (*JWKS, error) {
+	s := &JWKS{
+		file:    file,
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Len trả về số keys đang dùng
+func (s *JWKS) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// lookup tìm key theo kid và alg của JWT header. JWT không có kid chỉ được nhận khi
+// JWKS có đúng một key cho alg đó.
+func (s *JWKS) lookup(kid, alg string) (crypto.PublicKey, bool) {
+	if s.url != "" && s.stale() {
+		s.refetch()
+	}
+	if key, ok := s.find(kid, alg); ok {
+		return key, true
+	}
+	if s.url == "" || !s.refetch() {
+		return nil, false
+	}
+	return s.find(kid, alg)
+}
+
+func (s *JWKS) find(kid, alg string) (crypto.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match crypto.PublicKey
+	matches := 0
+	for _, k := range s.keys {
+		if k.alg != alg || (kid != "" && k.kid != kid) {
+			continue
+		}
+		match = k.key
+		matches++
+	}
+	return match, matches == 1
+}
+
+func (s *JWKS) stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now().Sub(s.fetchedAt) >= s.refresh
+}
+
+// refetch tải lại JWKS nếu lần thử gần nhất (kể cả thất bại, hoặc của request khác
+// đang chờ cùng lúc) đã cách ít nhất jwksMinRefetch; trả về true nếu đã tải lại thành
+// công. Lỗi chỉ được log, keys cũ vẫn được dùng.
+func (s *JWKS) refetch() bool {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	if s.now().Sub(s.lastAttempt) < jwksMinRefetch {
+		return false
+	}
+	if err := s.reload(); err != nil {
+		log.WithError(err).WithField("url", s.url).Warn("⚠️  Không thể tải lại JWKS, tiếp tục dùng keys cũ")
+		return false
+	}
+	return true
+}
+
+func (s *JWKS) reload() error {
+	s.lastAttempt = s.now()
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.fetchedAt = s.now()
+	return nil
+}
+
+func (s *JWKS) read() ([]byte, error) {
+	if s.file != "" {
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("không thể đọc JWT_JWKS_FILE: %w", err)
+		}
+		return data, nil
+	}
+
+	res, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("không thể tải JWKS từ %s: %w", s.url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS URL %s trả về status %d", s.url, res.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+	"x-twitter-backend/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PrincipalJWT là Principal.Kind của request xác thực bằng JWT
+const PrincipalJWT = "jwt"
+
+var jwtRequests = metrics.NewCounter("x_api_jwt_requests_total",
+	"Số request /api xác thực bằng JWT theo kết quả (ok, missing, invalid, expired, forbidden)",
+	"result")
+
+// JWTOptions là các kiểm tra áp dụng cho JWT
+type JWTOptions struct {
+	// Issuer và Audience phải khớp claim iss và aud (aud có thể là mảng)
+	Issuer   string
+	Audience string
+	// Leeway là độ lệch đồng hồ cho phép khi kiểm tra exp và nbf
+	Leeway time.Duration
+	// ScopeClaim là claim chứa scopes, mặc định "scope"
+	ScopeClaim string
+	// ScopeMap ánh xạ giá trị trong ScopeClaim sang route scope; giá trị trùng tên route
+	// scope được dùng trực tiếp, giá trị khác bị bỏ qua
+	ScopeMap map[string][]string
+	// Optional chuyển request không có Bearer token cho middleware xác thực tiếp theo
+	// (API key) thay vì trả 401
+	Optional bool
+}
+
+// JWTAuth yêu cầu JWT (Authorization: Bearer) ký bằng RS256 hoặc ES256 cho các route
+// /api (trừ /api/docs) và kiểm tra route scope từ claims của token
+type JWTAuth struct {
+	keys *JWKS
+	opts JWTOptions
+	now  func() time.Time
+}
+
+// NewJWTAuth tạo JWTAuth kiểm tra chữ ký bằng keys
+func NewJWTAuth(keys *JWKS, opts JWTOptions) (*JWTAuth, error) {
+	if opts.ScopeClaim == "" {
+		opts.ScopeClaim = "scope"
+	}
+	for value, scopes := range opts.ScopeMap {
+		for _, scope := range scopes {
+			if !knownScopes[scope] {
+				return nil, fmt.Errorf("JWT_SCOPE_MAP: %q ánh xạ sang scope không hợp lệ %q", value, scope)
+			}
+		}
+	}
+	return &JWTAuth{keys: keys, opts: opts, now: time.Now}, nil
+}
+
+// tokenError là lý do JWT bị từ chối
+type tokenError struct {
+	code    string
+	message string
+}
+
+func (e *tokenError) Error() string { return e.message }
+
+func invalidToken(format string, args ...any) *tokenError {
+	return &tokenError{code: "INVALID_TOKEN", message: fmt.Sprintf(format, args...)}
+}
+
+// Middleware xác thực request bằng JWT
+func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := routeScope(r)
+		if r.Method == http.MethodOptions || scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			if a.opts.Optional {
+				next.ServeHTTP(w, r)
+				return
+			}
+			jwtRequests.Inc("missing")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeError(w, r, apiError{
+				status:  http.StatusUnauthorized,
+				code:    "TOKEN_REQUIRED",
+				message: "Thiếu JWT: gửi header Authorization: Bearer <token>",
+			})
+			return
+		}
+
+		principal, err := a.verify(token)
+		if err != nil {
+			result := "invalid"
+			if err.code == "TOKEN_EXPIRED" {
+				result = "expired"
+			}
+			jwtRequests.Inc(result)
+			log.WithFields(log.Fields{
+				"request_id": RequestIDFromContext(r.Context()),
+				"reason":     err.message,
+			}).Warn("JWT không hợp lệ")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			writeError(w, r, apiError{
+				status:  http.StatusUnauthorized,
+				code:    err.code,
+				message: err.message,
+			})
+			return
+		}
+
+		addLogFields(r.Context(), log.Fields{"jwt_subject": principal.Name})
+
+		if !principal.Allows(scope) {
+			jwtRequests.Inc("forbidden")
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="api", error="insufficient_scope", scope=%q`, scope))
+			// Không đưa subject (do client gửi) vào response; subject đã có trong log request
+			writeError(w, r, apiError{
+				status:  http.StatusForbidden,
+				code:    "INSUFFICIENT_SCOPE",
+				message: fmt.Sprintf("JWT không có scope %s cho route này", scope),
+			})
+			return
+		}
+
+		jwtRequests.Inc("ok")
+		next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), principal)))
+	})
+}
+
+// bearerToken đọc token từ header Authorization: Bearer <token>
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// verify kiểm tra chữ ký, iss, aud, exp, nbf của JWT và trả về Principal với sub làm
+// tên và scopes đã ánh xạ
+func (a *JWTAuth) verify(token string) (*Principal, *tokenError) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("JWT phải gồm 3 phần header.payload.signature")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("JWT header không hợp lệ")
+	}
+	// alg do client chọn nên chỉ nhận đúng các thuật toán bất đối xứng đã hỗ trợ
+	// (chặn "none" và HS256 dùng public key làm secret)
+	if header.Alg != "RS256" && header.Alg != "ES256" {
+		return nil, invalidToken("JWT alg %q không được hỗ trợ (chỉ RS256, ES256)", header.Alg)
+	}
+	key, ok := a.keys.lookup(header.Kid, header.Alg)
+	if !ok {
+		return nil, invalidToken("không tìm thấy key %s với kid %q trong JWKS", header.Alg, header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature) {
+		return nil, invalidToken("chữ ký JWT không hợp lệ")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("JWT payload không hợp lệ")
+	}
+
+	if iss, _ := claims["iss"].(string); iss != a.opts.Issuer {
+		return nil, invalidToken("JWT iss %q không được chấp nhận", iss)
+	}
+	if !containsAudience(claims["aud"], a.opts.Audience) {
+		return nil, invalidToken("JWT không dành cho audience %s", a.opts.Audience)
+	}
+
+	now := a.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, invalidToken("JWT thiếu claim exp")
+	}
+	if now.After(exp.Add(a.opts.Leeway)) {
+		return nil, &tokenError{code: "TOKEN_EXPIRED", message: fmt.Sprintf("JWT đã hết hạn lúc %s", exp.UTC().Format(time.RFC3339))}
+	}
+	if _, present := claims["nbf"]; present {
+		nbf, ok := numericDate(claims["nbf"])
+		if !ok {
+			return nil, invalidToken("JWT claim nbf không hợp lệ")
+		}
+		if now.Before(nbf.Add(-a.opts.Leeway)) {
+			return nil, invalidToken("JWT chưa có hiệu lực trước %s", nbf.UTC().Format(time.RFC3339))
+		}
+	}
+
+	name, _ := claims["sub"].(string)
+	if name == "" {
+		return nil, invalidToken("JWT thiếu claim sub")
+	}
+	return &Principal{Kind: PrincipalJWT, Name: name, Scopes: a.scopes(claims[a.opts.ScopeClaim])}, nil
+}
+
+// scopes ánh xạ giá trị của scope claim (chuỗi phân tách bằng khoảng trắng hoặc mảng)
+// sang route scopes
+func (a *JWTAuth) scopes(claim any) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var scopes []string
+	add := func(scope string) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, value := range values {
+		if mapped, ok := a.opts.ScopeMap[value]; ok {
+			for _, scope := range mapped {
+				add(scope)
+			}
+		} else if knownScopes[value] {
+			add(value)
+		}
+	}
+	return scopes
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		// JWS dùng chữ ký R||S 64 byte thay vì ASN.1
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func containsAudience(aud any, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericDate đọc claim NumericDate (giây kể từ epoch, có thể có phần thập phân)
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	testIssuer   = "https://auth.internal.example"
+	testAudience = "x-fetch"
+)
+
+// testSigner là private key dùng để ký JWT trong tests
+type testSigner struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+func newRSASigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	return testSigner{kid: kid, alg: "RS256", key: key}
+}
+
+func newECSigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey: %v", err)
+	}
+	return testSigner{kid: kid, alg: "ES256", key: key}
+}
+
+// jwk trả về public key dạng JWK
+func (s testSigner) jwk() jwk {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return jwk{Kty: "RSA", Kid: s.kid, Use: "sig", Alg: "RS256", N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return jwk{Kty: "EC", Kid: s.kid, Crv: "P-256", X: b64(pub.X.FillBytes(make([]byte, 32))), Y: b64(pub.Y.FillBytes(make([]byte, 32)))}
+	}
+	panic("unsupported key")
+}
+
+func jwksJSON(t *testing.T, signers ...testSigner) []byte {
+	t.Helper()
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for _, s := range signers {
+		set.Keys = append(set.Keys, s.jwk())
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// sign tạo JWT với header alg/kid của signer và claims
+func (s testSigner) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	return s.signWithHeader(t, map[string]any{"alg": s.alg, "kid": s.kid, "typ": "JWT"}, claims)
+}
+
+func (s testSigner) signWithHeader(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+	encode := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, sv, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), sv.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKSFile(t *testing.T, signers ...testSigner) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t, signers...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newJWTTestRouter(t *testing.T, keys *JWKS, now time.Time, opts JWTOptions) *mux.Router {
+	t.Helper()
+	opts.Issuer, opts.Audience = testIssuer, testAudience
+	auth, err := NewJWTAuth(keys, opts)
+	if err != nil {
+		t.Fatalf("NewJWTAuth: %v", err)
+	}
+	auth.now = func() time.Time { return now }
+
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	router.Use(auth.Middleware)
+	return router
+}
+
+func serveWithToken(router http.Handler, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey := newRSASigner(t, "rsa-1")
+	ecKey := newECSigner(t, "ec-1")
+	unknown := newRSASigner(t, "rsa-1")
+	keys, err := NewJWKS(writeJWKSFile(t, rsaKey, ecKey), "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWKS: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router := newJWTTestRouter(t, keys, now, JWTOptions{
+		Leeway:   30 * time.Second,
+		ScopeMap: map[string][]string{"x-fetch.ops": {ScopeAdmin}},
+	})
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":   testIssuer,
+			"aud":   testAudience,
+			"sub":   "svc-dashboard",
+			"exp":   now.Add(time.Hour).Unix(),
+			"nbf":   now.Add(-time.Minute).Unix(),
+			"scope": "tweets:read users:read openid",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		status int
+		code   string
+	}{
+		{"RS256", "/api/user/alice", rsaKey.sign(t, claims(nil)), http.StatusOK, ""},
+		{"ES256", "/api/user/alice/tweets", ecKey.sign(t, claims(nil)), http.StatusOK, ""},
+		{"aud là mảng", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"aud": []string{"other", testAudience}})), http.StatusOK, ""},
+		{"scope là mảng", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"scope": []string{"users:read"}})), http.StatusOK, ""},
+		{"trong leeway", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()})), http.StatusOK, ""},
+		{"scope map", "/api/ratelimits", rsaKey.sign(t, claims(map[string]any{"scope": "x-fetch.ops"})), http.StatusOK, ""},
+		{"thiếu token", "/api/user/alice", "", http.StatusUnauthorized, "TOKEN_REQUIRED"},
+		{"hết hạn", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()})), http.StatusUnauthorized, "TOKEN_EXPIRED"},
+		{"thiếu exp", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"exp": nil})), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"nbf tương lai", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()})), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"iss sai", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"iss": "https://evil.example"})), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"aud sai", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"aud": "other"})), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"thiếu sub", "/api/user/alice", rsaKey.sign(t, claims(map[string]any{"sub": nil})), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"key khác cùng kid", "/api/user/alice", unknown.sign(t, claims(nil)), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"alg none", "/api/user/alice", rsaKey.signWithHeader(t, map[string]any{"alg": "none", "kid": "rsa-1"}, claims(nil)), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"alg không khớp key", "/api/user/alice", rsaKey.signWithHeader(t, map[string]any{"alg": "ES256", "kid": "rsa-1"}, claims(nil)), http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"không phải JWT", "/api/user/alice", "not-a-jwt", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"thiếu admin", "/api/ratelimits", rsaKey.sign(t, claims(nil)), http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithToken(router, tt.target, tt.token)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.code != "" && errorCode(t, rec) != tt.code {
+				t.Errorf("error = %s, want %s", errorCode(t, rec), tt.code)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 thiếu WWW-Authenticate")
+			}
+			if tt.status == http.StatusForbidden {
+				if got, want := rec.Header().Get("WWW-Authenticate"), `Bearer realm="api", error="insufficient_scope", scope="admin"`; got != want {
+					t.Errorf("WWW-Authenticate = %s, want %s", got, want)
+				}
+				// sub do client gửi không được phản hồi lại trong body
+				if strings.Contains(rec.Body.String(), "svc-dashboard") {
+					t.Errorf("body chứa JWT subject: %s", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestJWKSURLRefetchesOnUnknownKid(t *testing.T) {
+	oldKey := newRSASigner(t, "2024-01")
+	newKey := newECSigner(t, "2024-02")
+
+	var mu sync.Mutex
+	served := jwksJSON(t, oldKey)
+	fetches := 0
+	fetchCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		w.Write(served)
+	}))
+	defer server.Close()
+
+	keys, err := NewJWKS("", server.URL, time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWKS: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	keys.now = func() time.Time { return now }
+	keys.lastAttempt, keys.fetchedAt = now, now
+
+	claims := map[string]any{"iss": testIssuer, "aud": testAudience, "sub": "svc", "exp": now.Add(time.Hour).Unix(), "scope": "users:read"}
+	router := newJWTTestRouter(t, keys, now, JWTOptions{})
+
+	// Issuer xoay sang key mới
+	mu.Lock()
+	served = jwksJSON(t, oldKey, newKey)
+	mu.Unlock()
+
+	// Vừa tải JWKS xong nên kid lạ không làm tải lại ngay
+	if rec := serveWithToken(router, "/api/user/alice", newKey.sign(t, claims)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("kid lạ ngay sau khi tải JWKS: status = %d, want 401", rec.Code)
+	}
+	if got := fetchCount(); got != 1 {
+		t.Fatalf("fetches = %d, want 1", got)
+	}
+
+	now = now.Add(2 * jwksMinRefetch)
+	if rec := serveWithToken(router, "/api/user/alice", newKey.sign(t, claims)); rec.Code != http.StatusOK {
+		t.Fatalf("sau khi xoay key: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serveWithToken(router, "/api/user/alice", oldKey.sign(t, claims)); rec.Code != http.StatusOK {
+		t.Fatalf("key cũ: status = %d", rec.Code)
+	}
+	if got := fetchCount(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestJWTWithAPIKeyFallback(t *testing.T) {
+	signer := newECSigner(t, "ec-1")
+	keys, err := NewJWKS(writeJWKSFile(t, signer), "", time.Hour)
+	if err != nil {
+		t.Fatalf("NewJWKS: %v", err)
+	}
+	now := time.Now()
+	router := newJWTTestRouter(t, keys, now, JWTOptions{Optional: true})
+	apiKeys, err := NewAPIKeyAuth([]config.APIKey{{Name: "reader", Key: "reader-key", Scopes: []string{ScopeUsersRead}}}, "X-API-Key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.Use(apiKeys.Middleware)
+
+	token := signer.sign(t, map[string]any{"iss": testIssuer, "aud": testAudience, "sub": "svc", "exp": now.Add(time.Hour).Unix(), "scope": "users:read"})
+	if rec := serveWithToken(router, "/api/user/alice", token); rec.Code != http.StatusOK {
+		t.Errorf("JWT không kèm API key: status = %d", rec.Code)
+	}
+	if rec := serveWithKey(router, "/api/user/alice", "reader-key"); rec.Code != http.StatusOK {
+		t.Errorf("API key không kèm JWT: status = %d", rec.Code)
+	}
+	rec := serveWithKey(router, "/api/user/alice", "")
+	if rec.Code != http.StatusUnauthorized || errorCode(t, rec) != "API_KEY_REQUIRED" {
+		t.Errorf("không có credential: %d %s", rec.Code, rec.Body.String())
+	}
+	// JWT sai không được chuyển sang API key
+	if rec := serveWithToken(router, "/api/user/alice", "not-a-jwt"); rec.Code != http.StatusUnauthorized || errorCode(t, rec) != "INVALID_TOKEN" {
+		t.Errorf("JWT sai: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestParseJWKSRejectsWeakRSAKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseJWKS(jwksJSON(t, testSigner{kid: "weak", alg: "RS256", key: key})); err == nil {
+		t.Fatal("RSA key 1024 bit phải bị từ chối")
+	}
+}
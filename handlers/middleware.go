@@ -1,12 +1,55 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
+	"x-twitter-backend/services"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// requestIDHeader là header mang request ID để correlate logs và error responses
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID giới hạn request ID do client gửi lên để tránh log injection
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware gán request ID cho mỗi request: dùng X-Request-ID của client
+// nếu hợp lệ, nếu không thì sinh ID mới. ID được trả lại trong response header.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext trả về request ID của request, rỗng nếu không có
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
 // LoggingMiddleware log tất cả các HTTP requests
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -15,11 +58,14 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// Wrap response writer để capture status code
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		next.ServeHTTP(wrapped, r)
+		// Middleware bên trong (xác thực) ghi thêm fields, ví dụ tên API key
+		extra := &requestLog{fields: log.Fields{}}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, extra)))
 
 		duration := time.Since(start)
 
-		log.WithFields(log.Fields{
+		fields := log.Fields{
+			"request_id":  RequestIDFromContext(r.Context()),
 			"method":      r.Method,
 			"path":        r.URL.Path,
 			"query":       r.URL.RawQuery,
@@ -27,25 +73,56 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			"duration_ms": duration.Milliseconds(),
 			"ip":          r.RemoteAddr,
 			"user_agent":  r.UserAgent(),
-		}).Info("HTTP Request")
+		}
+		extra.mu.Lock()
+		for k, v := range extra.fields {
+			fields[k] = v
+		}
+		extra.mu.Unlock()
+
+		log.WithFields(fields).Info("HTTP Request")
 	})
 }
 
-// CORSMiddleware thêm CORS headers
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// CORSMiddleware thêm CORS headers. allowedOrigins chứa "*" thì cho phép mọi origin,
+// nếu không chỉ các origin được liệt kê nhận Access-Control-Allow-Origin.
+// allowHeaders là các request header bổ sung, ví dụ header chứa API key.
+func CORSMiddleware(allowedOrigins []string, allowHeaders ...string) func(http.Handler) http.Handler {
+	anyOrigin := false
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
 		}
+		origins[strings.TrimRight(origin, "/")] = true
+	}
+	headers := strings.Join(append([]string{"Content-Type", "Authorization", "X-Request-ID", "Cache-Control", "If-None-Match"}, allowHeaders...), ", ")
 
-		next.ServeHTTP(w, r)
-	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				if origin := r.Header.Get("Origin"); origins[origin] {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After, X-Cache, ETag, Last-Modified, "+
+				"X-RateLimit-Limit-Minute, X-RateLimit-Remaining-Minute, X-RateLimit-Limit-Day, X-RateLimit-Remaining-Day, "+
+				"RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy")
+
+			// Handle preflight requests
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // RecoveryMiddleware recover từ panics
@@ -53,11 +130,16 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.WithField("error", err).Error("Panic recovered")
-				
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(`{"error":"INTERNAL_ERROR","message":"Đã xảy ra lỗi không mong muốn","code":500}`))
+				log.WithFields(log.Fields{
+					"error":      err,
+					"request_id": RequestIDFromContext(r.Context()),
+				}).Error("Panic recovered")
+
+				writeError(w, r, apiError{
+					status:  http.StatusInternalServerError,
+					code:    "INTERNAL_ERROR",
+					message: "Đã xảy ra lỗi không mong muốn",
+				})
 			}
 		}()
 
@@ -76,3 +158,61 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap cho phép http.ResponseController (Flush, SetWriteDeadline) tới writer gốc
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// CacheMiddleware cho phép client bỏ qua response cache bằng Cache-Control: no-cache
+// (hoặc Pragma: no-cache) và gắn header X-Cache: HIT/MISS vào response
+func CacheMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if hasNoCache(r.Header.Get("Cache-Control")) || hasNoCache(r.Header.Get("Pragma")) {
+			ctx = services.WithCacheBypass(ctx)
+		}
+
+		rec := &services.CacheRecorder{}
+		ctx = services.WithCacheRecorder(ctx, rec)
+
+		next.ServeHTTP(&cacheStatusWriter{ResponseWriter: w, recorder: rec}, r.WithContext(ctx))
+	})
+}
+
+// hasNoCache kiểm tra header có chứa directive no-cache không
+func hasNoCache(header string) bool {
+	for _, directive := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheStatusWriter set header X-Cache ngay trước khi response header được gửi đi
+type cacheStatusWriter struct {
+	http.ResponseWriter
+	recorder    *services.CacheRecorder
+	wroteHeader bool
+}
+
+func (cw *cacheStatusWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		if status := cw.recorder.Status(); status != "" {
+			cw.Header().Set("X-Cache", status)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheStatusWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cacheStatusWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+)
+
+// PaginationLimits giới hạn auto-pagination (all=true): MaxItems là max_items lớn nhất
+// (và mặc định), Timeout là thời gian tối đa trước khi trả về cursor để gọi tiếp.
+// Response NDJSON gửi dần từng trang nên dùng StreamTimeout dài hơn.
+type PaginationLimits struct {
+	MaxItems      int
+	Timeout       time.Duration
+	StreamTimeout time.Duration
+}
+
+// DefaultPaginationLimits khớp giá trị mặc định của PAGINATE_MAX_ITEMS, PAGINATE_TIMEOUT
+// và PAGINATE_STREAM_TIMEOUT
+var DefaultPaginationLimits = PaginationLimits{
+	MaxItems:      10000,
+	Timeout:       10 * time.Second,
+	StreamTimeout: 5 * time.Minute,
+}
+
+// autoPaginate đọc all=true&max_items=N (count là kích thước mỗi trang). Trả về nil nếu client không yêu cầu
+// auto-pagination, ok=false nếu tham số không hợp lệ (đã gửi 400 cho client)
+func (h *TweetsHandler) autoPaginate(w http.ResponseWriter, r *http.Request) (*services.PaginateOptions, bool) {
+	query := r.URL.Query()
+
+	all, err := strconv.ParseBool(query.Get("all"))
+	if query.Get("all") != "" && err != nil {
+		h.respondWithError(w, r, http.StatusBadRequest, "all phải là true hoặc false", "INVALID_ALL",
+			models.InvalidParam{Name: "all", Reason: "phải là true hoặc false"})
+		return nil, false
+	}
+	if !all {
+		// Cursor gọi tiếp sau trang bị cắt bớt không phải pagination token của X API
+		if _, skip := services.SplitCursor(query.Get("pagination_token")); skip > 0 {
+			h.respondWithError(w, r, http.StatusBadRequest, "pagination_token này chỉ dùng được với all=true", "INVALID_PAGINATION_TOKEN",
+				models.InvalidParam{Name: "pagination_token", Reason: "cursor của auto-pagination chỉ dùng được với all=true"})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	maxItems := h.pagination.MaxItems
+	if value := query.Get("max_items"); value != "" {
+		maxItems, err = strconv.Atoi(value)
+		if err != nil || maxItems < 1 || maxItems > h.pagination.MaxItems {
+			h.respondWithError(w, r, http.StatusBadRequest, "max_items không hợp lệ", "INVALID_MAX_ITEMS",
+				models.InvalidParam{Name: "max_items", Reason: fmt.Sprintf("phải là số nguyên từ 1 đến %d", h.pagination.MaxItems)})
+			return nil, false
+		}
+	}
+
+	timeout := h.pagination.Timeout
+	if wantsNDJSON(r) {
+		timeout = h.pagination.StreamTimeout
+	}
+
+	// count (nếu có) là kích thước mỗi trang, mặc định lấy trang lớn nhất có thể
+	pageSize := 0
+	if query.Get("count") != "" {
+		pageSize = parseCount(query.Get("count"))
+	}
+
+	return &services.PaginateOptions{
+		MaxItems: maxItems,
+		PageSize: pageSize,
+		Deadline: time.Now().Add(timeout),
+	}, true
+}
+
+// fetchList lấy một trang, hoặc gộp các trang liên tiếp khi client yêu cầu all=true
+func fetchList[R any, T any](ctx context.Context, auto *services.PaginateOptions, count int, paginationToken string, fetch services.PageFetcher[R], page func(*R) (*[]T, **models.Meta)) (*R, error) {
+	if auto == nil {
+		return fetch(ctx, count, paginationToken)
+	}
+	return services.Paginate(ctx, *auto, paginationToken, fetch, page)
+}
+
+// Các hàm dưới đây trả về field items và meta của từng loại response cho fetchList
+
+func tweetsPage(r *models.TweetsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func followingPage(r *models.FollowingResponse) (*[]models.User, **models.Meta) {
+	return &r.Following, &r.Meta
+}
+
+func followersPage(r *models.FollowersResponse) (*[]models.User, **models.Meta) {
+	return &r.Followers, &r.Meta
+}
+
+func likedTweetsPage(r *models.LikedTweetsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func mentionsPage(r *models.MentionsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func searchTweetsPage(r *models.SearchTweetsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func searchUsersPage(r *models.SearchUsersResponse) (*[]models.User, **models.Meta) {
+	return &r.Users, &r.Meta
+}
+
+func likingUsersPage(r *models.LikingUsersResponse) (*[]models.User, **models.Meta) {
+	return &r.Users, &r.Meta
+}
+
+func quoteTweetsPage(r *models.QuoteTweetsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func retweetedByPage(r *models.RetweetedByResponse) (*[]models.User, **models.Meta) {
+	return &r.Users, &r.Meta
+}
+
+func blockingUsersPage(r *models.BlockingUsersResponse) (*[]models.User, **models.Meta) {
+	return &r.Users, &r.Meta
+}
+
+func mutingUsersPage(r *models.MutingUsersResponse) (*[]models.User, **models.Meta) {
+	return &r.Users, &r.Meta
+}
+
+func repostsPage(r *models.RepostsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// Route scopes mà API key (và các cơ chế xác thực khác) có thể được cấp
+const (
+	ScopeTweetsRead  = "tweets:read"
+	ScopeTweetsWrite = "tweets:write"
+	ScopeUsersRead   = "users:read"
+	ScopeAdmin       = "admin"
+	// ScopeAll cho phép mọi route
+	ScopeAll = "*"
+)
+
+// knownScopes dùng để kiểm tra cấu hình, tránh scope gõ sai âm thầm không có tác dụng
+var knownScopes = map[string]bool{
+	ScopeTweetsRead:  true,
+	ScopeTweetsWrite: true,
+	ScopeUsersRead:   true,
+	ScopeAdmin:       true,
+	ScopeAll:         true,
+}
+
+// Principal là client đã xác thực của request
+type Principal struct {
+	// Kind là cơ chế xác thực, ví dụ api_key
+	Kind   string
+	Name   string
+	Scopes []string
+}
+
+// Allows cho biết principal có được gọi route cần scope không
+func (p *Principal) Allows(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == ScopeAll || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext trả về client đã xác thực của request, nil nếu /api không
+// yêu cầu xác thực
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// routeScope trả về scope cần để gọi route của request (theo path template của mux
+// nên username như "tweets" không bị nhầm); rỗng với route công khai (/api/docs và
+// mọi route ngoài /api)
+func routeScope(r *http.Request) string {
+	path := routeTemplate(r)
+	switch {
+	case !strings.HasPrefix(path, "/api/"), path == "/api/docs":
+		return ""
+	case path == "/api/ratelimits", strings.HasPrefix(path, "/api/admin/"):
+		return ScopeAdmin
+	case strings.HasPrefix(path, "/api/tweets"):
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return ScopeTweetsWrite
+		}
+		return ScopeTweetsRead
+	case strings.HasSuffix(path, "/tweets"),
+		strings.HasSuffix(path, "/mentions"),
+		strings.HasSuffix(path, "/liked"),
+		strings.HasSuffix(path, "/timelines/reverse_chronological"),
+		path == "/api/users/reposts_of_me":
+		// Routes theo user nhưng trả về tweets
+		return ScopeTweetsRead
+	default:
+		return ScopeUsersRead
+	}
+}
+
+// routeTemplate trả về path template của route mux đã match, hoặc URL path nếu chưa match
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// requestLog là các field được middleware bên trong (ví dụ xác thực) thêm vào dòng
+// log "HTTP Request" của LoggingMiddleware
+type requestLog struct {
+	mu     sync.Mutex
+	fields log.Fields
+}
+
+type requestLogKey struct{}
+
+// addLogFields thêm fields vào dòng log của request; không làm gì nếu request không
+// đi qua LoggingMiddleware
+func addLogFields(ctx context.Context, fields log.Fields) {
+	rl, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for k, v := range fields {
+		rl.fields[k] = v
+	}
+}
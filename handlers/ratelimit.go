@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Nhóm route có bucket riêng trong RateLimiter
+const (
+	RouteGroupDefault = "default"
+	// RouteGroupSearch là search và counts, có quota X API thấp nhất
+	RouteGroupSearch = "search"
+	RouteGroupWrite  = "write"
+	RouteGroupAdmin  = "admin"
+)
+
+// rateLimitSweepInterval là chu kỳ xóa các bucket đã đầy (client không gửi request
+// trong thời gian nạp đầy bucket), để số buckets không tăng theo mọi IP từng gọi API
+const rateLimitSweepInterval = time.Minute
+
+var inboundRateLimited = metrics.NewCounter("x_api_inbound_rate_limited_total",
+	"Số request /api bị từ chối 429 do vượt giới hạn của client theo nhóm route và loại client (api_key, jwt, ip)",
+	"group", "client")
+
+// routeGroup trả về nhóm route của request; rỗng với route công khai
+func routeGroup(r *http.Request) string {
+	scope := routeScope(r)
+	path := routeTemplate(r)
+	switch {
+	case scope == "":
+		return ""
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+		return RouteGroupWrite
+	case scope == ScopeAdmin:
+		return RouteGroupAdmin
+	case strings.HasSuffix(path, "/search"), strings.Contains(path, "/search/"), strings.Contains(path, "/counts/"):
+		return RouteGroupSearch
+	default:
+		return RouteGroupDefault
+	}
+}
+
+// tokenBucket là số request còn lại của một client trong một nhóm route
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// bucketStatus là trạng thái bucket sau khi xét request hiện tại
+type bucketStatus struct {
+	allowed   bool
+	remaining int
+	// retryAfter là thời gian tới khi có lại một request (0 nếu allowed)
+	retryAfter time.Duration
+	// resetAfter là thời gian tới khi bucket đầy lại
+	resetAfter time.Duration
+}
+
+// take nạp lại bucket theo thời gian đã trôi qua rồi lấy một token nếu còn
+func (b *tokenBucket) take(limit config.RateLimit, now time.Time) bucketStatus {
+	perToken := limit.Period / time.Duration(limit.Requests)
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+float64(elapsed)/float64(perToken))
+	}
+	b.updated = now
+
+	status := bucketStatus{allowed: b.tokens >= 1}
+	if status.allowed {
+		b.tokens--
+	} else {
+		status.retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	status.remaining = int(b.tokens)
+	status.resetAfter = time.Duration((float64(limit.Burst) - b.tokens) * float64(perToken))
+	return status
+}
+
+// full cho biết bucket đã nạp đầy tại now, tức có thể xóa mà không đổi hành vi
+func (b *tokenBucket) full(limit config.RateLimit, now time.Time) bool {
+	perToken := limit.Period / time.Duration(limit.Requests)
+	return b.tokens+float64(now.Sub(b.updated))/float64(perToken) >= float64(limit.Burst)
+}
+
+// RateLimiter giới hạn request vào /api bằng token bucket riêng cho mỗi client và mỗi
+// nhóm route. Client là API key hoặc JWT subject đã xác thực (nên RateLimiter chạy sau
+// middleware xác thực), hoặc client IP khi request không có credential. Buckets được
+// giữ trong bộ nhớ của từng instance.
+type RateLimiter struct {
+	limits  map[string]config.RateLimit
+	trusted []*net.IPNet
+	now     func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter tạo RateLimiter với giới hạn theo nhóm route (nhóm không có trong
+// limits hoặc không Enabled thì không bị giới hạn). trustedProxies là IP/CIDR của
+// reverse proxy được tin X-Forwarded-For.
+func NewRateLimiter(limits map[string]config.RateLimit, trustedProxies []string) (*RateLimiter, error) {
+	l := &RateLimiter{
+		limits:  make(map[string]config.RateLimit),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+	for group, limit := range limits {
+		switch group {
+		case RouteGroupDefault, RouteGroupSearch, RouteGroupWrite, RouteGroupAdmin:
+		default:
+			return nil, fmt.Errorf("nhóm route không hợp lệ: %q", group)
+		}
+		if limit.Enabled() {
+			l.limits[group] = limit
+		}
+	}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q không hợp lệ: %w", proxy, err)
+		}
+		l.trusted = append(l.trusted, network)
+	}
+	return l, nil
+}
+
+// Middleware từ chối request vượt giới hạn với 429 RATE_LIMIT_EXCEEDED và gắn
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy vào response
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := routeGroup(r)
+		limit, ok := l.limits[group]
+		if r.Method == http.MethodOptions || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		kind, client := l.client(r)
+		now := l.now()
+		status := l.take(group+"|"+kind+":"+client, limit, now)
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(status.remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(status.resetAfter)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, ceilSeconds(limit.Period), limit.Burst))
+		if status.allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		inboundRateLimited.Inc(group, kind)
+		retryAfter := ceilSeconds(status.retryAfter)
+		log.WithFields(log.Fields{
+			"request_id": RequestIDFromContext(r.Context()),
+			"group":      group,
+			"client":     kind + ":" + client,
+		}).Debug("Client vượt giới hạn request")
+		h.Set("Retry-After", strconv.Itoa(retryAfter))
+		writeError(w, r, apiError{
+			status:  http.StatusTooManyRequests,
+			code:    "RATE_LIMIT_EXCEEDED",
+			message: fmt.Sprintf("Vượt giới hạn %d requests mỗi %s cho nhóm route %s, thử lại sau %d giây", limit.Requests, limit.Period, group, retryAfter),
+		})
+	})
+}
+
+// client trả về loại và định danh của client: principal đã xác thực hoặc client IP
+func (l *RateLimiter) client(r *http.Request) (kind, id string) {
+	if p := PrincipalFromContext(r.Context()); p != nil {
+		return p.Kind, p.Name
+	}
+	return "ip", l.clientIP(r)
+}
+
+// clientIP trả về RemoteAddr, hoặc khi RemoteAddr là trusted proxy thì địa chỉ đầu
+// tiên không phải trusted proxy tính từ cuối X-Forwarded-For (các phần tử phía trước
+// do client tự gửi nên không đáng tin)
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !l.isTrusted(ip) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		client = hop.String()
+		if !l.isTrusted(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func (l *RateLimiter) isTrusted(ip net.IP) bool {
+	for _, network := range l.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *RateLimiter) take(key string, limit config.RateLimit, now time.Time) bucketStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweepLocked(now)
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = bucket
+	}
+	return bucket.take(limit, now)
+}
+
+func (l *RateLimiter) sweepLocked(now time.Time) {
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		group, _, _ := strings.Cut(key, "|")
+		if bucket.full(l.limits[group], now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+
+	"github.com/gorilla/mux"
+)
+
+func newRateLimitTestRouter(t *testing.T, now *time.Time, limits map[string]config.RateLimit, trustedProxies ...string) (*mux.Router, *RateLimiter) {
+	t.Helper()
+	limiter, err := NewRateLimiter(limits, trustedProxies)
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	limiter.now = func() time.Time { return *now }
+
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	router.Use(limiter.Middleware)
+	return router, limiter
+}
+
+func serveFrom(router http.Handler, target, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router, _ := newRateLimitTestRouter(t, &now, map[string]config.RateLimit{
+		RouteGroupDefault: {Requests: 2, Period: time.Minute, Burst: 2},
+		RouteGroupSearch:  {Requests: 1, Period: time.Minute, Burst: 1},
+	})
+	const client = "198.51.100.7:4000"
+
+	for i := 0; i < 2; i++ {
+		rec := serveFrom(router, "/api/user/alice", client, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request #%d: status = %d", i+1, rec.Code)
+		}
+		if got, want := rec.Header().Get("RateLimit-Remaining"), []string{"1", "0"}[i]; got != want {
+			t.Errorf("request #%d: RateLimit-Remaining = %s, want %s", i+1, got, want)
+		}
+	}
+
+	rec := serveFrom(router, "/api/user/alice", client, nil)
+	if rec.Code != http.StatusTooManyRequests || errorCode(t, rec) != "RATE_LIMIT_EXCEEDED" {
+		t.Fatalf("vượt giới hạn: %d %s", rec.Code, rec.Body.String())
+	}
+	for header, want := range map[string]string{
+		"Retry-After":         "30",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "2;w=60;burst=2",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// Nhóm route khác và client khác có bucket riêng
+	if rec := serveFrom(router, "/api/tweets/search/recent?q=golang", client, nil); rec.Code == http.StatusTooManyRequests {
+		t.Errorf("search bị ảnh hưởng bởi bucket default: %d", rec.Code)
+	}
+	if rec := serveFrom(router, "/api/tweets/counts/recent?q=golang", client, nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("counts dùng chung bucket search: status = %d, want 429", rec.Code)
+	}
+	if rec := serveFrom(router, "/api/user/alice", "198.51.100.8:4000", nil); rec.Code != http.StatusOK {
+		t.Errorf("IP khác bị ảnh hưởng: %d", rec.Code)
+	}
+
+	// Mỗi 30 giây nạp lại một request
+	now = now.Add(30 * time.Second)
+	if rec := serveFrom(router, "/api/user/alice", client, nil); rec.Code != http.StatusOK {
+		t.Fatalf("sau 30s: status = %d", rec.Code)
+	}
+	if rec := serveFrom(router, "/api/user/alice", client, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("sau 30s chỉ có một request: status = %d", rec.Code)
+	}
+}
+
+func TestRateLimiterKeysByPrincipal(t *testing.T) {
+	limiter, err := NewRateLimiter(map[string]config.RateLimit{
+		RouteGroupDefault: {Requests: 1, Period: time.Minute, Burst: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	auth, err := NewAPIKeyAuth([]config.APIKey{
+		{Name: "a", Key: "key-a", Scopes: []string{ScopeAll}},
+		{Name: "b", Key: "key-b", Scopes: []string{ScopeAll}},
+	}, "X-API-Key", "")
+	if err != nil {
+		t.Fatalf("NewAPIKeyAuth: %v", err)
+	}
+	// Xác thực chạy trước rate limiter như trong main.go
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	router.Use(auth.Middleware)
+	router.Use(limiter.Middleware)
+
+	// Hai key sau cùng một IP (ví dụ NAT) không dùng chung bucket
+	for _, key := range []string{"key-a", "key-b"} {
+		if rec := serveWithKey(router, "/api/user/alice", key); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", key, rec.Code)
+		}
+	}
+	if rec := serveWithKey(router, "/api/user/alice", "key-a"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("key-a lần hai: status = %d, want 429", rec.Code)
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter, err := NewRateLimiter(nil, []string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{"không có proxy", "203.0.113.9:1234", nil, "203.0.113.9"},
+		{"client tự gửi XFF", "203.0.113.9:1234", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"qua trusted proxy", "10.0.0.5:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"XFF giả mạo phía trước", "10.0.0.5:1234", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"nhiều trusted proxy", "10.0.0.5:1234", []string{"198.51.100.1, 10.1.1.1", "10.2.2.2"}, "198.51.100.1"},
+		{"trusted proxy IPv6", "[2001:db8::1]:443", []string{"2001:db8::42"}, "2001:db8::42"},
+		{"XFF không hợp lệ", "10.0.0.5:1234", []string{"garbage, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy không gửi XFF", "10.0.0.5:1234", nil, "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/user/alice", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := limiter.clientIP(req); got != tt.want {
+				t.Errorf("clientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router, limiter := newRateLimitTestRouter(t, &now, map[string]config.RateLimit{
+		RouteGroupDefault: {Requests: 10, Period: time.Minute, Burst: 10},
+	})
+	for i := 0; i < 5; i++ {
+		serveFrom(router, "/api/user/alice", fmt.Sprintf("198.51.100.%d:1", i+1), nil)
+	}
+	if len(limiter.buckets) != 5 {
+		t.Fatalf("buckets = %d, want 5", len(limiter.buckets))
+	}
+
+	now = now.Add(2 * time.Minute)
+	serveFrom(router, "/api/user/alice", "198.51.100.9:1", nil)
+	if len(limiter.buckets) != 1 {
+		t.Errorf("buckets sau sweep = %d, want 1", len(limiter.buckets))
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import "github.com/gorilla/mux"
+
+// RegisterRoutes gắn CacheMiddleware và đăng ký các route của TweetsHandler lên
+// subrouter /api. Middleware xác thực, rate limit và session phải được gắn vào api
+// trước khi gọi để chạy trước cache. adminRoutes bật /ratelimits và /admin/*, chỉ
+// nên true khi có xác thực cấp được scope admin.
+func (h *TweetsHandler) RegisterRoutes(api *mux.Router, adminRoutes bool) {
+	api.Use(CacheMiddleware)
+
+	// User routes
+	api.HandleFunc("/user/{username}", h.GetUserInfo).Methods("GET")
+	api.HandleFunc("/user/{username}/following", h.GetUserFollowing).Methods("GET")
+	api.HandleFunc("/user/{username}/followers", h.GetUserFollowers).Methods("GET")
+	api.HandleFunc("/user/{username}/liked", h.GetLikedTweets).Methods("GET")
+	api.HandleFunc("/user/{username}/mentions", h.GetUserMentions).Methods("GET")
+	api.HandleFunc("/user/{username}/timelines/reverse_chronological", h.GetUserTimelineReverseChronological).Methods("GET")
+	api.HandleFunc("/user/{username}/tweets", h.GetUserTweets).Methods("GET")
+	api.HandleFunc("/user/{username}/blocking", h.GetBlockingUsers).Methods("GET")
+	api.HandleFunc("/user/{username}/muting", h.GetMutingUsers).Methods("GET")
+
+	// Users routes
+	// Các path cố định phải đăng ký trước /users/{user_id}, nếu không sẽ bị route đó nuốt mất
+	api.HandleFunc("/users", h.ListUsers).Methods("GET")
+	api.HandleFunc("/users/me", h.GetMe).Methods("GET")
+	api.HandleFunc("/users/search", h.SearchUsers).Methods("GET")
+	api.HandleFunc("/users/reposts_of_me", h.GetRepostsOfMe).Methods("GET")
+	api.HandleFunc("/users/by/username/{username}", h.GetUserInfo).Methods("GET")
+	api.HandleFunc("/users/{user_id}", h.GetUserByID).Methods("GET")
+
+	// Tweets routes
+	api.HandleFunc("/tweets", h.ListTweets).Methods("GET")
+	api.HandleFunc("/tweets/user/{username}", h.GetUserTweets).Methods("GET")
+	api.HandleFunc("/tweets/search", h.SearchTweets).Methods("GET")
+	api.HandleFunc("/tweets/search/recent", h.SearchTweets).Methods("GET")
+	api.HandleFunc("/tweets/search/all", h.SearchAllTweets).Methods("GET")
+	api.HandleFunc("/tweets/{tweet_id}", h.GetTweetByID).Methods("GET")
+	api.HandleFunc("/tweets/{tweet_id}/liking_users", h.GetLikingUsers).Methods("GET")
+	api.HandleFunc("/tweets/{tweet_id}/quote_tweets", h.GetQuoteTweets).Methods("GET")
+	api.HandleFunc("/tweets/{tweet_id}/retweeted_by", h.GetRetweetedBy).Methods("GET")
+	api.HandleFunc("/tweets/{tweet_id}/hidden", h.HideTweet).Methods("PUT")
+	api.HandleFunc("/tweets/counts/recent", h.GetTweetCounts).Methods("GET")
+	api.HandleFunc("/tweets/counts/all", h.GetAllTweetCounts).Methods("GET")
+
+	// Rate limits của X API và mức sử dụng Bearer Tokens
+	if adminRoutes {
+		api.HandleFunc("/ratelimits", h.GetRateLimits).Methods("GET")
+		api.HandleFunc("/admin/tokens", h.GetTokenPool).Methods("GET")
+	}
+}
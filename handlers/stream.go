@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ndjsonContentType là media type của response streaming: mỗi dòng là một JSON object
+const ndjsonContentType = "application/x-ndjson"
+
+// streamWriteGrace là thời gian ghi trailer sau deadline của auto-pagination
+const streamWriteGrace = 5 * time.Second
+
+// wantsNDJSON kiểm tra client có yêu cầu streaming NDJSON qua header Accept không
+func wantsNDJSON(r *http.Request) bool {
+	return accepts(r, ndjsonContentType)
+}
+
+// streamList gửi items của một hoặc nhiều trang (all=true) dưới dạng NDJSON: mỗi item
+// một dòng, flush sau mỗi trang, dòng cuối là models.StreamTrailer chứa meta. Lỗi ở
+// trang đầu được trả về như response lỗi thông thường; lỗi sau khi đã gửi items được
+// ghi vào trailer vì status code đã gửi đi.
+func streamList[R any, T any](h *TweetsHandler, w http.ResponseWriter, r *http.Request, auto *services.PaginateOptions, count int, paginationToken string, fetch services.PageFetcher[R], page func(*R) (*[]T, **models.Meta), message string) {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	started := false
+
+	visit := func(_ *R, items []T) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+		}
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		// Writer không hỗ trợ flush (ví dụ trong test) vẫn nhận đủ dữ liệu khi kết thúc
+		if err := rc.Flush(); err != nil && err != http.ErrNotSupported {
+			return err
+		}
+		return nil
+	}
+
+	var (
+		meta *models.Meta
+		err  error
+	)
+	if auto == nil {
+		meta, err = streamPage(ctx, count, paginationToken, fetch, page, visit)
+	} else {
+		// Response streaming được phép kéo dài hơn WriteTimeout của server
+		rc.SetWriteDeadline(auto.Deadline.Add(streamWriteGrace))
+		meta, err = services.WalkPages(ctx, *auto, paginationToken, fetch, page, visit)
+	}
+
+	if err != nil && !started {
+		log.WithError(err).Error(message)
+		h.respondWithServiceError(w, r, err, message)
+		return
+	}
+
+	trailer := models.StreamTrailer{Meta: meta}
+	if err != nil {
+		log.WithError(err).Error(message)
+		if ctx.Err() != nil {
+			return // client đã ngắt kết nối
+		}
+		e := apiError{status: http.StatusInternalServerError, code: "INTERNAL_ERROR", message: message}
+		if apiErr, ok := services.AsAPIError(err); ok {
+			e = serviceError(apiErr)
+		}
+		trailer.Meta = &models.Meta{}
+		trailer.Error = &models.ErrorResponse{
+			Error:     e.code,
+			Message:   e.message,
+			Detail:    e.upstreamDetail,
+			Code:      e.status,
+			RequestID: RequestIDFromContext(ctx),
+		}
+	}
+
+	if err := enc.Encode(trailer); err != nil {
+		log.WithError(err).Error("Lỗi khi ghi trailer của NDJSON response")
+	}
+}
+
+// streamPage lấy đúng một trang (không có all=true) và trả về meta của trang đó
+func streamPage[R any, T any](ctx context.Context, count int, paginationToken string, fetch services.PageFetcher[R], page func(*R) (*[]T, **models.Meta), visit func(*R, []T) error) (*models.Meta, error) {
+	resp, err := fetch(ctx, count, paginationToken)
+	if err != nil {
+		return nil, err
+	}
+	items, meta := page(resp)
+	if err := visit(resp, *items); err != nil {
+		return nil, err
+	}
+	return *meta, nil
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+)
+
+// decodeNDJSON tách body NDJSON thành các dòng item và trailer ở dòng cuối
+func decodeNDJSON(t *testing.T, body []byte) ([]json.RawMessage, models.StreamTrailer) {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimSpace(body), []byte("\n"))
+	var trailer models.StreamTrailer
+	if err := json.Unmarshal(lines[len(lines)-1], &trailer); err != nil {
+		t.Fatalf("trailer không hợp lệ: %v", err)
+	}
+	items := make([]json.RawMessage, 0, len(lines)-1)
+	for _, line := range lines[:len(lines)-1] {
+		items = append(items, line)
+	}
+	return items, trailer
+}
+
+func serveNDJSON(t *testing.T, router http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("Accept", ndjsonContentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func newStreamFake(n int) *services.FakeTwitterService {
+	fake := newTestFake()
+	created := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		fake.AddTweet(models.Tweet{ID: strconv.Itoa(200 + i), Text: "golang", AuthorID: "1", CreatedAt: created.Add(time.Duration(i) * time.Minute)})
+	}
+	return fake
+}
+
+func TestStreamNDJSONSinglePage(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newStreamFake(3)))
+
+	rec := serveNDJSON(t, router, "/api/user/alice/tweets?count=2")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ndjsonContentType {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("ETag") != "" {
+		t.Error("response streaming không được có ETag")
+	}
+
+	items, trailer := decodeNDJSON(t, rec.Body.Bytes())
+	var tweet models.Tweet
+	if err := json.Unmarshal(items[0], &tweet); err != nil || tweet.ID == "" {
+		t.Errorf("dòng đầu không phải tweet: %s", items[0])
+	}
+	if len(items) != 2 || trailer.Meta == nil || trailer.Meta.NextToken != "2" || trailer.Error != nil {
+		t.Errorf("%d items, trailer = %+v", len(items), trailer)
+	}
+}
+
+func TestStreamNDJSONAllPages(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newStreamFake(6)))
+
+	rec := serveNDJSON(t, router, "/api/user/alice/tweets?count=2&all=true&max_items=5")
+	items, trailer := decodeNDJSON(t, rec.Body.Bytes())
+	if len(items) != 5 {
+		t.Fatalf("items = %d, want 5", len(items))
+	}
+	want := models.Meta{ResultCount: 5, NextToken: "5", Pages: 3, StopReason: services.StopReasonMaxItems}
+	if trailer.Meta == nil || *trailer.Meta != want {
+		t.Errorf("trailer meta = %+v, want %+v", trailer.Meta, want)
+	}
+
+	// Users cũng stream được, list rỗng vẫn có trailer
+	rec = serveNDJSON(t, router, "/api/user/alice/followers?all=true")
+	items, trailer = decodeNDJSON(t, rec.Body.Bytes())
+	if rec.Code != http.StatusOK || len(items) != 0 || trailer.Meta == nil || trailer.Meta.ResultCount != 0 {
+		t.Errorf("followers rỗng: status = %d, %d items, trailer = %+v", rec.Code, len(items), trailer)
+	}
+}
+
+func TestStreamNDJSONFirstPageError(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newStreamFake(0)))
+
+	rec := serveNDJSON(t, router, "/api/user/nobody/tweets?all=true")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("status = %d, Content-Type = %q; want 404 JSON", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestStreamNDJSONMidStreamError(t *testing.T) {
+	fake := newStreamFake(4)
+	slow := &pageGateTwitterAPI{TwitterAPI: fake, release: make(chan struct{})}
+	slow.err = services.NewNotFoundError(services.CodeUserNotFound, "user đã bị xóa")
+	close(slow.release)
+	router := newTestRouter(NewTweetsHandler(slow))
+
+	rec := serveNDJSON(t, router, "/api/user/alice/tweets?count=2&all=true")
+	items, trailer := decodeNDJSON(t, rec.Body.Bytes())
+	if rec.Code != http.StatusOK || len(items) != 2 {
+		t.Fatalf("status = %d, %d items", rec.Code, len(items))
+	}
+	if trailer.Error == nil || trailer.Error.Error != services.CodeUserNotFound || trailer.Error.Code != http.StatusNotFound {
+		t.Errorf("trailer = %+v, want lỗi USER_NOT_FOUND", trailer)
+	}
+}
+
+// pageGateTwitterAPI chặn các trang sau trang đầu của GetUserTweets cho tới khi
+// release được đóng, sau đó trả về err nếu có
+type pageGateTwitterAPI struct {
+	services.TwitterAPI
+	release chan struct{}
+	err     error
+}
+
+func (p *pageGateTwitterAPI) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter services.TweetFilter) (*models.TweetsResponse, error) {
+	if paginationToken != "" {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+	}
+	return p.TwitterAPI.GetUserTweets(ctx, username, maxResults, paginationToken, filter)
+}
+
+func TestStreamNDJSONFlushesProgressively(t *testing.T) {
+	slow := &pageGateTwitterAPI{TwitterAPI: newStreamFake(4), release: make(chan struct{})}
+	router := newTestRouter(NewTweetsHandler(slow))
+	router.Use(LoggingMiddleware)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/user/alice/tweets?count=2&all=true", nil)
+	req.Header.Set("Accept", ndjsonContentType)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// Trang đầu tới client trong khi trang sau vẫn đang chờ upstream
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-lines:
+		case <-time.After(time.Second):
+			t.Fatal("trang đầu chưa được flush tới client")
+		}
+	}
+
+	close(slow.release)
+	count := 2
+	var last string
+	for line := range lines {
+		count++
+		last = line
+	}
+	var trailer models.StreamTrailer
+	if err := json.Unmarshal([]byte(last), &trailer); err != nil || trailer.Meta == nil || trailer.Meta.ResultCount != 5 {
+		t.Errorf("trailer = %s (%d dòng)", last, count)
+	}
+}
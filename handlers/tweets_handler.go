@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"x-twitter-backend/models"
 	"x-twitter-backend/services"
 
@@ -14,44 +18,83 @@ import (
 
 // TweetsHandler xử lý các HTTP requests liên quan đến tweets
 type TweetsHandler struct {
-	twitterService *services.TwitterService
+	twitterService services.TwitterAPI
+	maxAges        CacheMaxAges
+	pagination     PaginationLimits
 }
 
 // NewTweetsHandler tạo một instance mới của TweetsHandler
-func NewTweetsHandler(twitterService *services.TwitterService) *TweetsHandler {
+func NewTweetsHandler(twitterService services.TwitterAPI) *TweetsHandler {
 	return &TweetsHandler{
 		twitterService: twitterService,
+		maxAges:        DefaultCacheMaxAges,
+		pagination:     DefaultPaginationLimits,
 	}
 }
 
+// SetCacheMaxAges đặt max-age của header Cache-Control theo nhóm endpoint
+func (h *TweetsHandler) SetCacheMaxAges(maxAges CacheMaxAges) {
+	h.maxAges = maxAges
+}
+
+// SetPaginationLimits đặt giới hạn cho auto-pagination (all=true)
+func (h *TweetsHandler) SetPaginationLimits(limits PaginationLimits) {
+	h.pagination = limits
+}
+
 // GetUserTweets xử lý request lấy tweets của một user
-// GET /api/tweets/user/{username}?count=10
+// GET /api/tweets/user/{username}?count=10&pagination_token=xxx[&all=true&max_items=N]
+// [&start_time=2024-01-01T00:00:00Z&end_time=...&since_id=123&until_id=456]
 func (h *TweetsHandler) GetUserTweets(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.tweetFilter(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"start_time": filter.StartTime,
+		"since_id":   filter.SinceID,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy tweets")
 
 	// Gọi service để lấy tweets
-	response, err := h.twitterService.GetUserTweets(r.Context(), username, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		return h.twitterService.GetUserTweets(ctx, username, maxResults, token, filter)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, tweetsPage, "Không thể lấy tweets")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, tweetsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy tweets")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy tweets: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy tweets")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetUserInfo xử lý request lấy thông tin user
@@ -61,7 +104,7 @@ func (h *TweetsHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
@@ -74,42 +117,58 @@ func (h *TweetsHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	user, err := h.twitterService.GetUserByUsername(r.Context(), username)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy thông tin user")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy thông tin user: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy thông tin user")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, user)
+	h.respondCacheable(w, cacheProfile, user)
 }
 
 // GetUserFollowing xử lý request lấy danh sách tài khoản mà user đang theo dõi
-// GET /api/user/{username}/following?count=50&pagination_token=xxx
+// GET /api/user/{username}/following?count=50&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetUserFollowing(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
 		"username":   username,
 		"count":      count,
 		"page_token": paginationToken,
+		"all":        auto != nil,
 		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy danh sách following")
 
-	response, err := h.twitterService.GetUserFollowing(r.Context(), username, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.FollowingResponse, error) {
+		return h.twitterService.GetUserFollowing(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, followingPage, "Không thể lấy danh sách following")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, followingPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy danh sách following")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy danh sách following: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy danh sách following")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheProfile, response)
 }
 
 // HealthCheck xử lý health check request
@@ -120,11 +179,33 @@ func (h *TweetsHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"service": "X Twitter Backend API",
 		"version": "1.0.0",
 	}
+
+	// Trạng thái upstream: "degraded" nếu có circuit breaker không ở trạng thái closed
+	upstream := map[string]interface{}{"status": "ok"}
+	if breakers, err := h.twitterService.GetCircuitBreakers(r.Context()); err == nil {
+		tripped := make([]models.CircuitBreakerStatus, 0)
+		for _, b := range breakers.CircuitBreakers {
+			if b.State != services.CircuitClosed {
+				tripped = append(tripped, b)
+			}
+		}
+		if len(tripped) > 0 {
+			upstream["status"] = "degraded"
+		}
+		upstream["open_circuits"] = tripped
+	}
+	response["upstream"] = upstream
+
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
 // respondWithJSON gửi JSON response
 func (h *TweetsHandler) respondWithJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
+	writeJSON(w, statusCode, payload)
+}
+
+// writeJSON gửi JSON response với status code
+func writeJSON(w http.ResponseWriter, statusCode int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
@@ -133,73 +214,117 @@ func (h *TweetsHandler) respondWithJSON(w http.ResponseWriter, statusCode int, p
 	}
 }
 
-// respondWithError gửi error response
-func (h *TweetsHandler) respondWithError(w http.ResponseWriter, statusCode int, message, code string) {
-	errorResponse := models.ErrorResponse{
-		Error:   code,
-		Message: message,
-		Code:    statusCode,
-	}
-	h.respondWithJSON(w, statusCode, errorResponse)
-}
-
 // GetUserFollowers xử lý request lấy danh sách followers
-// GET /api/user/{username}/followers?count=50&pagination_token=xxx
+// GET /api/user/{username}/followers?count=50&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetUserFollowers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
 		"username":   username,
 		"count":      count,
 		"page_token": paginationToken,
+		"all":        auto != nil,
 		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy danh sách followers")
 
-	response, err := h.twitterService.GetUserFollowers(r.Context(), username, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.FollowersResponse, error) {
+		return h.twitterService.GetUserFollowers(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, followersPage, "Không thể lấy danh sách followers")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, followersPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy danh sách followers")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy danh sách followers: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy danh sách followers")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheProfile, response)
 }
 
-// SearchTweets xử lý request tìm kiếm tweets
-// GET /api/tweets/search?q=golang&count=20
+// SearchTweets xử lý request tìm kiếm tweets trong 7 ngày gần nhất
+// GET /api/tweets/search?q=golang&count=20&pagination_token=xxx[&all=true&max_items=N]
+// [&start_time=2024-01-01T00:00:00Z&end_time=...&since_id=123&until_id=456]
 func (h *TweetsHandler) SearchTweets(w http.ResponseWriter, r *http.Request) {
+	h.searchTweets(w, r, h.twitterService.SearchTweets, "Không thể tìm kiếm tweets")
+}
+
+// SearchAllTweets xử lý request tìm kiếm tweets trong toàn bộ lịch sử (full-archive),
+// cùng tham số với SearchTweets, count tối đa 500
+// GET /api/tweets/search/all?q=golang&count=100&start_time=2015-01-01T00:00:00Z
+func (h *TweetsHandler) SearchAllTweets(w http.ResponseWriter, r *http.Request) {
+	h.searchTweets(w, r, h.twitterService.SearchAllTweets, "Không thể tìm kiếm tweets full-archive")
+}
+
+// searchTweets đọc tham số chung của recent và full-archive search rồi gọi search
+func (h *TweetsHandler) searchTweets(w http.ResponseWriter, r *http.Request, search func(ctx context.Context, query string, maxResults int, paginationToken string, filter services.TweetFilter) (*models.SearchTweetsResponse, error), message string) {
 	query := r.URL.Query().Get("q")
 
 	if query == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY")
+		h.respondWithError(w, r, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY", requiredParam("q"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.tweetFilter(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"query": query,
-		"count": count,
-		"ip":    r.RemoteAddr,
+		"path":       r.URL.Path,
+		"query":      query,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"start_time": filter.StartTime,
+		"since_id":   filter.SinceID,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request tìm kiếm tweets")
 
-	response, err := h.twitterService.SearchTweets(r.Context(), query, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.SearchTweetsResponse, error) {
+		return search(ctx, query, maxResults, token, filter)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, searchTweetsPage, message)
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, searchTweetsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi tìm kiếm tweets")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể tìm kiếm tweets: "+err.Error(), "SEARCH_ERROR")
+		h.respondWithServiceError(w, r, err, message)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheSearch, response)
 }
 
 // GetTweetByID xử lý request lấy chi tiết tweet
@@ -209,7 +334,7 @@ func (h *TweetsHandler) GetTweetByID(w http.ResponseWriter, r *http.Request) {
 	tweetID := vars["tweet_id"]
 
 	if tweetID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID", requiredParam("tweet_id"))
 		return
 	}
 
@@ -221,97 +346,158 @@ func (h *TweetsHandler) GetTweetByID(w http.ResponseWriter, r *http.Request) {
 	response, err := h.twitterService.GetTweetByID(r.Context(), tweetID)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy chi tiết tweet")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy chi tiết tweet: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy chi tiết tweet")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetLikedTweets xử lý request lấy liked tweets
-// GET /api/user/{username}/liked?count=20
+// GET /api/user/{username}/liked?count=20&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetLikedTweets(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy liked tweets")
 
-	response, err := h.twitterService.GetLikedTweets(r.Context(), username, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.LikedTweetsResponse, error) {
+		return h.twitterService.GetLikedTweets(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, likedTweetsPage, "Không thể lấy liked tweets")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, likedTweetsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy liked tweets")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy liked tweets: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy liked tweets")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // SearchUsers xử lý request tìm kiếm users
-// GET /api/users/search?q=elon&count=10
+// GET /api/users/search?q=elon&count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 
 	if query == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY")
+		h.respondWithError(w, r, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY", requiredParam("q"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"query": query,
-		"count": count,
-		"ip":    r.RemoteAddr,
+		"query":      query,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request tìm kiếm users")
 
-	response, err := h.twitterService.SearchUsers(r.Context(), query, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.SearchUsersResponse, error) {
+		return h.twitterService.SearchUsers(ctx, query, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, searchUsersPage, "Không thể tìm kiếm users")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, searchUsersPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi tìm kiếm users")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể tìm kiếm users: "+err.Error(), "SEARCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể tìm kiếm users")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheSearch, response)
 }
 
 // GetUserMentions xử lý request lấy mentions
-// GET /api/user/{username}/mentions?count=20
+// GET /api/user/{username}/mentions?count=20&pagination_token=xxx[&all=true&max_items=N]
+// [&start_time=2024-01-01T00:00:00Z&end_time=...&since_id=123&until_id=456]
 func (h *TweetsHandler) GetUserMentions(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	filter, ok := h.tweetFilter(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"start_time": filter.StartTime,
+		"since_id":   filter.SinceID,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy mentions")
 
-	response, err := h.twitterService.GetUserMentions(r.Context(), username, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.MentionsResponse, error) {
+		return h.twitterService.GetUserMentions(ctx, username, maxResults, token, filter)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, mentionsPage, "Không thể lấy mentions")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, mentionsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy mentions")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy mentions: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy mentions")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // ListTweets xử lý request lấy danh sách tweets theo IDs
@@ -319,7 +505,7 @@ func (h *TweetsHandler) GetUserMentions(w http.ResponseWriter, r *http.Request)
 func (h *TweetsHandler) ListTweets(w http.ResponseWriter, r *http.Request) {
 	idsParam := r.URL.Query().Get("ids")
 	if idsParam == "" {
-		h.respondWithError(w, http.StatusBadRequest, "ids là bắt buộc (comma-separated)", "MISSING_IDS")
+		h.respondWithError(w, r, http.StatusBadRequest, "ids là bắt buộc (comma-separated)", "MISSING_IDS", requiredParam("ids"))
 		return
 	}
 
@@ -332,7 +518,7 @@ func (h *TweetsHandler) ListTweets(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(ids) == 0 {
-		h.respondWithError(w, http.StatusBadRequest, "Không có ID hợp lệ", "INVALID_IDS")
+		h.respondWithError(w, r, http.StatusBadRequest, "Không có ID hợp lệ", "INVALID_IDS", models.InvalidParam{Name: "ids", Reason: "không chứa ID hợp lệ nào"})
 		return
 	}
 
@@ -344,129 +530,224 @@ func (h *TweetsHandler) ListTweets(w http.ResponseWriter, r *http.Request) {
 	response, err := h.twitterService.ListTweets(r.Context(), ids)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy danh sách tweets")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy danh sách tweets: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy danh sách tweets")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetLikingUsers xử lý request lấy users đã like tweet
-// GET /api/tweets/{tweet_id}/liking_users?count=10
+// GET /api/tweets/{tweet_id}/liking_users?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetLikingUsers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tweetID := vars["tweet_id"]
 
 	if tweetID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID", requiredParam("tweet_id"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"tweet_id": tweetID,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"tweet_id":   tweetID,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy liking users")
 
-	response, err := h.twitterService.GetLikingUsers(r.Context(), tweetID, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.LikingUsersResponse, error) {
+		return h.twitterService.GetLikingUsers(ctx, tweetID, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, likingUsersPage, "Không thể lấy liking users")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, likingUsersPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy liking users")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy liking users: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy liking users")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetQuoteTweets xử lý request lấy quote tweets
-// GET /api/tweets/{tweet_id}/quote_tweets?count=10
+// GET /api/tweets/{tweet_id}/quote_tweets?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetQuoteTweets(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tweetID := vars["tweet_id"]
 
 	if tweetID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID", requiredParam("tweet_id"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"tweet_id": tweetID,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"tweet_id":   tweetID,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy quote tweets")
 
-	response, err := h.twitterService.GetQuoteTweets(r.Context(), tweetID, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.QuoteTweetsResponse, error) {
+		return h.twitterService.GetQuoteTweets(ctx, tweetID, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, quoteTweetsPage, "Không thể lấy quote tweets")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, quoteTweetsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy quote tweets")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy quote tweets: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy quote tweets")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetRetweetedBy xử lý request lấy users đã retweet
-// GET /api/tweets/{tweet_id}/retweeted_by?count=10
+// GET /api/tweets/{tweet_id}/retweeted_by?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetRetweetedBy(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tweetID := vars["tweet_id"]
 
 	if tweetID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID", requiredParam("tweet_id"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"tweet_id": tweetID,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"tweet_id":   tweetID,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy retweeted by")
 
-	response, err := h.twitterService.GetRetweetedBy(r.Context(), tweetID, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.RetweetedByResponse, error) {
+		return h.twitterService.GetRetweetedBy(ctx, tweetID, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, retweetedByPage, "Không thể lấy retweeted by")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, retweetedByPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy retweeted by")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy retweeted by: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy retweeted by")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetTweetCounts xử lý request lấy tweet counts
-// GET /api/tweets/counts/recent?q=keyword&start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z
+// GET /api/tweets/counts/recent?q=keyword&start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&granularity=hour
+// hoặc thời gian tương đối: ?q=keyword&start_time=-24h
 func (h *TweetsHandler) GetTweetCounts(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY")
+		h.respondWithError(w, r, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY", requiredParam("q"))
 		return
 	}
 
-	startTime := r.URL.Query().Get("start_time")
-	endTime := r.URL.Query().Get("end_time")
+	opts, ok := h.tweetCountsOptions(w, r, recentCountsMaxAge)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"query":      query,
-		"start_time": startTime,
-		"end_time":   endTime,
-		"ip":         r.RemoteAddr,
+		"query":       query,
+		"start_time":  opts.StartTime,
+		"end_time":    opts.EndTime,
+		"granularity": opts.Granularity,
+		"ip":          r.RemoteAddr,
 	}).Info("Nhận request lấy tweet counts")
 
-	response, err := h.twitterService.GetTweetCounts(r.Context(), query, startTime, endTime)
+	response, err := h.twitterService.GetTweetCounts(r.Context(), query, opts)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy tweet counts")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy tweet counts: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy tweet counts")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheCounts, response)
+}
+
+// GetAllTweetCounts xử lý request lấy tweet counts trong toàn bộ lịch sử (full-archive),
+// cùng tham số với GetTweetCounts nhưng không giới hạn 7 ngày; mỗi trang tối đa 31 ngày,
+// trang tiếp theo qua pagination_token
+// GET /api/tweets/counts/all?q=keyword&start_time=2020-01-01T00:00:00Z&granularity=day&pagination_token=xxx
+func (h *TweetsHandler) GetAllTweetCounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		h.respondWithError(w, r, http.StatusBadRequest, "Query là bắt buộc", "MISSING_QUERY", requiredParam("q"))
+		return
+	}
+
+	opts, ok := h.tweetCountsOptions(w, r, 0)
+	if !ok {
+		return
+	}
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"query":       query,
+		"start_time":  opts.StartTime,
+		"end_time":    opts.EndTime,
+		"granularity": opts.Granularity,
+		"page_token":  paginationToken,
+		"ip":          r.RemoteAddr,
+	}).Info("Nhận request lấy tweet counts full-archive")
+
+	response, err := h.twitterService.GetAllTweetCounts(r.Context(), query, opts, paginationToken)
+	if err != nil {
+		log.WithError(err).Error("Lỗi khi lấy tweet counts full-archive")
+		h.respondWithServiceError(w, r, err, "Không thể lấy tweet counts full-archive")
+		return
+	}
+
+	h.respondCacheable(w, cacheCounts, response)
 }
 
 // GetUserByID xử lý request lấy user theo ID
@@ -476,7 +757,7 @@ func (h *TweetsHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	userID := vars["user_id"]
 
 	if userID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "User ID là bắt buộc", "MISSING_USER_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "User ID là bắt buộc", "MISSING_USER_ID", requiredParam("user_id"))
 		return
 	}
 
@@ -488,11 +769,11 @@ func (h *TweetsHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	user, err := h.twitterService.GetUserByID(r.Context(), userID)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy user theo ID")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy user: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy user")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, user)
+	h.respondCacheable(w, cacheProfile, user)
 }
 
 // ListUsers xử lý request lấy danh sách users theo IDs
@@ -500,7 +781,7 @@ func (h *TweetsHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 func (h *TweetsHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	idsParam := r.URL.Query().Get("ids")
 	if idsParam == "" {
-		h.respondWithError(w, http.StatusBadRequest, "ids là bắt buộc (comma-separated)", "MISSING_IDS")
+		h.respondWithError(w, r, http.StatusBadRequest, "ids là bắt buộc (comma-separated)", "MISSING_IDS", requiredParam("ids"))
 		return
 	}
 
@@ -512,7 +793,7 @@ func (h *TweetsHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(ids) == 0 {
-		h.respondWithError(w, http.StatusBadRequest, "Không có ID hợp lệ", "INVALID_IDS")
+		h.respondWithError(w, r, http.StatusBadRequest, "Không có ID hợp lệ", "INVALID_IDS", models.InvalidParam{Name: "ids", Reason: "không chứa ID hợp lệ nào"})
 		return
 	}
 
@@ -524,11 +805,11 @@ func (h *TweetsHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	response, err := h.twitterService.ListUsers(r.Context(), ids)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy danh sách users")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy danh sách users: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy danh sách users")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheProfile, response)
 }
 
 // GetMe xử lý request lấy thông tin authenticated user
@@ -539,37 +820,54 @@ func (h *TweetsHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	user, err := h.twitterService.GetMe(r.Context())
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy authenticated user")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy authenticated user: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy authenticated user")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, user)
+	h.respondCacheable(w, cacheProfile, user)
 }
 
 // GetBlockingUsers xử lý request lấy blocking users
-// GET /api/users/{username}/blocking?count=10
+// GET /api/users/{username}/blocking?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetBlockingUsers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy blocking users")
 
-	response, err := h.twitterService.GetBlockingUsers(r.Context(), username, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.BlockingUsersResponse, error) {
+		return h.twitterService.GetBlockingUsers(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, blockingUsersPage, "Không thể lấy blocking users")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, blockingUsersPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy blocking users")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy blocking users: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy blocking users")
 		return
 	}
 
@@ -577,29 +875,46 @@ func (h *TweetsHandler) GetBlockingUsers(w http.ResponseWriter, r *http.Request)
 }
 
 // GetMutingUsers xử lý request lấy muting users
-// GET /api/users/{username}/muting?count=10
+// GET /api/users/{username}/muting?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetMutingUsers(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
-	paginationToken := r.URL.Query().Get("pagination_token")
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy muting users")
 
-	response, err := h.twitterService.GetMutingUsers(r.Context(), username, count, paginationToken)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.MutingUsersResponse, error) {
+		return h.twitterService.GetMutingUsers(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, mutingUsersPage, "Không thể lấy muting users")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, mutingUsersPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy muting users")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy muting users: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy muting users")
 		return
 	}
 
@@ -613,13 +928,13 @@ func (h *TweetsHandler) HideTweet(w http.ResponseWriter, r *http.Request) {
 	tweetID := vars["tweet_id"]
 
 	if tweetID == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID")
+		h.respondWithError(w, r, http.StatusBadRequest, "Tweet ID là bắt buộc", "MISSING_TWEET_ID", requiredParam("tweet_id"))
 		return
 	}
 
 	hiddenParam := r.URL.Query().Get("hidden")
 	if hiddenParam == "" {
-		h.respondWithError(w, http.StatusBadRequest, "hidden parameter là bắt buộc (true/false)", "MISSING_HIDDEN")
+		h.respondWithError(w, r, http.StatusBadRequest, "hidden parameter là bắt buộc (true/false)", "MISSING_HIDDEN", requiredParam("hidden"))
 		return
 	}
 
@@ -634,7 +949,7 @@ func (h *TweetsHandler) HideTweet(w http.ResponseWriter, r *http.Request) {
 	response, err := h.twitterService.HideTweet(r.Context(), tweetID, hidden)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi hide tweet")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể hide tweet: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể hide tweet")
 		return
 	}
 
@@ -642,54 +957,283 @@ func (h *TweetsHandler) HideTweet(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetUserTimelineReverseChronological xử lý request lấy timeline reverse chronological
-// GET /api/users/{username}/timelines/reverse_chronological?count=10
+// GET /api/users/{username}/timelines/reverse_chronological?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetUserTimelineReverseChronological(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	username := vars["username"]
 
 	if username == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME")
+		h.respondWithError(w, r, http.StatusBadRequest, "Username là bắt buộc", "MISSING_USERNAME", requiredParam("username"))
 		return
 	}
 
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"username": username,
-		"count":    count,
-		"ip":       r.RemoteAddr,
+		"username":   username,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy timeline reverse chronological")
 
-	response, err := h.twitterService.GetUserTimelineReverseChronological(r.Context(), username, count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		return h.twitterService.GetUserTimelineReverseChronological(ctx, username, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, tweetsPage, "Không thể lấy timeline")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, tweetsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy timeline")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy timeline: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy timeline")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, response)
+	h.respondCacheable(w, cacheTimeline, response)
 }
 
 // GetRepostsOfMe xử lý request lấy reposts của authenticated user
-// GET /api/users/reposts_of_me?count=10
+// GET /api/users/reposts_of_me?count=10&pagination_token=xxx[&all=true&max_items=N]
 func (h *TweetsHandler) GetRepostsOfMe(w http.ResponseWriter, r *http.Request) {
 	count := parseCount(r.URL.Query().Get("count"))
+	paginationToken, ok := h.paginationToken(w, r)
+	if !ok {
+		return
+	}
+	auto, ok := h.autoPaginate(w, r)
+	if !ok {
+		return
+	}
 
 	log.WithFields(log.Fields{
-		"count": count,
-		"ip":    r.RemoteAddr,
+		"count":      count,
+		"page_token": paginationToken,
+		"all":        auto != nil,
+		"ip":         r.RemoteAddr,
 	}).Info("Nhận request lấy reposts of me")
 
-	response, err := h.twitterService.GetRepostsOfMe(r.Context(), count)
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.RepostsResponse, error) {
+		return h.twitterService.GetRepostsOfMe(ctx, maxResults, token)
+	}
+	if wantsNDJSON(r) {
+		streamList(h, w, r, auto, count, paginationToken, fetch, repostsPage, "Không thể lấy reposts")
+		return
+	}
+
+	response, err := fetchList(r.Context(), auto, count, paginationToken, fetch, repostsPage)
 	if err != nil {
 		log.WithError(err).Error("Lỗi khi lấy reposts")
-		h.respondWithError(w, http.StatusInternalServerError, "Không thể lấy reposts: "+err.Error(), "FETCH_ERROR")
+		h.respondWithServiceError(w, r, err, "Không thể lấy reposts")
 		return
 	}
 
 	h.respondWithJSON(w, http.StatusOK, response)
 }
 
+// GetRateLimits xử lý request xem quota còn lại của X API theo endpoint
+// GET /api/ratelimits
+func (h *TweetsHandler) GetRateLimits(w http.ResponseWriter, r *http.Request) {
+	response, err := h.twitterService.GetRateLimits(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Lỗi khi lấy rate limits")
+		h.respondWithServiceError(w, r, err, "Không thể lấy rate limits")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// GetTokenPool xử lý request xem mức sử dụng của từng Bearer Token
+// GET /api/admin/tokens
+func (h *TweetsHandler) GetTokenPool(w http.ResponseWriter, r *http.Request) {
+	response, err := h.twitterService.GetTokenPool(r.Context())
+	if err != nil {
+		log.WithError(err).Error("Lỗi khi lấy trạng thái Bearer Tokens")
+		h.respondWithServiceError(w, r, err, "Không thể lấy trạng thái Bearer Tokens")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, response)
+}
+
+// validPaginationToken giới hạn pagination token: token của X API chỉ gồm chữ và số
+var validPaginationToken = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// paginationToken đọc và kiểm tra pagination_token; trả về false nếu token không hợp lệ
+// (đã gửi 400 cho client)
+func (h *TweetsHandler) paginationToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := r.URL.Query().Get("pagination_token")
+	if token == "" || validPaginationToken.MatchString(token) {
+		return token, true
+	}
+
+	h.respondWithError(w, r, http.StatusBadRequest, "pagination_token không hợp lệ", "INVALID_PAGINATION_TOKEN",
+		models.InvalidParam{Name: "pagination_token", Reason: "chỉ gồm chữ, số, '_' hoặc '-' và tối đa 256 ký tự"})
+	return "", false
+}
+
+// validTweetID là snowflake ID của tweet: chỉ gồm chữ số, tối đa 19 ký tự
+var validTweetID = regexp.MustCompile(`^[1-9][0-9]{0,18}$`)
+
+// tweetFilter đọc start_time, end_time (RFC3339), since_id và until_id; trả về false
+// nếu có tham số không hợp lệ (đã gửi 400 cho client)
+func (h *TweetsHandler) tweetFilter(w http.ResponseWriter, r *http.Request) (services.TweetFilter, bool) {
+	query := r.URL.Query()
+	var filter services.TweetFilter
+
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"start_time", &filter.StartTime},
+		{"end_time", &filter.EndTime},
+	} {
+		value := query.Get(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.respondWithError(w, r, http.StatusBadRequest, p.name+" không hợp lệ", "INVALID_TIME",
+				models.InvalidParam{Name: p.name, Reason: "phải theo định dạng RFC3339, ví dụ 2024-01-01T00:00:00Z"})
+			return filter, false
+		}
+		*p.dest = t
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && !filter.StartTime.Before(filter.EndTime) {
+		h.respondWithError(w, r, http.StatusBadRequest, "start_time phải trước end_time", "INVALID_TIME_RANGE",
+			models.InvalidParam{Name: "start_time", Reason: "phải trước end_time"})
+		return filter, false
+	}
+
+	for _, p := range []struct {
+		name string
+		dest *string
+	}{
+		{"since_id", &filter.SinceID},
+		{"until_id", &filter.UntilID},
+	} {
+		value := query.Get(p.name)
+		if value == "" {
+			continue
+		}
+		if !validTweetID.MatchString(value) {
+			h.respondWithError(w, r, http.StatusBadRequest, p.name+" không hợp lệ", "INVALID_TWEET_ID",
+				models.InvalidParam{Name: p.name, Reason: "phải là tweet ID dạng số"})
+			return filter, false
+		}
+		*p.dest = value
+	}
+	if filter.SinceID != "" && filter.UntilID != "" && services.CompareTweetIDs(filter.SinceID, filter.UntilID) >= 0 {
+		h.respondWithError(w, r, http.StatusBadRequest, "since_id phải nhỏ hơn until_id", "INVALID_ID_RANGE",
+			models.InvalidParam{Name: "since_id", Reason: "phải nhỏ hơn until_id"})
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// recentCountsMaxAge là khoảng thời gian mà tweet counts recent của X API cho phép truy vấn
+const recentCountsMaxAge = 7 * 24 * time.Hour
+
+// tweetCountsOptions đọc start_time, end_time và granularity của tweet counts. Thời gian là
+// RFC3339 hoặc tương đối so với hiện tại (-24h, -90m, -7d), không được ở tương lai và nếu
+// maxAge > 0 thì không được cũ hơn maxAge (tính theo ngày). Trả về false nếu tham số không hợp lệ (đã gửi 400)
+func (h *TweetsHandler) tweetCountsOptions(w http.ResponseWriter, r *http.Request, maxAge time.Duration) (services.TweetCountsOptions, bool) {
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	opts := services.TweetCountsOptions{Granularity: query.Get("granularity")}
+
+	if opts.Granularity != "" {
+		if _, ok := services.GranularityStep(opts.Granularity); !ok {
+			h.respondWithError(w, r, http.StatusBadRequest, "granularity không hợp lệ", "INVALID_GRANULARITY",
+				models.InvalidParam{Name: "granularity", Reason: "phải là minute, hour hoặc day"})
+			return opts, false
+		}
+	}
+
+	for _, p := range []struct {
+		name    string
+		dest    *time.Time
+		roundUp bool
+	}{
+		{"start_time", &opts.StartTime, true},
+		{"end_time", &opts.EndTime, false},
+	} {
+		value := query.Get(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseCountsTime(value, now, p.roundUp)
+		if err != nil {
+			h.respondWithError(w, r, http.StatusBadRequest, p.name+" không hợp lệ", "INVALID_TIME",
+				models.InvalidParam{Name: p.name, Reason: "phải theo định dạng RFC3339 (ví dụ 2024-01-01T00:00:00Z) hoặc tương đối như -24h, -7d"})
+			return opts, false
+		}
+		if t.After(now) {
+			h.respondWithError(w, r, http.StatusBadRequest, p.name+" không được ở tương lai", "INVALID_TIME",
+				models.InvalidParam{Name: p.name, Reason: "không được ở tương lai"})
+			return opts, false
+		}
+		if maxAge > 0 && t.Before(now.Add(-maxAge)) {
+			h.respondWithError(w, r, http.StatusBadRequest, p.name+" nằm ngoài khoảng cho phép", "INVALID_TIME",
+				models.InvalidParam{Name: p.name, Reason: fmt.Sprintf("không được cũ hơn %d ngày", maxAge/(24*time.Hour))})
+			return opts, false
+		}
+		*p.dest = t
+	}
+
+	if !opts.StartTime.IsZero() && !opts.EndTime.IsZero() && !opts.StartTime.Before(opts.EndTime) {
+		h.respondWithError(w, r, http.StatusBadRequest, "start_time phải trước end_time", "INVALID_TIME_RANGE",
+			models.InvalidParam{Name: "start_time", Reason: "phải trước end_time"})
+		return opts, false
+	}
+
+	return opts, true
+}
+
+// parseCountsTime đọc thời gian RFC3339 hoặc tương đối dạng -<duration> (thêm đơn vị d là ngày).
+// Thời gian tương đối được làm tròn tới phút, lên nếu roundUp, để các request trong cùng
+// một phút dùng chung cache
+func parseCountsTime(value string, now time.Time, roundUp bool) (time.Time, error) {
+	if !strings.HasPrefix(value, "-") {
+		return time.Parse(time.RFC3339, value)
+	}
+
+	var offset time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, err
+		}
+		offset = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		offset = d
+	}
+
+	t := now.Add(offset)
+	rounded := t.Truncate(time.Minute)
+	if roundUp && rounded.Before(t) {
+		rounded = rounded.Add(time.Minute)
+	}
+	return rounded, nil
+}
+
 func parseCount(countStr string) int {
 	if countStr == "" {
 		return 10
@@ -707,7 +1251,7 @@ func splitComma(s string) []string {
 	if s == "" {
 		return []string{}
 	}
-	
+
 	result := []string{}
 	parts := strings.Split(s, ",")
 	for _, part := range parts {
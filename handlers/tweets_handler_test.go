@@ -0,0 +1,593 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	log.SetOutput(io.Discard)
+}
+
+// newTestFake seed một FakeTwitterService nhỏ: alice (authenticated user) follow bob,
+// bob like và retweet tweet của alice, bob quote tweet của alice
+func newTestFake() *services.FakeTwitterService {
+	fake := services.NewFakeTwitterService()
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	fake.AddUser(models.User{ID: "1", Username: "alice", Name: "Alice", CreatedAt: created})
+	fake.AddUser(models.User{ID: "2", Username: "bob", Name: "Bob", CreatedAt: created})
+	fake.AddTweet(models.Tweet{ID: "100", Text: "hello golang", AuthorID: "1", CreatedAt: created})
+	fake.AddTweet(models.Tweet{
+		ID: "101", Text: "hi @alice", AuthorID: "2", CreatedAt: created.Add(time.Minute),
+		Entities:         &models.TweetEntities{Mentions: []models.Mention{{Username: "alice", ID: "1"}}},
+		ReferencedTweets: []models.ReferencedTweet{{Type: "quoted", ID: "100"}},
+	})
+	fake.AddFollowing("1", "2")
+	fake.AddLike("2", "100")
+	fake.AddRetweet("100", "2")
+	fake.AddBlocking("1", "2")
+	fake.AddMuting("1", "2")
+	fake.SetMe("1")
+	fake.SetRateLimit("tweets", 900, 899, created.Add(15*time.Minute))
+	return fake
+}
+
+// newTestRouter đăng ký routes /api qua RegisterRoutes như setupRouter trong main.go (có
+// route admin); middleware xác thực và rate limit do từng test gắn thêm
+func newTestRouter(h *TweetsHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(RequestIDMiddleware)
+	router.Use(ConditionalGetMiddleware)
+	router.HandleFunc("/health", h.HealthCheck).Methods("GET")
+
+	h.RegisterRoutes(router.PathPrefix("/api").Subrouter(), true)
+	return router
+}
+
+func serve(t *testing.T, router http.Handler, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+// routeCase mô tả một route: fakeMethod là method của TwitterAPI dùng để inject lỗi,
+// notFound là request trả về 404 (rỗng nếu route không có trường hợp not found)
+type routeCase struct {
+	name       string
+	method     string
+	target     string
+	fakeMethod string
+	notFound   string
+	notFoundTo int
+}
+
+var routeCases = []routeCase{
+	{"user info", "GET", "/api/user/alice", "GetUserByUsername", "/api/user/nobody", 404},
+	{"user by username", "GET", "/api/users/by/username/Alice", "GetUserByUsername", "/api/users/by/username/nobody", 404},
+	{"following", "GET", "/api/user/alice/following", "GetUserFollowing", "/api/user/nobody/following", 404},
+	{"followers", "GET", "/api/user/bob/followers", "GetUserFollowers", "/api/user/nobody/followers", 404},
+	{"liked", "GET", "/api/user/bob/liked", "GetLikedTweets", "/api/user/nobody/liked", 404},
+	{"mentions", "GET", "/api/user/alice/mentions", "GetUserMentions", "/api/user/nobody/mentions", 404},
+	{"reverse chronological", "GET", "/api/user/alice/timelines/reverse_chronological", "GetUserTimelineReverseChronological", "/api/user/nobody/timelines/reverse_chronological", 404},
+	{"user tweets", "GET", "/api/user/alice/tweets?count=5", "GetUserTweets", "/api/user/nobody/tweets", 404},
+	{"tweets by user", "GET", "/api/tweets/user/alice", "GetUserTweets", "/api/tweets/user/nobody", 404},
+	{"blocking", "GET", "/api/user/alice/blocking", "GetBlockingUsers", "/api/user/bob/blocking", 403},
+	{"muting", "GET", "/api/user/alice/muting", "GetMutingUsers", "/api/user/bob/muting", 403},
+	{"list users", "GET", "/api/users?ids=1,2", "ListUsers", "/api/users?ids=,", 400},
+	{"me", "GET", "/api/users/me", "GetMe", "", 0},
+	{"search users", "GET", "/api/users/search?q=golang", "SearchUsers", "/api/users/search", 400},
+	{"reposts of me", "GET", "/api/users/reposts_of_me", "GetRepostsOfMe", "", 0},
+	{"user by id", "GET", "/api/users/2", "GetUserByID", "/api/users/999", 404},
+	{"list tweets", "GET", "/api/tweets?ids=100,101", "ListTweets", "/api/tweets", 400},
+	{"search tweets", "GET", "/api/tweets/search?q=golang", "SearchTweets", "/api/tweets/search", 400},
+	{"search recent", "GET", "/api/tweets/search/recent?q=golang", "SearchTweets", "/api/tweets/search/recent", 400},
+	{"search all", "GET", "/api/tweets/search/all?q=golang&count=200", "SearchAllTweets", "/api/tweets/search/all", 400},
+	{"tweet counts", "GET", "/api/tweets/counts/recent?q=golang", "GetTweetCounts", "/api/tweets/counts/recent", 400},
+	{"tweet counts all", "GET", "/api/tweets/counts/all?q=golang&start_time=2024-01-01T00:00:00Z&granularity=day", "GetAllTweetCounts", "/api/tweets/counts/all?q=golang&start_time=2024-01-01", 400},
+	{"tweet by id", "GET", "/api/tweets/100", "GetTweetByID", "/api/tweets/999", 404},
+	{"liking users", "GET", "/api/tweets/100/liking_users", "GetLikingUsers", "", 0},
+	{"quote tweets", "GET", "/api/tweets/100/quote_tweets", "GetQuoteTweets", "", 0},
+	{"retweeted by", "GET", "/api/tweets/100/retweeted_by", "GetRetweetedBy", "", 0},
+	{"hide tweet", "PUT", "/api/tweets/100/hidden?hidden=true", "HideTweet", "/api/tweets/999/hidden?hidden=true", 404},
+	{"rate limits", "GET", "/api/ratelimits", "GetRateLimits", "", 0},
+	{"token pool", "GET", "/api/admin/tokens", "GetTokenPool", "", 0},
+}
+
+func TestRoutesSuccess(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	for _, tc := range routeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(t, router, tc.method, tc.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Errorf("body không phải JSON hợp lệ: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoutesNotFoundAndInvalid(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	for _, tc := range routeCases {
+		if tc.notFound == "" {
+			continue
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(t, router, tc.method, tc.notFound)
+			if rec.Code != tc.notFoundTo {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tc.notFoundTo, rec.Body.String())
+			}
+
+			var body models.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("không decode được error response: %v", err)
+			}
+			if body.Code != tc.notFoundTo || body.Error == "" || body.RequestID == "" {
+				t.Errorf("error response không đầy đủ: %+v", body)
+			}
+		})
+	}
+}
+
+func TestRoutesErrorInjection(t *testing.T) {
+	fake := newTestFake()
+	router := newTestRouter(NewTweetsHandler(fake))
+
+	injected := &services.APIError{
+		Kind:       services.ErrorKindRateLimited,
+		Code:       services.CodeRateLimited,
+		Message:    "vượt quá rate limit",
+		RetryAfter: 30 * time.Second,
+	}
+
+	for _, tc := range routeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake.SetError(tc.fakeMethod, injected)
+			defer fake.SetError(tc.fakeMethod, nil)
+
+			rec := serve(t, router, tc.method, tc.target)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want 429; body: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got != "30" {
+				t.Errorf("Retry-After = %q, want 30", got)
+			}
+
+			var body models.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("không decode được error response: %v", err)
+			}
+			if body.Error != services.CodeRateLimited {
+				t.Errorf("error = %q, want %s", body.Error, services.CodeRateLimited)
+			}
+		})
+	}
+}
+
+func TestUntypedServiceErrorIsInternal(t *testing.T) {
+	fake := newTestFake()
+	fake.SetError("GetUserByUsername", io.ErrUnexpectedEOF)
+	router := newTestRouter(NewTweetsHandler(fake))
+
+	rec := serve(t, router, "GET", "/api/user/alice")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	req := httptest.NewRequest("GET", "/api/tweets/999", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+		t.Fatalf("Content-Type = %q, want %s", ct, problemContentType)
+	}
+
+	var problem models.ProblemDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("không decode được problem details: %v", err)
+	}
+	if problem.Status != http.StatusNotFound || problem.Code != services.CodeTweetNotFound || problem.RequestID != "req-123" {
+		t.Errorf("problem details sai: %+v", problem)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	rec := serve(t, router, "GET", "/health")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var body struct {
+		Status   string `json:"status"`
+		Upstream struct {
+			Status string `json:"status"`
+		} `json:"upstream"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("không decode được health response: %v", err)
+	}
+	if body.Status != "ok" || body.Upstream.Status != "ok" {
+		t.Errorf("health = %+v, want ok/ok", body)
+	}
+}
+
+func TestPaginationToken(t *testing.T) {
+	fake := newTestFake()
+	created := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	fake.AddTweet(models.Tweet{ID: "102", Text: "golang tips", AuthorID: "1", CreatedAt: created})
+	router := newTestRouter(NewTweetsHandler(fake))
+
+	var pages []models.TweetsResponse
+	token := ""
+	for i := 0; i < 3; i++ {
+		target := "/api/user/alice/tweets?count=1"
+		if token != "" {
+			target += "&pagination_token=" + token
+		}
+		rec := serve(t, router, "GET", target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d; body: %s", target, rec.Code, rec.Body.String())
+		}
+		var page models.TweetsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		if page.Meta == nil || page.Meta.NextToken == "" {
+			break
+		}
+		token = page.Meta.NextToken
+	}
+
+	if len(pages) != 2 || pages[0].Tweets[0].ID == pages[1].Tweets[0].ID {
+		t.Fatalf("pages = %+v, want 2 trang khác nhau", pages)
+	}
+	if pages[1].Meta.PreviousToken == "" {
+		t.Errorf("trang 2 thiếu previous_token: %+v", pages[1].Meta)
+	}
+}
+
+func TestPaginationTokenInvalid(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	for _, target := range []string{
+		"/api/user/alice/tweets?pagination_token=bad%20token!",
+		"/api/tweets/search?q=golang&pagination_token=" + strings.Repeat("a", 257),
+		"/api/tweets/100/liking_users?pagination_token=%27%3B--",
+	} {
+		rec := serve(t, router, "GET", target)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("GET %s: status = %d, want 400", target, rec.Code)
+		}
+		var body models.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Error != "INVALID_PAGINATION_TOKEN" {
+			t.Errorf("GET %s: error = %q, want INVALID_PAGINATION_TOKEN", target, body.Error)
+		}
+	}
+
+	// Token đúng định dạng nhưng upstream không nhận ra vẫn là 400
+	if rec := serve(t, router, "GET", "/api/user/alice/tweets?pagination_token=999"); rec.Code != http.StatusBadRequest {
+		t.Errorf("token ngoài phạm vi: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAutoPagination(t *testing.T) {
+	fake := newTestFake()
+	created := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		fake.AddTweet(models.Tweet{ID: strconv.Itoa(200 + i), Text: "golang", AuthorID: "1", CreatedAt: created.Add(time.Duration(i) * time.Minute)})
+	}
+	h := NewTweetsHandler(fake)
+	h.SetPaginationLimits(PaginationLimits{MaxItems: 3, Timeout: time.Second})
+	router := newTestRouter(h)
+
+	var page models.TweetsResponse
+	rec := serve(t, router, "GET", "/api/user/alice/tweets?count=1&all=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Tweets) != 3 || page.Meta.StopReason != services.StopReasonMaxItems || page.Meta.NextToken == "" {
+		t.Fatalf("%d tweets, meta = %+v; want 3 tweets, dừng vì max_items", len(page.Tweets), page.Meta)
+	}
+
+	// Gọi tiếp bằng cursor lấy hết phần còn lại
+	var rest models.TweetsResponse
+	rec = serve(t, router, "GET", "/api/user/alice/tweets?all=true&max_items=3&pagination_token="+page.Meta.NextToken)
+	if err := json.Unmarshal(rec.Body.Bytes(), &rest); err != nil {
+		t.Fatal(err)
+	}
+	if len(rest.Tweets) != 2 || rest.Meta.StopReason != "" || rest.Meta.NextToken != "" {
+		t.Errorf("%d tweets, meta = %+v; want 2 tweets cuối", len(rest.Tweets), rest.Meta)
+	}
+
+	for _, target := range []string{
+		"/api/user/alice/tweets?all=maybe",
+		"/api/user/alice/tweets?all=true&max_items=0",
+		"/api/user/alice/tweets?all=true&max_items=4",
+		"/api/user/bob/followers?all=true&max_items=abc",
+	} {
+		if rec := serve(t, router, "GET", target); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want 400", target, rec.Code)
+		}
+	}
+}
+
+// minPageTwitterAPI nâng max_results của GetUserTweets lên tối thiểu 5 như timeline của
+// X API, nên trang cuối của auto-pagination có thể nhiều items hơn yêu cầu
+type minPageTwitterAPI struct {
+	services.TwitterAPI
+}
+
+func (m minPageTwitterAPI) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter services.TweetFilter) (*models.TweetsResponse, error) {
+	return m.TwitterAPI.GetUserTweets(ctx, username, max(maxResults, 5), paginationToken, filter)
+}
+
+func TestAutoPaginationTrimsToMaxItems(t *testing.T) {
+	// alice có 13 tweets: 100 và 200..211
+	router := newTestRouter(NewTweetsHandler(minPageTwitterAPI{newStreamFake(12)}))
+
+	for _, tc := range []struct {
+		name     string
+		query    string
+		want     int
+		wantNext string
+	}{
+		{"max_items nhỏ hơn trang tối thiểu", "all=true&max_items=2", 2, "2_"},
+		{"max_items giữa hai kích thước trang", "count=5&all=true&max_items=7", 7, "2_5"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := map[string]bool{}
+			cursor := ""
+			for i := 0; ; i++ {
+				var page models.TweetsResponse
+				rec := serve(t, router, "GET", "/api/user/alice/tweets?"+tc.query+"&pagination_token="+cursor)
+				if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+					t.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+				}
+				if i == 0 && (len(page.Tweets) != tc.want || page.Meta.StopReason != services.StopReasonMaxItems || page.Meta.NextToken != tc.wantNext) {
+					t.Fatalf("%d tweets, meta = %+v; want %d tweets, cursor %q", len(page.Tweets), page.Meta, tc.want, tc.wantNext)
+				}
+				if len(page.Tweets) > tc.want {
+					t.Fatalf("%d tweets vượt max_items", len(page.Tweets))
+				}
+				for _, tweet := range page.Tweets {
+					if seen[tweet.ID] {
+						t.Fatalf("tweet %s bị trùng", tweet.ID)
+					}
+					seen[tweet.ID] = true
+				}
+				if cursor = page.Meta.NextToken; cursor == "" {
+					break
+				}
+			}
+			if len(seen) != 13 {
+				t.Errorf("lấy được %d tweets, want 13", len(seen))
+			}
+
+			// NDJSON cũng chỉ gửi đúng max_items items
+			rec := serveNDJSON(t, router, "/api/user/alice/tweets?"+tc.query)
+			items, trailer := decodeNDJSON(t, rec.Body.Bytes())
+			if len(items) != tc.want || trailer.Meta == nil || trailer.Meta.ResultCount != tc.want || trailer.Meta.NextToken != tc.wantNext {
+				t.Errorf("NDJSON: %d items, trailer = %+v", len(items), trailer.Meta)
+			}
+		})
+	}
+
+	// Cursor giữa trang không phải pagination token của X API
+	if rec := serve(t, router, "GET", "/api/user/alice/tweets?pagination_token=2_5"); rec.Code != http.StatusBadRequest {
+		t.Errorf("cursor không có all=true: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestTweetFilters(t *testing.T) {
+	fake := newTestFake()
+	created := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	fake.AddTweet(models.Tweet{ID: "102", Text: "golang tips", AuthorID: "1", CreatedAt: created})
+	fake.AddTweet(models.Tweet{ID: "103", Text: "golang news", AuthorID: "1", CreatedAt: created.Add(time.Hour)})
+	router := newTestRouter(NewTweetsHandler(fake))
+
+	for _, tc := range []struct {
+		target string
+		want   []string
+	}{
+		{"/api/user/alice/tweets?since_id=100", []string{"103", "102"}},
+		{"/api/user/alice/tweets?since_id=100&until_id=103", []string{"102"}},
+		{"/api/user/alice/tweets?start_time=2024-01-02T00:00:00Z&end_time=2024-01-02T13:00:00Z", []string{"102"}},
+		{"/api/tweets/search?q=golang&start_time=2024-01-02T12:30:00%2B00:00", []string{"103"}},
+	} {
+		rec := serve(t, router, "GET", tc.target)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d; body: %s", tc.target, rec.Code, rec.Body.String())
+		}
+		var body models.TweetsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, tweet := range body.Tweets {
+			ids = append(ids, tweet.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("GET %s: tweets = %v, want %v", tc.target, ids, tc.want)
+		}
+	}
+
+	// Mentions cũng nhận filter
+	rec := serve(t, router, "GET", "/api/user/alice/mentions?until_id=101")
+	var mentions models.MentionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &mentions); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(mentions.Tweets) != 0 {
+		t.Errorf("mentions until_id=101: status = %d, %d tweets; want 0", rec.Code, len(mentions.Tweets))
+	}
+}
+
+func TestTweetFiltersInvalid(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+
+	for _, tc := range []struct {
+		target string
+		code   string
+	}{
+		{"/api/user/alice/tweets?start_time=2024-01-01", "INVALID_TIME"},
+		{"/api/tweets/search?q=golang&end_time=yesterday", "INVALID_TIME"},
+		{"/api/user/alice/tweets?start_time=2024-01-02T00:00:00Z&end_time=2024-01-01T00:00:00Z", "INVALID_TIME_RANGE"},
+		{"/api/user/alice/mentions?since_id=abc", "INVALID_TWEET_ID"},
+		{"/api/user/alice/tweets?until_id=0123", "INVALID_TWEET_ID"},
+		{"/api/tweets/search?q=golang&since_id=200&until_id=99", "INVALID_ID_RANGE"},
+	} {
+		rec := serve(t, router, "GET", tc.target)
+		var body models.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Error != tc.code {
+			t.Errorf("GET %s: status = %d, error = %q; want 400 %s", tc.target, rec.Code, body.Error, tc.code)
+		}
+	}
+}
+
+func TestTweetCounts(t *testing.T) {
+	fake := newTestFake()
+	recent := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Hour)
+	fake.AddTweet(models.Tweet{ID: "300", Text: "golang 1.22", AuthorID: "1", CreatedAt: recent.Add(time.Minute)})
+	fake.AddTweet(models.Tweet{ID: "301", Text: "golang generics", AuthorID: "2", CreatedAt: recent.Add(2 * time.Minute)})
+	fake.AddTweet(models.Tweet{ID: "302", Text: "golang modules", AuthorID: "1", CreatedAt: recent.Add(-28 * time.Hour)})
+	router := newTestRouter(NewTweetsHandler(fake))
+
+	rec := serve(t, router, "GET", "/api/tweets/counts/recent?q=golang&start_time=-3d&granularity=day")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	var body models.TweetCountsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Granularity != "day" || body.TotalTweetCount != 3 || len(body.Counts) != 4 {
+		t.Errorf("granularity = %q, total = %d, %d buckets; want day, 3, 4", body.Granularity, body.TotalTweetCount, len(body.Counts))
+	}
+	if body.Peak == nil || body.Peak.TweetCount != 2 || body.Peak.Start.After(recent) || !body.Peak.End.After(recent) {
+		t.Errorf("peak = %+v, want bucket chứa 2 tweets lúc %s", body.Peak, recent)
+	}
+
+	// Granularity mặc định là hour, không có tweet thì không có peak
+	rec = serve(t, router, "GET", "/api/tweets/counts/recent?q=rust&start_time=-90m")
+	body = models.TweetCountsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || body.Granularity != "hour" || body.TotalTweetCount != 0 || body.Peak != nil {
+		t.Errorf("status = %d, response = %+v", rec.Code, body)
+	}
+}
+
+func TestTweetCountsInvalid(t *testing.T) {
+	router := newTestRouter(NewTweetsHandler(newTestFake()))
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+
+	for _, tc := range []struct {
+		target string
+		code   string
+	}{
+		{"/api/tweets/counts/recent?q=golang&start_time=2024-13-01T00:00:00Z", "INVALID_TIME"},
+		{"/api/tweets/counts/recent?q=golang&start_time=24h", "INVALID_TIME"},
+		{"/api/tweets/counts/recent?q=golang&start_time=-3x", "INVALID_TIME"},
+		{"/api/tweets/counts/recent?q=golang&start_time=-8d", "INVALID_TIME"},
+		{"/api/tweets/counts/recent?q=golang&end_time=" + future, "INVALID_TIME"},
+		{"/api/tweets/counts/recent?q=golang&start_time=-1h&end_time=-2h", "INVALID_TIME_RANGE"},
+		{"/api/tweets/counts/recent?q=golang&granularity=week", "INVALID_GRANULARITY"},
+	} {
+		rec := serve(t, router, "GET", tc.target)
+		var body models.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Error != tc.code {
+			t.Errorf("GET %s: status = %d, error = %q; want 400 %s", tc.target, rec.Code, body.Error, tc.code)
+		}
+	}
+}
+
+func TestParseCountsTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 30, 0, time.UTC)
+
+	for _, tc := range []struct {
+		value   string
+		roundUp bool
+		want    time.Time
+	}{
+		{"2024-03-09T08:15:00+07:00", true, time.Date(2024, 3, 9, 1, 15, 0, 0, time.UTC)},
+		{"-1h", true, time.Date(2024, 3, 10, 11, 1, 0, 0, time.UTC)},
+		{"-1h", false, time.Date(2024, 3, 10, 11, 0, 0, 0, time.UTC)},
+		{"-2d", false, time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)},
+	} {
+		got, err := parseCountsTime(tc.value, now, tc.roundUp)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("parseCountsTime(%q, %v) = %s, %v; want %s", tc.value, tc.roundUp, got, err, tc.want)
+		}
+	}
+}
+
+func TestFullArchiveAccessRequired(t *testing.T) {
+	fake := newTestFake()
+	router := newTestRouter(NewTweetsHandler(fake))
+	denied := &services.APIError{
+		Kind:    services.ErrorKindForbidden,
+		Code:    services.CodeArchiveAccessRequired,
+		Message: "Bearer Token không có quyền truy cập full-archive",
+	}
+	fake.SetError("SearchAllTweets", denied)
+	fake.SetError("GetAllTweetCounts", denied)
+
+	for _, target := range []string{"/api/tweets/search/all?q=golang", "/api/tweets/counts/all?q=golang"} {
+		rec := serve(t, router, "GET", target)
+		var body models.ErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusForbidden || body.Error != services.CodeArchiveAccessRequired {
+			t.Errorf("GET %s: status = %d, error = %q; want 403 %s", target, rec.Code, body.Error, services.CodeArchiveAccessRequired)
+		}
+	}
+
+	// Recent search vẫn hoạt động với cùng token
+	if rec := serve(t, router, "GET", "/api/tweets/search?q=golang"); rec.Code != http.StatusOK {
+		t.Errorf("recent search: status = %d, want 200", rec.Code)
+	}
+}
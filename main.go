@@ -9,6 +9,7 @@ import (
 	"time"
 	"x-twitter-backend/config"
 	"x-twitter-backend/handlers"
+	"x-twitter-backend/metrics"
 	"x-twitter-backend/services"
 
 	"github.com/gorilla/mux"
@@ -40,11 +41,109 @@ func main() {
 		log.WithError(err).Fatal("❌ Không thể khởi tạo Twitter service")
 	}
 
+	// Request coalescing ngay trước TwitterService, response cache ở ngoài cùng
+	var twitterAPI services.TwitterAPI = twitterService
+	if cfg.CoalesceRequests {
+		twitterAPI = services.NewCoalescedTwitterService(twitterAPI)
+	}
+	if cfg.CacheBackend == services.CacheBackendRedis || cfg.CacheMaxEntries > 0 {
+		store, err := services.NewCacheStore(cfg)
+		if err != nil {
+			log.WithError(err).Fatal("❌ Không thể khởi tạo cache store")
+		}
+		twitterAPI = services.NewCachedTwitterService(twitterAPI, store, cfg)
+	}
+
 	// Initialize handlers
-	tweetsHandler := handlers.NewTweetsHandler(twitterService)
+	tweetsHandler := handlers.NewTweetsHandler(twitterAPI)
+	tweetsHandler.SetCacheMaxAges(handlers.CacheMaxAges{
+		Profile:  cfg.CacheTTLProfile,
+		Timeline: cfg.CacheTTLTimeline,
+		Search:   cfg.CacheTTLSearch,
+		Counts:   cfg.CacheTTLCounts,
+	})
+	tweetsHandler.SetPaginationLimits(handlers.PaginationLimits{
+		MaxItems:      cfg.PaginateMaxItems,
+		Timeout:       cfg.PaginateTimeout,
+		StreamTimeout: cfg.PaginateStreamTimeout,
+	})
+
+	// Đăng nhập OAuth 2.0 cho end users (tùy chọn)
+	var authHandler *handlers.AuthHandler
+	if cfg.HasOAuth2() {
+		sessions, err := services.NewSessionManager(cfg, services.NewOAuth2Client(cfg))
+		if err != nil {
+			log.WithError(err).Fatal("❌ Không thể khởi tạo session store")
+		}
+		authHandler = handlers.NewAuthHandler(sessions, twitterAPI, handlers.SessionCookie{
+			Name:   cfg.SessionCookieName,
+			Secure: cfg.SessionCookieSecure,
+		})
+	}
+
+	// API keys cho /api (tùy chọn)
+	var apiKeyAuth *handlers.APIKeyAuth
+	if cfg.HasAPIKeys() {
+		apiKeyAuth, err = handlers.NewAPIKeyAuth(cfg.APIKeys, cfg.APIKeyHeader, cfg.APIKeyQueryParam)
+		if err != nil {
+			log.WithError(err).Fatal("❌ Cấu hình API keys không hợp lệ")
+		}
+		log.WithField("keys", len(cfg.APIKeys)).Info("🔑 /api nhận API key")
+	}
+
+	// JWT cho /api (tùy chọn); khi có cả API keys, request không có Bearer token dùng API key
+	var jwtAuth *handlers.JWTAuth
+	if cfg.HasJWT() {
+		jwks, err := handlers.NewJWKS(cfg.JWTJWKSFile, cfg.JWTJWKSURL, cfg.JWTJWKSRefresh)
+		if err != nil {
+			log.WithError(err).Fatal("❌ Không thể tải JWKS")
+		}
+		jwtAuth, err = handlers.NewJWTAuth(jwks, handlers.JWTOptions{
+			Issuer:     cfg.JWTIssuer,
+			Audience:   cfg.JWTAudience,
+			Leeway:     cfg.JWTLeeway,
+			ScopeClaim: cfg.JWTScopeClaim,
+			ScopeMap:   cfg.JWTScopeMap,
+			Optional:   apiKeyAuth != nil,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("❌ Cấu hình JWT không hợp lệ")
+		}
+		log.WithFields(log.Fields{"keys": jwks.Len(), "issuer": cfg.JWTIssuer}).Info("🔑 /api nhận JWT")
+	}
+
+	if apiKeyAuth == nil && jwtAuth == nil {
+		log.Warn("⚠️  Chưa cấu hình API keys hoặc JWT, /api không yêu cầu xác thực")
+	}
+
+	// Giới hạn request vào /api theo client (API key, JWT subject hoặc IP) và nhóm route,
+	// chỉ bật khi cấu hình RATE_LIMIT_*
+	var rateLimiter *handlers.RateLimiter
+	if cfg.RateLimitEnabled() {
+		rateLimiter, err = handlers.NewRateLimiter(cfg.RateLimits(), cfg.TrustedProxies)
+		if err != nil {
+			log.WithError(err).Fatal("❌ Cấu hình rate limit không hợp lệ")
+		}
+		log.WithFields(log.Fields{
+			"default":         cfg.RateLimitDefault.String(),
+			"search":          cfg.RateLimitSearch.String(),
+			"write":           cfg.RateLimitWrite.String(),
+			"admin":           cfg.RateLimitAdmin.String(),
+			"trusted_proxies": len(cfg.TrustedProxies),
+		}).Info("🚦 Rate limit cho /api")
+	} else {
+		log.Info("🚦 Không giới hạn request vào /api (đặt RATE_LIMIT_DEFAULT để bật)")
+	}
 
 	// Setup router
-	router := setupRouter(tweetsHandler)
+	router := setupRouter(tweetsHandler, routerOptions{
+		authHandler:  authHandler,
+		apiKeyAuth:   apiKeyAuth,
+		jwtAuth:      jwtAuth,
+		rateLimiter:  rateLimiter,
+		corsOrigins:  cfg.CORSAllowedOrigins,
+		apiKeyHeader: cfg.APIKeyHeader,
+	})
 
 	// Create HTTP server
 	server := &http.Server{
@@ -69,51 +168,85 @@ func main() {
 	gracefulShutdown(server)
 }
 
+// routerOptions là các thành phần tùy chọn của router; zero value là không xác thực
+// và CORS cho phép mọi origin
+type routerOptions struct {
+	// authHandler nil nếu chưa cấu hình OAuth 2.0
+	authHandler *handlers.AuthHandler
+	// apiKeyAuth nil nếu /api không yêu cầu API key
+	apiKeyAuth *handlers.APIKeyAuth
+	// jwtAuth nil nếu /api không nhận JWT
+	jwtAuth *handlers.JWTAuth
+	// rateLimiter nil nếu không giới hạn request vào /api
+	rateLimiter  *handlers.RateLimiter
+	corsOrigins  []string
+	apiKeyHeader string
+}
+
+// adminEnabled cho biết route admin có được bảo vệ bởi xác thực cấp được scope admin
+// không; JWT có thể mang scope admin trong claims nên luôn tính là có
+func (o routerOptions) adminEnabled() bool {
+	return o.jwtAuth != nil || (o.apiKeyAuth != nil && o.apiKeyAuth.GrantsScope(handlers.ScopeAdmin))
+}
+
 // setupRouter thiết lập tất cả các routes
-func setupRouter(tweetsHandler *handlers.TweetsHandler) *mux.Router {
+func setupRouter(tweetsHandler *handlers.TweetsHandler, opts routerOptions) *mux.Router {
 	router := mux.NewRouter()
+	authHandler := opts.authHandler
+
+	corsOrigins := opts.corsOrigins
+	if len(corsOrigins) == 0 {
+		corsOrigins = []string{"*"}
+	}
+	var corsHeaders []string
+	if opts.apiKeyHeader != "" {
+		corsHeaders = append(corsHeaders, opts.apiKeyHeader)
+	}
 
 	// Apply middlewares
+	router.Use(handlers.RequestIDMiddleware)
 	router.Use(handlers.RecoveryMiddleware)
 	router.Use(handlers.LoggingMiddleware)
-	router.Use(handlers.CORSMiddleware)
+	router.Use(handlers.CORSMiddleware(corsOrigins, corsHeaders...))
+	router.Use(handlers.ConditionalGetMiddleware)
 
 	// Health check
 	router.HandleFunc("/health", tweetsHandler.HealthCheck).Methods("GET")
 
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// OAuth 2.0 login cho end users
+	if authHandler != nil {
+		router.HandleFunc("/auth/login", authHandler.Login).Methods("GET")
+		router.HandleFunc("/auth/callback", authHandler.Callback).Methods("GET")
+		router.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	}
+
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
+	// JWT trước API key: request đã xác thực bằng JWT không cần API key
+	if opts.jwtAuth != nil {
+		api.Use(opts.jwtAuth.Middleware)
+	}
+	if opts.apiKeyAuth != nil {
+		api.Use(opts.apiKeyAuth.Middleware)
+	}
+	// Sau xác thực để giới hạn theo API key/JWT subject thay vì IP
+	if opts.rateLimiter != nil {
+		api.Use(opts.rateLimiter.Middleware)
+	}
+	if authHandler != nil {
+		api.Use(authHandler.SessionMiddleware)
+	}
 
-	// User routes
-	api.HandleFunc("/user/{username}", tweetsHandler.GetUserInfo).Methods("GET")
-	api.HandleFunc("/user/{username}/following", tweetsHandler.GetUserFollowing).Methods("GET")
-	api.HandleFunc("/user/{username}/followers", tweetsHandler.GetUserFollowers).Methods("GET")
-	api.HandleFunc("/user/{username}/liked", tweetsHandler.GetLikedTweets).Methods("GET")
-	api.HandleFunc("/user/{username}/mentions", tweetsHandler.GetUserMentions).Methods("GET")
-	api.HandleFunc("/user/{username}/timelines/reverse_chronological", tweetsHandler.GetUserTimelineReverseChronological).Methods("GET")
-	api.HandleFunc("/user/{username}/tweets", tweetsHandler.GetUserTweets).Methods("GET")
-	api.HandleFunc("/user/{username}/blocking", tweetsHandler.GetBlockingUsers).Methods("GET")
-	api.HandleFunc("/user/{username}/muting", tweetsHandler.GetMutingUsers).Methods("GET")
-
-	// Users routes
-	api.HandleFunc("/users", tweetsHandler.ListUsers).Methods("GET")
-	api.HandleFunc("/users/{user_id}", tweetsHandler.GetUserByID).Methods("GET")
-	api.HandleFunc("/users/by/username/{username}", tweetsHandler.GetUserInfo).Methods("GET")
-	api.HandleFunc("/users/me", tweetsHandler.GetMe).Methods("GET")
-	api.HandleFunc("/users/search", tweetsHandler.SearchUsers).Methods("GET")
-	api.HandleFunc("/users/reposts_of_me", tweetsHandler.GetRepostsOfMe).Methods("GET")
-
-	// Tweets routes
-	api.HandleFunc("/tweets", tweetsHandler.ListTweets).Methods("GET")
-	api.HandleFunc("/tweets/user/{username}", tweetsHandler.GetUserTweets).Methods("GET")
-	api.HandleFunc("/tweets/search", tweetsHandler.SearchTweets).Methods("GET")
-	api.HandleFunc("/tweets/search/recent", tweetsHandler.SearchTweets).Methods("GET")
-	api.HandleFunc("/tweets/{tweet_id}", tweetsHandler.GetTweetByID).Methods("GET")
-	api.HandleFunc("/tweets/{tweet_id}/liking_users", tweetsHandler.GetLikingUsers).Methods("GET")
-	api.HandleFunc("/tweets/{tweet_id}/quote_tweets", tweetsHandler.GetQuoteTweets).Methods("GET")
-	api.HandleFunc("/tweets/{tweet_id}/retweeted_by", tweetsHandler.GetRetweetedBy).Methods("GET")
-	api.HandleFunc("/tweets/{tweet_id}/hidden", tweetsHandler.HideTweet).Methods("PUT")
-	api.HandleFunc("/tweets/counts/recent", tweetsHandler.GetTweetCounts).Methods("GET")
+	// Rate limits của X API và mức sử dụng Bearer Tokens: chỉ đăng ký khi có xác thực
+	// cấp được scope admin, để không lộ thông tin vận hành khi /api công khai
+	adminRoutes := opts.adminEnabled()
+	if !adminRoutes {
+		log.Warn("⚠️  Không có API key hoặc JWT với scope admin, /api/ratelimits và /api/admin/* bị tắt")
+	}
+	tweetsHandler.RegisterRoutes(api, adminRoutes)
 
 	// API documentation endpoint
 	api.HandleFunc("/docs", handleAPIDocs).Methods("GET")
@@ -186,6 +319,37 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Health check endpoint",
       "example": "/health"
     },
+    {
+      "path": "/metrics",
+      "method": "GET",
+      "description": "Metrics theo Prometheus text format (trạng thái circuit breaker, rate limit còn lại của X API)",
+      "example": "/metrics"
+    },
+    {
+      "path": "/auth/login",
+      "method": "GET",
+      "description": "Đăng nhập bằng tài khoản X (OAuth 2.0 PKCE), redirect tới trang authorize của X. Chỉ có khi cấu hình TWITTER_OAUTH2_CLIENT_ID",
+      "parameters": {
+        "redirect": "Đường dẫn trong cùng site để chuyển tới sau khi đăng nhập (optional)"
+      },
+      "example": "/auth/login?redirect=/test"
+    },
+    {
+      "path": "/auth/callback",
+      "method": "GET",
+      "description": "Redirect URI nhận code từ X, tạo session và set cookie; các request /api sau đó dùng token của user đăng nhập",
+      "parameters": {
+        "code": "Authorization code do X trả về",
+        "state": "State đã tạo ở /auth/login"
+      },
+      "example": "/auth/callback?code=xxx&state=yyy"
+    },
+    {
+      "path": "/auth/logout",
+      "method": "POST",
+      "description": "Xóa session, thu hồi token và xóa cookie",
+      "example": "/auth/logout"
+    },
     {
       "path": "/api/user/{username}",
       "method": "GET",
@@ -223,7 +387,8 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Lấy danh sách tweets mà user đã like",
       "parameters": {
         "username": "Username của tài khoản Twitter/X",
-        "count": "Số lượng tweets (default: 10, max: 100)"
+        "count": "Số lượng tweets (default: 10, max: 100)",
+        "pagination_token": "Token phân trang (optional)"
       },
       "example": "/api/user/elonmusk/liked?count=20"
     },
@@ -233,7 +398,12 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Lấy danh sách tweets có mention đến user",
       "parameters": {
         "username": "Username của tài khoản Twitter/X",
-        "count": "Số lượng tweets (default: 10, max: 100)"
+        "count": "Số lượng tweets (default: 10, max: 100)",
+        "pagination_token": "Token phân trang (optional)",
+        "start_time": "Chỉ lấy tweets từ thời điểm này, RFC3339 (optional)",
+        "end_time": "Chỉ lấy tweets trước thời điểm này, RFC3339 (optional)",
+        "since_id": "Chỉ lấy tweets có ID lớn hơn (optional)",
+        "until_id": "Chỉ lấy tweets có ID nhỏ hơn (optional)"
       },
       "example": "/api/user/elonmusk/mentions?count=20"
     },
@@ -243,7 +413,12 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Lấy tweets mới nhất của một user",
       "parameters": {
         "username": "Username của tài khoản Twitter/X",
-        "count": "Số lượng tweets (default: 10, max: 100)"
+        "count": "Số lượng tweets (default: 10, max: 100)",
+        "pagination_token": "Token phân trang (optional)",
+        "start_time": "Chỉ lấy tweets từ thời điểm này, RFC3339 (optional)",
+        "end_time": "Chỉ lấy tweets trước thời điểm này, RFC3339 (optional)",
+        "since_id": "Chỉ lấy tweets có ID lớn hơn (optional)",
+        "until_id": "Chỉ lấy tweets có ID nhỏ hơn (optional)"
       },
       "example": "/api/tweets/user/elonmusk?count=20"
     },
@@ -253,10 +428,43 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Tìm kiếm tweets theo từ khóa",
       "parameters": {
         "q": "Từ khóa tìm kiếm (bắt buộc)",
-        "count": "Số lượng tweets (default: 10, max: 100)"
+        "count": "Số lượng tweets (default: 10, max: 100)",
+        "pagination_token": "Token phân trang (optional)",
+        "start_time": "Chỉ lấy tweets từ thời điểm này, RFC3339 (optional)",
+        "end_time": "Chỉ lấy tweets trước thời điểm này, RFC3339 (optional)",
+        "since_id": "Chỉ lấy tweets có ID lớn hơn (optional)",
+        "until_id": "Chỉ lấy tweets có ID nhỏ hơn (optional)"
       },
       "example": "/api/tweets/search?q=golang&count=20"
     },
+    {
+      "path": "/api/tweets/search/all",
+      "method": "GET",
+      "description": "Tìm kiếm tweets trong toàn bộ lịch sử (full-archive, cần gói Pro hoặc Academic Research)",
+      "parameters": {
+        "q": "Từ khóa tìm kiếm (bắt buộc)",
+        "count": "Số lượng tweets (default: 10, max: 500)",
+        "pagination_token": "Token phân trang (optional)",
+        "start_time": "Chỉ lấy tweets từ thời điểm này, RFC3339 (optional)",
+        "end_time": "Chỉ lấy tweets trước thời điểm này, RFC3339 (optional)",
+        "since_id": "Chỉ lấy tweets có ID lớn hơn (optional)",
+        "until_id": "Chỉ lấy tweets có ID nhỏ hơn (optional)"
+      },
+      "example": "/api/tweets/search/all?q=golang&start_time=2015-01-01T00:00:00Z&count=100"
+    },
+    {
+      "path": "/api/tweets/counts/all",
+      "method": "GET",
+      "description": "Đếm tweets trong toàn bộ lịch sử theo bucket (full-archive, cần gói Pro hoặc Academic Research)",
+      "parameters": {
+        "q": "Từ khóa tìm kiếm (bắt buộc)",
+        "start_time": "RFC3339 hoặc tương đối như -30d (optional, mặc định 30 ngày trước)",
+        "end_time": "RFC3339 hoặc tương đối (optional)",
+        "granularity": "minute, hour hoặc day (default: hour)",
+        "pagination_token": "Token phân trang (optional)"
+      },
+      "example": "/api/tweets/counts/all?q=golang&start_time=2020-01-01T00:00:00Z&granularity=day"
+    },
     {
       "path": "/api/tweets/{tweet_id}",
       "method": "GET",
@@ -272,16 +480,34 @@ func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
       "description": "Tìm kiếm users theo từ khóa",
       "parameters": {
         "q": "Từ khóa tìm kiếm (bắt buộc)",
-        "count": "Số lượng users (default: 10, max: 100)"
+        "count": "Số lượng users (default: 10, max: 100)",
+        "pagination_token": "Token phân trang (optional)"
       },
       "example": "/api/users/search?q=elon&count=10"
+    },
+    {
+      "path": "/api/ratelimits",
+      "method": "GET",
+      "description": "Xem quota còn lại và thời điểm reset của X API theo từng endpoint (từ x-rate-limit-* headers)",
+      "example": "/api/ratelimits"
+    },
+    {
+      "path": "/api/admin/tokens",
+      "method": "GET",
+      "description": "Xem mức sử dụng, rate limit và trạng thái cách ly (401/403) của từng Bearer Token khi cấu hình TWITTER_BEARER_TOKENS",
+      "example": "/api/admin/tokens"
     }
   ],
-  "authentication": "Yêu cầu TWITTER_BEARER_TOKEN trong environment variables",
+  "authentication": "Server gọi X API bằng TWITTER_BEARER_TOKEN (hoặc danh sách TWITTER_BEARER_TOKENS). Khi cấu hình API_KEYS_FILE/API_KEYS và/hoặc JWT_JWKS_FILE/JWT_JWKS_URL, client phải gửi API key (header X-API-Key hoặc query api_key) hoặc JWT RS256/ES256 (Authorization: Bearer) cho mọi route /api trừ /api/docs",
   "notes": [
     "API tuân thủ rate limits của Twitter API",
     "Tất cả responses trả về dạng JSON",
-    "Errors được trả về với format chuẩn: {error, message, code}",
+    "Errors được trả về với format chuẩn: {error, message, detail, code, request_id}; error là mã lỗi ổn định (USER_NOT_FOUND, RATE_LIMITED, UPSTREAM_UNAVAILABLE...)",
+    "Responses được cache in-memory với TTL theo nhóm endpoint; header X-Cache: HIT/MISS, gửi Cache-Control: no-cache để bỏ qua cache",
+    "Gửi Accept: application/problem+json để nhận lỗi theo RFC 7807 (type, title, status, detail, instance, request_id, invalid_params)",
+    "API key có scopes (tweets:read, tweets:write, users:read, admin) và quota mỗi phút/mỗi ngày; lỗi API_KEY_REQUIRED, INVALID_API_KEY (401), INSUFFICIENT_SCOPE (403), QUOTA_EXCEEDED (429 kèm Retry-After và X-RateLimit-*-Minute/Day)",
+    "JWT phải có iss, aud, exp, sub khớp cấu hình; scopes lấy từ claim scope (hoặc JWT_SCOPE_CLAIM); lỗi TOKEN_REQUIRED, INVALID_TOKEN, TOKEN_EXPIRED (401), INSUFFICIENT_SCOPE (403)",
+    "Khi cấu hình RATE_LIMIT_DEFAULT, mỗi client (API key, JWT subject hoặc IP) có giới hạn request riêng cho các nhóm route default, search, write, admin; response có RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, vượt giới hạn trả 429 RATE_LIMIT_EXCEEDED kèm Retry-After",
     "Các API miễn phí và không bị giới hạn bởi Twitter API v2"
   ]
 }`
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"x-twitter-backend/config"
+	"x-twitter-backend/handlers"
+	"x-twitter-backend/models"
+	"x-twitter-backend/services"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSetupRouterFixedUserPaths(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	fake := services.NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice", Name: "Alice"})
+	fake.SetMe("1")
+	router := setupRouter(handlers.NewTweetsHandler(fake), routerOptions{})
+
+	// Các path này không được rơi vào /api/users/{user_id}
+	for _, target := range []string{"/api/users/me", "/api/users/search?q=x", "/api/users/reposts_of_me"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200; body: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/users/me", nil))
+	var me models.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &me); err != nil || me.ID != "1" {
+		t.Errorf("GET /api/users/me = %s, want user 1", rec.Body.String())
+	}
+}
+
+func TestSetupRouterAdminRoutesRequireAdminAuth(t *testing.T) {
+	log.SetOutput(io.Discard)
+
+	keys := func(scopes ...string) *handlers.APIKeyAuth {
+		auth, err := handlers.NewAPIKeyAuth([]config.APIKey{{Name: "test", Key: "secret", Scopes: scopes}}, "X-API-Key", "")
+		if err != nil {
+			t.Fatalf("NewAPIKeyAuth: %v", err)
+		}
+		return auth
+	}
+
+	tests := []struct {
+		name string
+		opts routerOptions
+		want int
+	}{
+		{"không xác thực", routerOptions{}, http.StatusNotFound},
+		{"API key không có scope admin", routerOptions{apiKeyAuth: keys(handlers.ScopeTweetsRead)}, http.StatusNotFound},
+		{"API key có scope admin", routerOptions{apiKeyAuth: keys(handlers.ScopeAdmin)}, http.StatusOK},
+		{"API key có mọi scope", routerOptions{apiKeyAuth: keys(handlers.ScopeAll)}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupRouter(handlers.NewTweetsHandler(services.NewFakeTwitterService()), tt.opts)
+			for _, target := range []string{"/api/ratelimits", "/api/admin/tokens"} {
+				req := httptest.NewRequest("GET", target, nil)
+				req.Header.Set("X-API-Key", "secret")
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+				if rec.Code != tt.want {
+					t.Errorf("GET %s: status = %d, want %d", target, rec.Code, tt.want)
+				}
+			}
+		})
+	}
+}
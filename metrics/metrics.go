@@ -0,0 +1,170 @@
+// Package metrics là registry metrics tối giản, xuất ra Prometheus text format
+// (version 0.0.4) tại GET /metrics mà không cần thư viện client của Prometheus.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Sample là một giá trị của metric với các labels
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// collector sinh samples của một metric mỗi lần scrape
+type collector struct {
+	name    string
+	help    string
+	kind    string // counter | gauge
+	collect func() []Sample
+}
+
+var (
+	mu         sync.RWMutex
+	collectors = make(map[string]*collector)
+)
+
+// register thêm (hoặc thay thế) collector theo tên metric
+func register(c *collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors[c.name] = c
+}
+
+// RegisterGaugeFunc đăng ký gauge mà giá trị được tính lại mỗi lần scrape.
+// Đăng ký lại cùng tên sẽ thay thế collector cũ.
+func RegisterGaugeFunc(name, help string, collect func() []Sample) {
+	register(&collector{name: name, help: help, kind: "gauge", collect: collect})
+}
+
+// RegisterCounterFunc giống RegisterGaugeFunc nhưng cho counter do nơi khác tự đếm
+func RegisterCounterFunc(name, help string, collect func() []Sample) {
+	register(&collector{name: name, help: help, kind: "counter", collect: collect})
+}
+
+// Counter là counter đơn điệu tăng, phân theo labels
+type Counter struct {
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]float64 // label values nối bằng \xff -> giá trị
+}
+
+// NewCounter tạo và đăng ký một Counter
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{labelNames: labelNames, values: make(map[string]float64)}
+	RegisterCounterFunc(name, help, c.samples)
+	return c
+}
+
+// Inc tăng counter thêm 1 với label values theo đúng thứ tự labelNames
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add tăng counter thêm v
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if len(labelValues) != len(c.labelNames) {
+		log.Warnf("metrics: số label values (%d) không khớp labels %v", len(labelValues), c.labelNames)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(labelValues, "\xff")] += v
+}
+
+func (c *Counter) samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	samples := make([]Sample, 0, len(c.values))
+	for key, value := range c.values {
+		labels := make(map[string]string, len(c.labelNames))
+		if len(c.labelNames) > 0 {
+			for i, v := range strings.Split(key, "\xff") {
+				labels[c.labelNames[i]] = v
+			}
+		}
+		samples = append(samples, Sample{Labels: labels, Value: value})
+	}
+	return samples
+}
+
+// WriteText ghi tất cả metrics theo Prometheus text format
+func WriteText(w io.Writer) error {
+	mu.RLock()
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]*collector, 0, len(names))
+	for _, name := range names {
+		list = append(list, collectors[name])
+	}
+	mu.RUnlock()
+
+	for _, c := range list {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", c.name, escapeHelp(c.help), c.name, c.kind); err != nil {
+			return err
+		}
+
+		lines := make([]string, 0)
+		for _, s := range c.collect() {
+			lines = append(lines, c.name+formatLabels(s.Labels)+" "+strconv.FormatFloat(s.Value, 'g', -1, 64))
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler trả về http.Handler phục vụ GET /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WriteText(w); err != nil {
+			log.WithError(err).Error("Lỗi khi ghi metrics")
+		}
+	})
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+`="`+escapeLabel(labels[k])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
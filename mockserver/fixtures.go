@@ -0,0 +1,250 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// User là user trong fixtures, theo đúng format JSON của X API v2
+type User struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Username        string             `json:"username"`
+	CreatedAt       *time.Time         `json:"created_at,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	ProfileImageURL string             `json:"profile_image_url,omitempty"`
+	Protected       bool               `json:"protected"`
+	Verified        bool               `json:"verified"`
+	PublicMetrics   *UserPublicMetrics `json:"public_metrics,omitempty"`
+}
+
+// UserPublicMetrics là public_metrics của user
+type UserPublicMetrics struct {
+	FollowersCount int `json:"followers_count"`
+	FollowingCount int `json:"following_count"`
+	TweetCount     int `json:"tweet_count"`
+	ListedCount    int `json:"listed_count"`
+}
+
+// Tweet là tweet trong fixtures, theo đúng format JSON của X API v2
+type Tweet struct {
+	ID                  string              `json:"id"`
+	Text                string              `json:"text"`
+	AuthorID            string              `json:"author_id"`
+	ConversationID      string              `json:"conversation_id,omitempty"`
+	CreatedAt           time.Time           `json:"created_at"`
+	Lang                string              `json:"lang,omitempty"`
+	EditHistoryTweetIDs []string            `json:"edit_history_tweet_ids,omitempty"`
+	PublicMetrics       *TweetPublicMetrics `json:"public_metrics,omitempty"`
+	Entities            *TweetEntities      `json:"entities,omitempty"`
+	ReferencedTweets    []ReferencedTweet   `json:"referenced_tweets,omitempty"`
+}
+
+// TweetPublicMetrics là public_metrics của tweet
+type TweetPublicMetrics struct {
+	RetweetCount    int `json:"retweet_count"`
+	ReplyCount      int `json:"reply_count"`
+	LikeCount       int `json:"like_count"`
+	QuoteCount      int `json:"quote_count"`
+	BookmarkCount   int `json:"bookmark_count"`
+	ImpressionCount int `json:"impression_count"`
+}
+
+// TweetEntities là entities của tweet
+type TweetEntities struct {
+	Hashtags []EntityTag `json:"hashtags,omitempty"`
+	Mentions []EntityTag `json:"mentions,omitempty"`
+	URLs     []EntityURL `json:"urls,omitempty"`
+}
+
+// EntityTag là hashtag hoặc mention trong tweet
+type EntityTag struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Tag      string `json:"tag,omitempty"`
+	Username string `json:"username,omitempty"`
+	ID       string `json:"id,omitempty"`
+}
+
+// EntityURL là URL trong tweet
+type EntityURL struct {
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	URL         string `json:"url"`
+	ExpandedURL string `json:"expanded_url"`
+	DisplayURL  string `json:"display_url"`
+}
+
+// ReferencedTweet là tweet được tham chiếu (replied_to, quoted, retweeted)
+type ReferencedTweet struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// Relations mô tả quan hệ giữa users và tweets trong fixtures
+type Relations struct {
+	// Me là user ID được trả về cho /2/users/me
+	Me string `json:"me"`
+	// Following: user ID -> danh sách user IDs mà user đó follow
+	Following map[string][]string `json:"following"`
+	// Likes: user ID -> danh sách tweet IDs mà user đó đã like
+	Likes map[string][]string `json:"likes"`
+	// Retweets: tweet ID -> danh sách user IDs đã retweet tweet đó
+	Retweets map[string][]string `json:"retweets"`
+	// Blocking: user ID -> danh sách user IDs mà user đó block
+	Blocking map[string][]string `json:"blocking"`
+	// Muting: user ID -> danh sách user IDs mà user đó mute
+	Muting map[string][]string `json:"muting"`
+}
+
+// Fixtures là toàn bộ dữ liệu mà mock server phục vụ
+type Fixtures struct {
+	Users     []User
+	Tweets    []Tweet
+	Relations Relations
+
+	usersByID       map[string]*User
+	usersByUsername map[string]*User
+	tweetsByID      map[string]*Tweet
+}
+
+// LoadFixtures đọc users.json, tweets.json và relations.json từ thư mục dir
+func LoadFixtures(dir string) (*Fixtures, error) {
+	f := &Fixtures{}
+
+	if err := readJSONFile(filepath.Join(dir, "users.json"), &f.Users); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(filepath.Join(dir, "tweets.json"), &f.Tweets); err != nil {
+		return nil, err
+	}
+
+	relationsPath := filepath.Join(dir, "relations.json")
+	if _, err := os.Stat(relationsPath); err == nil {
+		if err := readJSONFile(relationsPath, &f.Relations); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.index(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("không thể đọc fixture %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("fixture %s không hợp lệ: %w", path, err)
+	}
+	return nil
+}
+
+// index xây dựng các map tra cứu và sắp xếp tweets mới nhất trước
+func (f *Fixtures) index() error {
+	f.usersByID = make(map[string]*User, len(f.Users))
+	f.usersByUsername = make(map[string]*User, len(f.Users))
+	for i := range f.Users {
+		u := &f.Users[i]
+		if u.ID == "" || u.Username == "" {
+			return fmt.Errorf("fixture user thứ %d thiếu id hoặc username", i)
+		}
+		f.usersByID[u.ID] = u
+		f.usersByUsername[strings.ToLower(u.Username)] = u
+	}
+
+	sort.SliceStable(f.Tweets, func(i, j int) bool {
+		return f.Tweets[i].CreatedAt.After(f.Tweets[j].CreatedAt)
+	})
+
+	f.tweetsByID = make(map[string]*Tweet, len(f.Tweets))
+	for i := range f.Tweets {
+		t := &f.Tweets[i]
+		if t.ID == "" {
+			return fmt.Errorf("fixture tweet thứ %d thiếu id", i)
+		}
+		if _, ok := f.usersByID[t.AuthorID]; !ok {
+			return fmt.Errorf("fixture tweet %s có author_id %s không tồn tại", t.ID, t.AuthorID)
+		}
+		if len(t.EditHistoryTweetIDs) == 0 {
+			t.EditHistoryTweetIDs = []string{t.ID}
+		}
+		if t.ConversationID == "" {
+			t.ConversationID = t.ID
+		}
+		f.tweetsByID[t.ID] = t
+	}
+
+	return nil
+}
+
+// usersFor trả về users theo thứ tự IDs, bỏ qua các ID không tồn tại
+func (f *Fixtures) usersFor(ids []string) []User {
+	users := make([]User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := f.usersByID[id]; ok {
+			users = append(users, *u)
+		}
+	}
+	return users
+}
+
+// tweetsFor trả về tweets theo thứ tự IDs, bỏ qua các ID không tồn tại
+func (f *Fixtures) tweetsFor(ids []string) []Tweet {
+	tweets := make([]Tweet, 0, len(ids))
+	for _, id := range ids {
+		if t, ok := f.tweetsByID[id]; ok {
+			tweets = append(tweets, *t)
+		}
+	}
+	return tweets
+}
+
+// filterTweets trả về tweets thỏa điều kiện, giữ thứ tự mới nhất trước
+func (f *Fixtures) filterTweets(match func(t *Tweet) bool) []Tweet {
+	tweets := make([]Tweet, 0)
+	for i := range f.Tweets {
+		if match(&f.Tweets[i]) {
+			tweets = append(tweets, f.Tweets[i])
+		}
+	}
+	return tweets
+}
+
+// followersOf trả về IDs của các users đang follow userID
+func (f *Fixtures) followersOf(userID string) []string {
+	ids := make([]string, 0)
+	for followerID, targets := range f.Relations.Following {
+		for _, target := range targets {
+			if target == userID {
+				ids = append(ids, followerID)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// likersOf trả về IDs của các users đã like tweetID
+func (f *Fixtures) likersOf(tweetID string) []string {
+	ids := make([]string, 0)
+	for userID, tweetIDs := range f.Relations.Likes {
+		for _, id := range tweetIDs {
+			if id == tweetID {
+				ids = append(ids, userID)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
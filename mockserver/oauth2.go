@@ -0,0 +1,263 @@
+package mockserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// authCodeTTL là thời gian sống của authorization code (X API: 30 giây)
+const authCodeTTL = 30 * time.Second
+
+// authCode là authorization code đã cấp, chờ được đổi lấy token
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	challenge     string
+	challengeType string
+	userID        string
+	scope         string
+	expiresAt     time.Time
+}
+
+// issuedToken là access hoặc refresh token do authorization server giả lập cấp
+type issuedToken struct {
+	clientID  string
+	userID    string
+	scope     string
+	expiresAt time.Time // zero với refresh token (không hết hạn)
+}
+
+// handleAuthorize xử lý GET /i/oauth2/authorize. Không có màn hình đồng ý: user
+// Options.OAuth2User (mặc định authenticated user của fixtures) được coi như đã
+// bấm Authorize và trình duyệt được redirect về redirect_uri kèm code và state.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "state", "code_challenge"} {
+		if query.Get(name) == "" {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "Missing required parameter: "+name)
+			return
+		}
+	}
+	if query.Get("response_type") != "code" {
+		writeOAuth2Error(w, http.StatusBadRequest, "unsupported_response_type", "response_type must be code")
+		return
+	}
+	if s.opts.OAuth2ClientID != "" && query.Get("client_id") != s.opts.OAuth2ClientID {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_client", "Unknown client_id")
+		return
+	}
+	method := query.Get("code_challenge_method")
+	if method == "" {
+		method = "plain"
+	}
+	if method != "S256" && method != "plain" {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "code_challenge_method must be S256 or plain")
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "redirect_uri must be an absolute URL")
+		return
+	}
+
+	user, ok := s.oauth2User()
+	if !ok {
+		writeOAuth2Error(w, http.StatusInternalServerError, "server_error", "OAuth2User không có trong fixtures")
+		return
+	}
+
+	code := randomMockToken()
+	s.mu.Lock()
+	s.codes[code] = authCode{
+		clientID:      query.Get("client_id"),
+		redirectURI:   query.Get("redirect_uri"),
+		challenge:     query.Get("code_challenge"),
+		challengeType: method,
+		userID:        user.ID,
+		scope:         query.Get("scope"),
+		expiresAt:     time.Now().Add(authCodeTTL),
+	}
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// handleToken xử lý POST /2/oauth2/token với grant authorization_code (kiểm tra
+// PKCE code_verifier) và refresh_token (refresh token được xoay vòng như X API)
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := s.oauth2Client(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var grant issuedToken
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code, ok := s.codes[r.PostForm.Get("code")]
+		// Code chỉ dùng được một lần, kể cả khi đổi thất bại
+		delete(s.codes, r.PostForm.Get("code"))
+		if !ok || time.Now().After(code.expiresAt) || code.clientID != clientID {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant", "Value passed for the authorization code was invalid.")
+			return
+		}
+		if r.PostForm.Get("redirect_uri") != code.redirectURI {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request.")
+			return
+		}
+		if !verifyPKCE(code.challenge, code.challengeType, r.PostForm.Get("code_verifier")) {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant", "Value passed for the code_verifier was invalid.")
+			return
+		}
+		grant = issuedToken{clientID: clientID, userID: code.userID, scope: code.scope}
+	case "refresh_token":
+		refresh := r.PostForm.Get("refresh_token")
+		token, ok := s.refreshTokens[refresh]
+		if !ok || token.clientID != clientID {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant", "Value passed for the token was invalid.")
+			return
+		}
+		delete(s.refreshTokens, refresh)
+		grant = token
+	default:
+		writeOAuth2Error(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+		return
+	}
+
+	access := "mock-at-" + randomMockToken()
+	grant.expiresAt = time.Now().Add(s.opts.OAuth2TokenTTL)
+	s.accessTokens[access] = grant
+
+	resp := map[string]interface{}{
+		"token_type":   "bearer",
+		"expires_in":   int(s.opts.OAuth2TokenTTL.Seconds()),
+		"access_token": access,
+		"scope":        grant.scope,
+	}
+	if hasScope(grant.scope, "offline.access") {
+		refresh := "mock-rt-" + randomMockToken()
+		s.refreshTokens[refresh] = issuedToken{clientID: grant.clientID, userID: grant.userID, scope: grant.scope}
+		resp["refresh_token"] = refresh
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRevoke xử lý POST /2/oauth2/revoke cho access hoặc refresh token
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.oauth2Client(w, r); !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "Missing required parameter: token")
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.accessTokens, token)
+	delete(s.refreshTokens, token)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]bool{"revoked": true})
+}
+
+// oauth2Client đọc form và xác thực client: HTTP Basic với confidential client
+// (Options.OAuth2ClientSecret), client_id trong form với public client
+func (s *Server) oauth2Client(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", "Request body must be application/x-www-form-urlencoded")
+		return "", false
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	if s.opts.OAuth2ClientSecret != "" {
+		id, secret, ok := r.BasicAuth()
+		if ok {
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+		}
+		if !ok || secret != s.opts.OAuth2ClientSecret {
+			writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client", "Missing valid authorization header")
+			return "", false
+		}
+		clientID = id
+	}
+	if clientID == "" || (s.opts.OAuth2ClientID != "" && clientID != s.opts.OAuth2ClientID) {
+		writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client", "Missing valid authorization header")
+		return "", false
+	}
+	return clientID, true
+}
+
+// oauth2User là user đăng nhập qua /i/oauth2/authorize
+func (s *Server) oauth2User() (*User, bool) {
+	if s.opts.OAuth2User != "" {
+		user, ok := s.fixtures.usersByUsername[strings.ToLower(s.opts.OAuth2User)]
+		return user, ok
+	}
+	user, ok := s.fixtures.usersByID[s.fixtures.Relations.Me]
+	return user, ok
+}
+
+// lookupAccessToken trả về user ID của access token do mock cấp; expired=true nếu
+// token từng được cấp nhưng đã hết hạn
+func (s *Server) lookupAccessToken(token string) (userID string, issued, expired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant, ok := s.accessTokens[token]
+	if !ok {
+		return "", false, false
+	}
+	if time.Now().After(grant.expiresAt) {
+		return "", true, true
+	}
+	return grant.userID, true, false
+}
+
+// verifyPKCE so code_verifier với code_challenge theo RFC 7636
+func verifyPKCE(challenge, method, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	if method == "plain" {
+		return verifier == challenge
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+}
+
+func hasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func randomMockToken() string {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// writeOAuth2Error gửi lỗi theo RFC 6749 mục 5.2 như token endpoint của X
+func writeOAuth2Error(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
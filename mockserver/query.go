@@ -0,0 +1,268 @@
+package mockserver
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenEncoding tạo pagination token dạng chuỗi chữ-số giống token của X API
+var tokenEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+func encodeToken(offset int) string {
+	return tokenEncoding.EncodeToString([]byte("mock:" + strconv.Itoa(offset)))
+}
+
+func decodeToken(token string) (int, error) {
+	raw, err := tokenEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), "mock:") {
+		return 0, fmt.Errorf("invalid token")
+	}
+	return strconv.Atoi(strings.TrimPrefix(string(raw), "mock:"))
+}
+
+// pageInfo chứa tokens của trang hiện tại
+type pageInfo struct {
+	next     string
+	previous string
+}
+
+// paginate cắt items theo max_results và token trong query parameter tokenParam.
+// Trả về false nếu đã gửi lỗi 400 cho client.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T, limit int, tokenParam string) ([]T, pageInfo, bool) {
+	start := 0
+	if token := r.URL.Query().Get(tokenParam); token != "" {
+		offset, err := decodeToken(token)
+		if err != nil || offset < 0 || offset > len(items) {
+			writeInvalidParameter(w, tokenParam, token,
+				fmt.Sprintf("The `%s` query parameter value [%s] is not valid", tokenParam, token))
+			return nil, pageInfo{}, false
+		}
+		start = offset
+	}
+
+	end := min(start+limit, len(items))
+	info := pageInfo{}
+	if end < len(items) {
+		info.next = encodeToken(end)
+	}
+	if start > 0 {
+		info.previous = encodeToken(max(start-limit, 0))
+	}
+	return items[start:end], info, true
+}
+
+// parseMaxResults đọc max_results trong khoảng [lo, hi]; trả về false nếu đã gửi lỗi 400
+func parseMaxResults(w http.ResponseWriter, r *http.Request, lo, hi, def int) (int, bool) {
+	raw := r.URL.Query().Get("max_results")
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < lo || n > hi {
+		writeInvalidParameter(w, "max_results", raw,
+			fmt.Sprintf("The `max_results` query parameter value [%s] is not between %d and %d", raw, lo, hi))
+		return 0, false
+	}
+	return n, true
+}
+
+// tweetWindow là bộ lọc start_time/end_time/since_id/until_id của X API
+type tweetWindow struct {
+	start   *time.Time
+	end     *time.Time
+	sinceID string
+	untilID string
+}
+
+// parseTweetWindow đọc các tham số lọc; trả về false nếu đã gửi lỗi 400
+func parseTweetWindow(w http.ResponseWriter, r *http.Request) (tweetWindow, bool) {
+	q := r.URL.Query()
+	win := tweetWindow{sinceID: q.Get("since_id"), untilID: q.Get("until_id")}
+
+	for _, name := range []string{"start_time", "end_time"} {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeInvalidParameter(w, name, raw,
+				fmt.Sprintf("Invalid '%s':'%s'. '%s' must be a valid RFC3339 date-time", name, raw, name))
+			return win, false
+		}
+		if name == "start_time" {
+			win.start = &t
+		} else {
+			win.end = &t
+		}
+	}
+
+	for name, value := range map[string]string{"since_id": win.sinceID, "until_id": win.untilID} {
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			writeInvalidParameter(w, name, value,
+				fmt.Sprintf("The `%s` query parameter value [%s] is not a valid Tweet ID", name, value))
+			return win, false
+		}
+	}
+
+	return win, true
+}
+
+// matches kiểm tra tweet có nằm trong cửa sổ thời gian/ID không
+func (win tweetWindow) matches(t *Tweet) bool {
+	if win.start != nil && t.CreatedAt.Before(*win.start) {
+		return false
+	}
+	if win.end != nil && !t.CreatedAt.Before(*win.end) {
+		return false
+	}
+	if win.sinceID != "" && compareIDs(t.ID, win.sinceID) <= 0 {
+		return false
+	}
+	if win.untilID != "" && compareIDs(t.ID, win.untilID) >= 0 {
+		return false
+	}
+	return true
+}
+
+// compareIDs so sánh hai snowflake ID dạng chuỗi số
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// excluded kiểm tra tham số exclude=retweets,replies của user timeline
+func excluded(r *http.Request, t *Tweet) bool {
+	for _, ex := range strings.Split(r.URL.Query().Get("exclude"), ",") {
+		for _, ref := range t.ReferencedTweets {
+			if (ex == "retweets" && ref.Type == "retweeted") || (ex == "replies" && ref.Type == "replied_to") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// wantsExpansion kiểm tra expansions có chứa name không
+func wantsExpansion(r *http.Request, name string) bool {
+	for _, e := range strings.Split(r.URL.Query().Get("expansions"), ",") {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// searchQuery là một query tìm kiếm đã parse: các nhóm điều kiện nối bằng OR,
+// trong mỗi nhóm các điều kiện nối bằng AND
+type searchQuery [][]searchTerm
+
+type searchTerm struct {
+	negate bool
+	op     string // "", "from", "is"
+	value  string
+}
+
+// parseSearchQuery hỗ trợ tập con cú pháp search của X: từ khóa, "cụm từ",
+// #hashtag, @mention, from:username, is:retweet/reply/quote, -phủ định và OR
+func parseSearchQuery(raw string) searchQuery {
+	var groups searchQuery
+	var current []searchTerm
+
+	for _, token := range tokenizeQuery(raw) {
+		if token == "OR" {
+			if len(current) > 0 {
+				groups = append(groups, current)
+			}
+			current = nil
+			continue
+		}
+
+		term := searchTerm{}
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			term.negate = true
+			token = token[1:]
+		}
+		if op, value, ok := strings.Cut(token, ":"); ok && (op == "from" || op == "is") {
+			term.op = op
+			token = value
+		}
+		term.value = strings.ToLower(strings.Trim(token, `"`))
+		current = append(current, term)
+	}
+
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// tokenizeQuery tách query theo khoảng trắng, giữ nguyên "cụm từ trong ngoặc kép"
+func tokenizeQuery(raw string) []string {
+	var tokens []string
+	var b strings.Builder
+	inQuotes := false
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			b.WriteRune(r)
+		case r == ' ' && !inQuotes:
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
+
+// matches kiểm tra tweet có khớp query không
+func (q searchQuery) matches(f *Fixtures, t *Tweet) bool {
+	for _, group := range q {
+		ok := true
+		for _, term := range group {
+			if term.matches(f, t) == term.negate {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (term searchTerm) matches(f *Fixtures, t *Tweet) bool {
+	switch term.op {
+	case "from":
+		author, ok := f.usersByID[t.AuthorID]
+		return ok && strings.EqualFold(author.Username, term.value)
+	case "is":
+		kind := map[string]string{"retweet": "retweeted", "reply": "replied_to", "quote": "quoted"}[term.value]
+		for _, ref := range t.ReferencedTweets {
+			if ref.Type == kind {
+				return true
+			}
+		}
+		return false
+	default:
+		return strings.Contains(strings.ToLower(t.Text), term.value)
+	}
+}
@@ -0,0 +1,64 @@
+package mockserver
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateWindow là trạng thái rate limit của một endpoint trong cửa sổ hiện tại
+type rateWindow struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+func (rw rateWindow) writeHeaders(h http.Header) {
+	h.Set("x-rate-limit-limit", strconv.Itoa(rw.limit))
+	h.Set("x-rate-limit-remaining", strconv.Itoa(rw.remaining))
+	h.Set("x-rate-limit-reset", strconv.FormatInt(rw.reset.Unix(), 10))
+}
+
+// rateLimiter đếm request theo cửa sổ cố định, giống cách X API reset quota
+type rateLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	limits  map[string]int
+	windows map[string]*rateWindow
+	now     func() time.Time
+}
+
+func newRateLimiter(window time.Duration, limits map[string]int) *rateLimiter {
+	return &rateLimiter{
+		window:  window,
+		limits:  limits,
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// take trừ một request khỏi quota của (family, credential); trả về false khi đã hết quota
+func (l *rateLimiter) take(family, credential string) (rateWindow, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := family + "|" + credential
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.reset) {
+		limit, ok := l.limits[family]
+		if !ok {
+			limit = fallbackRateLimit
+		}
+		w = &rateWindow{limit: limit, remaining: limit, reset: now.Add(l.window)}
+		l.windows[key] = w
+	}
+
+	if w.remaining <= 0 {
+		return *w, false
+	}
+	w.remaining--
+	return *w, true
+}
@@ -0,0 +1,374 @@
+// Package mockserver là một server giả lập các endpoints X API v2 mà
+// TwitterService sử dụng. Dữ liệu được load từ fixtures trên disk, responses
+// có meta/next_token và x-rate-limit-* headers giống API thật, nên có thể chạy
+// integration test offline bằng cách trỏ TWITTER_API_BASE_URL về server này.
+package mockserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// Options cấu hình hành vi của mock server
+type Options struct {
+	// BearerToken, nếu khác rỗng, là token duy nhất được chấp nhận
+	BearerToken string
+	// BearerTokens là Bearer token của các app khác được chấp nhận cùng BearerToken;
+	// rate limit được tính riêng cho từng token như X API
+	BearerTokens []string
+	// AccessToken, nếu khác rỗng, là oauth_token duy nhất được chấp nhận với
+	// OAuth 1.0a user context. Chữ ký OAuth không được kiểm tra.
+	AccessToken string
+	// RateLimitWindow là độ dài một cửa sổ rate limit (mặc định 15 phút như X API)
+	RateLimitWindow time.Duration
+	// RateLimits ghi đè số request tối đa mỗi cửa sổ theo path template,
+	// ví dụ "/2/users/{id}/followers": 15
+	RateLimits map[string]int
+	// DenyFullArchive giả lập token không có quyền full-archive (gói Free/Basic):
+	// /2/tweets/search/all và /2/tweets/counts/all trả về 403 như X API
+	DenyFullArchive bool
+
+	// Authorization server OAuth 2.0 giả lập (/i/oauth2/authorize, /2/oauth2/token,
+	// /2/oauth2/revoke). OAuth2ClientID rỗng chấp nhận mọi client_id; OAuth2ClientSecret
+	// khác rỗng yêu cầu xác thực client bằng HTTP Basic (confidential client).
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	// OAuth2User là username đăng nhập khi authorize (mặc định authenticated user của fixtures)
+	OAuth2User string
+	// OAuth2TokenTTL là thời gian sống của access token (mặc định 2 giờ như X API)
+	OAuth2TokenTTL time.Duration
+}
+
+// Số request mỗi 15 phút với app-only auth, theo tài liệu X API v2
+var defaultRateLimits = map[string]int{
+	"/2/users":                        300,
+	"/2/users/me":                     75,
+	"/2/users/reposts_of_me":          75,
+	"/2/users/by/username/{username}": 300,
+	"/2/users/{id}":                   300,
+	"/2/users/{id}/tweets":            1500,
+	"/2/users/{id}/mentions":          450,
+	"/2/users/{id}/following":         15,
+	"/2/users/{id}/followers":         15,
+	"/2/users/{id}/liked_tweets":      75,
+	"/2/users/{id}/blocking":          15,
+	"/2/users/{id}/muting":            15,
+	"/2/tweets":                       300,
+	"/2/tweets/{id}":                  300,
+	"/2/tweets/search/recent":         450,
+	"/2/tweets/counts/recent":         300,
+	"/2/tweets/search/all":            300,
+	"/2/tweets/counts/all":            300,
+	"/2/tweets/{id}/quote_tweets":     75,
+	"/2/tweets/{id}/retweeted_by":     75,
+	"/2/tweets/{id}/liking_users":     75,
+	"/2/tweets/{id}/hidden":           50,
+}
+
+const fallbackRateLimit = 300
+
+// Server là mock X API v2 server
+type Server struct {
+	fixtures *Fixtures
+	opts     Options
+	router   *mux.Router
+	limiter  *rateLimiter
+
+	mu            sync.Mutex
+	hidden        map[string]bool // tweet ID -> trạng thái hidden đã được PUT
+	codes         map[string]authCode
+	accessTokens  map[string]issuedToken
+	refreshTokens map[string]issuedToken
+}
+
+// New tạo mock server phục vụ dữ liệu từ fixtures
+func New(fixtures *Fixtures, opts Options) *Server {
+	if opts.RateLimitWindow <= 0 {
+		opts.RateLimitWindow = 15 * time.Minute
+	}
+	if opts.OAuth2TokenTTL <= 0 {
+		opts.OAuth2TokenTTL = 2 * time.Hour
+	}
+
+	limits := make(map[string]int, len(defaultRateLimits))
+	for path, limit := range defaultRateLimits {
+		limits[path] = limit
+	}
+	for path, limit := range opts.RateLimits {
+		limits[path] = limit
+	}
+
+	s := &Server{
+		fixtures: fixtures,
+		opts:     opts,
+		limiter:  newRateLimiter(opts.RateLimitWindow, limits),
+		hidden:   make(map[string]bool),
+
+		codes:         make(map[string]authCode),
+		accessTokens:  make(map[string]issuedToken),
+		refreshTokens: make(map[string]issuedToken),
+	}
+	s.router = s.setupRouter()
+	return s
+}
+
+// ServeHTTP implement http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func (s *Server) setupRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusNotFound, "Not Found", "Endpoint không được mock server hỗ trợ: "+r.URL.Path, "about:blank")
+	})
+
+	// Authorization server, đăng ký trước subrouter /2 vì không cần Bearer Token
+	router.HandleFunc("/i/oauth2/authorize", s.handleAuthorize).Methods("GET")
+	router.HandleFunc("/2/oauth2/token", s.handleToken).Methods("POST")
+	router.HandleFunc("/2/oauth2/revoke", s.handleRevoke).Methods("POST")
+
+	v2 := router.PathPrefix("/2").Subrouter()
+	v2.Use(s.authMiddleware)
+	v2.Use(s.rateLimitMiddleware)
+
+	// Users
+	v2.HandleFunc("/users", s.handleUsers).Methods("GET")
+	v2.HandleFunc("/users/me", s.requireUserContext(s.handleMe)).Methods("GET")
+	v2.HandleFunc("/users/reposts_of_me", s.requireUserContext(s.handleRepostsOfMe)).Methods("GET")
+	v2.HandleFunc("/users/by/username/{username}", s.handleUserByUsername).Methods("GET")
+	v2.HandleFunc("/users/{id}", s.handleUserByID).Methods("GET")
+	v2.HandleFunc("/users/{id}/tweets", s.handleUserTweets).Methods("GET")
+	v2.HandleFunc("/users/{id}/mentions", s.handleUserMentions).Methods("GET")
+	v2.HandleFunc("/users/{id}/following", s.handleFollowing).Methods("GET")
+	v2.HandleFunc("/users/{id}/followers", s.handleFollowers).Methods("GET")
+	v2.HandleFunc("/users/{id}/liked_tweets", s.handleLikedTweets).Methods("GET")
+	v2.HandleFunc("/users/{id}/blocking", s.requireUserContext(s.handleBlocking)).Methods("GET")
+	v2.HandleFunc("/users/{id}/muting", s.requireUserContext(s.handleMuting)).Methods("GET")
+
+	// Tweets
+	v2.HandleFunc("/tweets", s.handleTweets).Methods("GET")
+	v2.HandleFunc("/tweets/search/recent", s.handleSearchRecent).Methods("GET")
+	v2.HandleFunc("/tweets/counts/recent", s.handleCountsRecent).Methods("GET")
+	v2.HandleFunc("/tweets/search/all", s.requireFullArchive(s.handleSearchAll)).Methods("GET")
+	v2.HandleFunc("/tweets/counts/all", s.requireFullArchive(s.handleCountsAll)).Methods("GET")
+	v2.HandleFunc("/tweets/{id}", s.handleTweetByID).Methods("GET")
+	v2.HandleFunc("/tweets/{id}/quote_tweets", s.handleQuoteTweets).Methods("GET")
+	v2.HandleFunc("/tweets/{id}/retweeted_by", s.handleRetweetedBy).Methods("GET")
+	v2.HandleFunc("/tweets/{id}/liking_users", s.handleLikingUsers).Methods("GET")
+	v2.HandleFunc("/tweets/{id}/hidden", s.requireUserContext(s.handleHideReply)).Methods("PUT")
+
+	return router
+}
+
+// credentialKey là context key chứa credential của request: "Bearer <token>" hoặc
+// "OAuth <oauth_token>" (không gồm nonce/timestamp thay đổi theo từng request)
+type credentialKey struct{}
+
+// oauth2UserKey là context key chứa user ID của access token OAuth 2.0 user context
+type oauth2UserKey struct{}
+
+// authMiddleware yêu cầu header Authorization: Bearer <token> (app-only hoặc access
+// token OAuth 2.0 do mock cấp) hoặc OAuth ... oauth_token="..." (OAuth 1.0a user context)
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		ctx := r.Context()
+		var credential string
+		switch {
+		case strings.HasPrefix(header, "Bearer "):
+			token := strings.TrimPrefix(header, "Bearer ")
+			if userID, issued, expired := s.lookupAccessToken(token); issued {
+				if expired {
+					writeProblem(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized", "about:blank")
+					return
+				}
+				ctx = context.WithValue(ctx, oauth2UserKey{}, userID)
+				credential = header
+				break
+			}
+			if !s.acceptsBearer(token) {
+				writeProblem(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized", "about:blank")
+				return
+			}
+			credential = header
+		case strings.HasPrefix(header, "OAuth "):
+			token := oauthToken(header)
+			if token == "" || (s.opts.AccessToken != "" && token != s.opts.AccessToken) {
+				writeProblem(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized", "about:blank")
+				return
+			}
+			credential = "OAuth " + token
+		default:
+			writeProblem(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized", "about:blank")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, credentialKey{}, credential)))
+	})
+}
+
+// acceptsBearer cho biết Bearer token của app có hợp lệ không; không cấu hình
+// BearerToken lẫn BearerTokens thì chấp nhận mọi token khác rỗng
+func (s *Server) acceptsBearer(token string) bool {
+	if token == "" {
+		return false
+	}
+	if s.opts.BearerToken == "" && len(s.opts.BearerTokens) == 0 {
+		return true
+	}
+	if token == s.opts.BearerToken {
+		return true
+	}
+	for _, accepted := range s.opts.BearerTokens {
+		if token == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// oauthToken lấy oauth_token từ header Authorization OAuth 1.0a; rỗng nếu không có
+func oauthToken(header string) string {
+	for _, param := range strings.Split(strings.TrimPrefix(header, "OAuth "), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || key != "oauth_token" {
+			continue
+		}
+		token, err := url.QueryUnescape(strings.Trim(value, `"`))
+		if err != nil {
+			return ""
+		}
+		return token
+	}
+	return ""
+}
+
+// userContext cho biết request có được gửi với user context (OAuth 1.0a hoặc access
+// token OAuth 2.0 của user) không
+func userContext(r *http.Request) bool {
+	if _, ok := r.Context().Value(oauth2UserKey{}).(string); ok {
+		return true
+	}
+	credential, _ := r.Context().Value(credentialKey{}).(string)
+	return strings.HasPrefix(credential, "OAuth ")
+}
+
+// me là user ID của authenticated user: chủ access token OAuth 2.0, hoặc user
+// Relations.Me của fixtures với OAuth 1.0a
+func (s *Server) me(r *http.Request) string {
+	if userID, ok := r.Context().Value(oauth2UserKey{}).(string); ok {
+		return userID
+	}
+	return s.fixtures.Relations.Me
+}
+
+// requireUserContext trả về 403 unsupported-authentication như X API khi endpoint
+// cần authenticated user nhưng request dùng Bearer Token (app-only)
+func (s *Server) requireUserContext(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !userContext(r) {
+			writeProblem(w, http.StatusForbidden, "Unsupported Authentication",
+				"Authenticating with OAuth 2.0 Application-Only is forbidden for this endpoint.  Supported authentication types are [OAuth 1.0a User Context, OAuth 2.0 User Context].",
+				"https://api.twitter.com/2/problems/unsupported-authentication")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireFullArchive trả về 403 client-forbidden như X API khi token không có quyền
+// full-archive (Options.DenyFullArchive)
+func (s *Server) requireFullArchive(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.DenyFullArchive {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"title":               "Client Forbidden",
+				"detail":              "When authenticating requests to the Twitter API v2 endpoints, you must use keys and tokens from a Twitter developer App that is attached to a Project with the appropriate level of API access.",
+				"reason":              "client-not-enrolled",
+				"required_enrollment": "Appropriate Level of API Access",
+				"registration_url":    "https://developer.twitter.com/en/portal/product",
+				"type":                "https://api.twitter.com/2/problems/client-forbidden",
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitMiddleware đếm request theo (path template, credential) và gắn x-rate-limit-* headers
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		family := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				family = tpl
+			}
+		}
+
+		credential, _ := r.Context().Value(credentialKey{}).(string)
+		window, ok := s.limiter.take(family, credential)
+		window.writeHeaders(w.Header())
+		if !ok {
+			log.WithField("endpoint", family).Warn("Mock X API: rate limit exceeded")
+			writeProblem(w, http.StatusTooManyRequests, "Too Many Requests", "Too Many Requests", "about:blank")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON gửi response JSON với status code
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.WithError(err).Error("Mock X API: lỗi khi encode JSON response")
+	}
+}
+
+// writeProblem gửi lỗi theo format problem của X API v2
+func writeProblem(w http.ResponseWriter, status int, title, detail, problemType string) {
+	writeJSON(w, status, map[string]interface{}{
+		"title":  title,
+		"detail": detail,
+		"type":   problemType,
+		"status": status,
+	})
+}
+
+// writeInvalidParameter gửi lỗi 400 giống X API khi query parameter không hợp lệ
+func writeInvalidParameter(w http.ResponseWriter, name, value, message string) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"errors": []map[string]interface{}{
+			{
+				"parameters": map[string][]string{name: {value}},
+				"message":    message,
+			},
+		},
+		"title":  "Invalid Request",
+		"detail": "One or more parameters to your request was invalid.",
+		"type":   "https://api.twitter.com/2/problems/invalid-request",
+	})
+}
+
+// resourceNotFound tạo một partial error "resource-not-found" như X API trả về với status 200
+func resourceNotFound(resourceType, parameter, value, detail string) map[string]interface{} {
+	return map[string]interface{}{
+		"value":         value,
+		"detail":        detail,
+		"title":         "Not Found Error",
+		"resource_type": resourceType,
+		"parameter":     parameter,
+		"resource_id":   value,
+		"type":          "https://api.twitter.com/2/problems/resource-not-found",
+	}
+}
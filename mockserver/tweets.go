@@ -0,0 +1,343 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// handleUserTweets xử lý GET /2/users/:id/tweets
+func (s *Server) handleUserTweets(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	win, ok := parseTweetWindow(w, r)
+	if !ok {
+		return
+	}
+
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return t.AuthorID == user.ID && win.matches(t) && !excluded(r, t)
+	})
+	s.writeTweetPage(w, r, tweets, 5, 100, 10, "pagination_token", true)
+}
+
+// handleUserMentions xử lý GET /2/users/:id/mentions
+func (s *Server) handleUserMentions(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	win, ok := parseTweetWindow(w, r)
+	if !ok {
+		return
+	}
+
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return mentions(t, user.Username) && win.matches(t)
+	})
+	s.writeTweetPage(w, r, tweets, 5, 100, 10, "pagination_token", true)
+}
+
+// handleLikedTweets xử lý GET /2/users/:id/liked_tweets
+func (s *Server) handleLikedTweets(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	tweets := s.fixtures.tweetsFor(s.fixtures.Relations.Likes[user.ID])
+	s.writeTweetPage(w, r, tweets, 5, 100, 100, "pagination_token", false)
+}
+
+// handleTweetByID xử lý GET /2/tweets/:id
+func (s *Server) handleTweetByID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	tweet, ok := s.fixtures.tweetsByID[id]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{
+				resourceNotFound("tweet", "id", id, "Could not find tweet with id: ["+id+"]."),
+			},
+		})
+		return
+	}
+
+	resp := map[string]interface{}{"data": tweet}
+	s.addIncludes(r, resp, []Tweet{*tweet})
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleHideReply xử lý PUT /2/tweets/:id/hidden (cần user context). Như X API, chỉ
+// ẩn/hiện được reply trong conversation do authenticated user bắt đầu.
+func (s *Server) handleHideReply(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		Hidden *bool `json:"hidden"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Hidden == nil {
+		writeInvalidParameter(w, "hidden", "", "The `hidden` field is required")
+		return
+	}
+
+	tweet, ok := s.fixtures.tweetsByID[id]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "Not Found Error", "Could not find tweet with id: ["+id+"].", "https://api.twitter.com/2/problems/resource-not-found")
+		return
+	}
+	root, ok := s.fixtures.tweetsByID[tweet.ConversationID]
+	if tweet.ConversationID == tweet.ID || !ok || root.AuthorID != s.me(r) {
+		writeProblem(w, http.StatusForbidden, "Forbidden", "You can only hide or unhide replies to conversations you authored.", "about:blank")
+		return
+	}
+
+	s.mu.Lock()
+	s.hidden[id] = *body.Hidden
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]bool{"hidden": *body.Hidden},
+	})
+}
+
+// handleTweets xử lý GET /2/tweets?ids=...
+func (s *Server) handleTweets(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query().Get("ids")
+	if ids == "" {
+		writeInvalidParameter(w, "ids", "", "The `ids` query parameter can not be empty")
+		return
+	}
+
+	tweets := make([]Tweet, 0)
+	notFound := make([]interface{}, 0)
+	for _, id := range strings.Split(ids, ",") {
+		if tweet, ok := s.fixtures.tweetsByID[id]; ok {
+			tweets = append(tweets, *tweet)
+		} else {
+			notFound = append(notFound, resourceNotFound("tweet", "ids", id, "Could not find tweet with ids: ["+id+"]."))
+		}
+	}
+
+	resp := map[string]interface{}{}
+	if len(tweets) > 0 {
+		resp["data"] = tweets
+		s.addIncludes(r, resp, tweets)
+	}
+	if len(notFound) > 0 {
+		resp["errors"] = notFound
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSearchRecent xử lý GET /2/tweets/search/recent
+func (s *Server) handleSearchRecent(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("query")
+	if raw == "" {
+		writeInvalidParameter(w, "query", "", "The `query` query parameter can not be empty")
+		return
+	}
+	win, ok := parseTweetWindow(w, r)
+	if !ok {
+		return
+	}
+
+	query := parseSearchQuery(raw)
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return query.matches(s.fixtures, t) && win.matches(t)
+	})
+	s.writeTweetPage(w, r, tweets, 10, 100, 10, "next_token", true)
+}
+
+// handleSearchAll xử lý GET /2/tweets/search/all (full-archive, tối đa 500 tweets mỗi trang)
+func (s *Server) handleSearchAll(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("query")
+	if raw == "" {
+		writeInvalidParameter(w, "query", "", "The `query` query parameter can not be empty")
+		return
+	}
+	win, ok := parseTweetWindow(w, r)
+	if !ok {
+		return
+	}
+
+	query := parseSearchQuery(raw)
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return query.matches(s.fixtures, t) && win.matches(t)
+	})
+	s.writeTweetPage(w, r, tweets, 10, 500, 10, "next_token", true)
+}
+
+// handleCountsRecent xử lý GET /2/tweets/counts/recent
+func (s *Server) handleCountsRecent(w http.ResponseWriter, r *http.Request) {
+	s.writeCounts(w, r, 7*24*time.Hour)
+}
+
+// handleCountsAll xử lý GET /2/tweets/counts/all; mọi bucket nằm trong một trang
+func (s *Server) handleCountsAll(w http.ResponseWriter, r *http.Request) {
+	s.writeCounts(w, r, 30*24*time.Hour)
+}
+
+// writeCounts đếm tweets khớp query theo granularity, mặc định trong khoảng defaultWindow
+// trước hiện tại
+func (s *Server) writeCounts(w http.ResponseWriter, r *http.Request, defaultWindow time.Duration) {
+	raw := r.URL.Query().Get("query")
+	if raw == "" {
+		writeInvalidParameter(w, "query", "", "The `query` query parameter can not be empty")
+		return
+	}
+	win, ok := parseTweetWindow(w, r)
+	if !ok {
+		return
+	}
+
+	step := time.Hour
+	granularity := r.URL.Query().Get("granularity")
+	switch granularity {
+	case "", "hour":
+		granularity = "hour"
+	case "minute":
+		step = time.Minute
+	case "day":
+		step = 24 * time.Hour
+	default:
+		writeInvalidParameter(w, "granularity", granularity,
+			"The `granularity` query parameter value ["+granularity+"] is not one of [minute,hour,day]")
+		return
+	}
+
+	now := time.Now().UTC()
+	end := now.Add(-30 * time.Second)
+	if win.end != nil {
+		end = *win.end
+	}
+	start := end.Add(-defaultWindow)
+	if win.start != nil {
+		start = *win.start
+	}
+
+	query := parseSearchQuery(raw)
+	matched := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return query.matches(s.fixtures, t) && win.matches(t)
+	})
+
+	buckets := make([]map[string]interface{}, 0)
+	total := 0
+	for bucketStart := start; bucketStart.Before(end); {
+		bucketEnd := bucketStart.Truncate(step).Add(step)
+		if bucketEnd.After(end) {
+			bucketEnd = end
+		}
+
+		count := 0
+		for i := range matched {
+			if !matched[i].CreatedAt.Before(bucketStart) && matched[i].CreatedAt.Before(bucketEnd) {
+				count++
+			}
+		}
+		total += count
+
+		buckets = append(buckets, map[string]interface{}{
+			"start":       bucketStart.UTC().Format(time.RFC3339Nano),
+			"end":         bucketEnd.UTC().Format(time.RFC3339Nano),
+			"tweet_count": count,
+		})
+		bucketStart = bucketEnd
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": buckets,
+		"meta": map[string]interface{}{"total_tweet_count": total},
+	})
+}
+
+// handleQuoteTweets xử lý GET /2/tweets/:id/quote_tweets
+func (s *Server) handleQuoteTweets(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		for _, ref := range t.ReferencedTweets {
+			if ref.Type == "quoted" && ref.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+	s.writeTweetPage(w, r, tweets, 10, 100, 10, "pagination_token", false)
+}
+
+// handleRetweetedBy xử lý GET /2/tweets/:id/retweeted_by
+func (s *Server) handleRetweetedBy(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.Relations.Retweets[id]), 1, 100, 100)
+}
+
+// handleLikingUsers xử lý GET /2/tweets/:id/liking_users
+func (s *Server) handleLikingUsers(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.likersOf(id)), 1, 100, 100)
+}
+
+// writeTweetPage gửi một trang tweets; timeline=true thêm newest_id/oldest_id vào meta
+func (s *Server) writeTweetPage(w http.ResponseWriter, r *http.Request, tweets []Tweet, lo, hi, def int, tokenParam string, timeline bool) {
+	limit, ok := parseMaxResults(w, r, lo, hi, def)
+	if !ok {
+		return
+	}
+
+	page, info, ok := paginate(w, r, tweets, limit, tokenParam)
+	if !ok {
+		return
+	}
+
+	meta := map[string]interface{}{"result_count": len(page)}
+	if timeline && len(page) > 0 {
+		meta["newest_id"] = page[0].ID
+		meta["oldest_id"] = page[len(page)-1].ID
+	}
+	if info.next != "" {
+		meta["next_token"] = info.next
+	}
+	if info.previous != "" && tokenParam == "pagination_token" {
+		meta["previous_token"] = info.previous
+	}
+
+	resp := map[string]interface{}{"meta": meta}
+	if len(page) > 0 {
+		resp["data"] = page
+		s.addIncludes(r, resp, page)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// addIncludes thêm includes.users khi client yêu cầu expansions=author_id
+func (s *Server) addIncludes(r *http.Request, resp map[string]interface{}, tweets []Tweet) {
+	if !wantsExpansion(r, "author_id") {
+		return
+	}
+
+	seen := make(map[string]bool)
+	authors := make([]string, 0)
+	for _, t := range tweets {
+		if !seen[t.AuthorID] {
+			seen[t.AuthorID] = true
+			authors = append(authors, t.AuthorID)
+		}
+	}
+	resp["includes"] = map[string]interface{}{"users": s.fixtures.usersFor(authors)}
+}
+
+// mentions kiểm tra tweet có mention đến username không
+func mentions(t *Tweet, username string) bool {
+	if t.Entities != nil {
+		for _, m := range t.Entities.Mentions {
+			if strings.EqualFold(m.Username, username) {
+				return true
+			}
+		}
+	}
+	return strings.Contains(strings.ToLower(t.Text), "@"+strings.ToLower(username))
+}
@@ -0,0 +1,185 @@
+package mockserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// handleUserByUsername xử lý GET /2/users/by/username/:username
+func (s *Server) handleUserByUsername(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	user, ok := s.fixtures.usersByUsername[strings.ToLower(username)]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{
+				resourceNotFound("user", "username", username, "Could not find user with username: ["+username+"]."),
+			},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": user})
+}
+
+// handleUserByID xử lý GET /2/users/:id
+func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	user, ok := s.fixtures.usersByID[id]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{
+				resourceNotFound("user", "id", id, "Could not find user with id: ["+id+"]."),
+			},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": user})
+}
+
+// handleUsers xử lý GET /2/users?ids=...
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query().Get("ids")
+	if ids == "" {
+		writeInvalidParameter(w, "ids", "", "The `ids` query parameter can not be empty")
+		return
+	}
+
+	users := make([]User, 0)
+	notFound := make([]interface{}, 0)
+	for _, id := range strings.Split(ids, ",") {
+		if user, ok := s.fixtures.usersByID[id]; ok {
+			users = append(users, *user)
+		} else {
+			notFound = append(notFound, resourceNotFound("user", "ids", id, "Could not find user with ids: ["+id+"]."))
+		}
+	}
+
+	resp := map[string]interface{}{}
+	if len(users) > 0 {
+		resp["data"] = users
+	}
+	if len(notFound) > 0 {
+		resp["errors"] = notFound
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleMe xử lý GET /2/users/me
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.fixtures.usersByID[s.me(r)]
+	if !ok {
+		writeProblem(w, http.StatusForbidden, "Unsupported Authentication",
+			"Authenticating with OAuth 2.0 Application-Only is forbidden for this endpoint.  Supported authentication types are [OAuth 1.0a User Context, OAuth 2.0 User Context].",
+			"https://api.twitter.com/2/problems/unsupported-authentication")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": user})
+}
+
+// handleFollowing xử lý GET /2/users/:id/following
+func (s *Server) handleFollowing(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.Relations.Following[user.ID]), 1, 1000, 100)
+}
+
+// handleFollowers xử lý GET /2/users/:id/followers
+func (s *Server) handleFollowers(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return
+	}
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.followersOf(user.ID)), 1, 1000, 100)
+}
+
+// handleBlocking xử lý GET /2/users/:id/blocking (cần user context)
+func (s *Server) handleBlocking(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathMe(w, r)
+	if !ok {
+		return
+	}
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.Relations.Blocking[user.ID]), 1, 1000, 100)
+}
+
+// handleMuting xử lý GET /2/users/:id/muting (cần user context)
+func (s *Server) handleMuting(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.pathMe(w, r)
+	if !ok {
+		return
+	}
+	s.writeUserPage(w, r, s.fixtures.usersFor(s.fixtures.Relations.Muting[user.ID]), 1, 1000, 100)
+}
+
+// handleRepostsOfMe xử lý GET /2/users/reposts_of_me (cần user context): các tweets
+// của authenticated user đã được người khác retweet
+func (s *Server) handleRepostsOfMe(w http.ResponseWriter, r *http.Request) {
+	me := s.me(r)
+	tweets := s.fixtures.filterTweets(func(t *Tweet) bool {
+		return t.AuthorID == me && len(s.fixtures.Relations.Retweets[t.ID]) > 0
+	})
+	s.writeTweetPage(w, r, tweets, 1, 100, 10, "pagination_token", false)
+}
+
+// pathMe như pathUser, nhưng {id} phải là authenticated user như X API yêu cầu với
+// các danh sách riêng tư (blocking, muting)
+func (s *Server) pathMe(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	user, ok := s.pathUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	if user.ID != s.me(r) {
+		writeProblem(w, http.StatusForbidden, "Forbidden", "You are not permitted to perform this action.", "about:blank")
+		return nil, false
+	}
+	return user, true
+}
+
+// pathUser lấy user theo {id} trong path; gửi lỗi not-found nếu không tồn tại
+func (s *Server) pathUser(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	id := mux.Vars(r)["id"]
+	user, ok := s.fixtures.usersByID[id]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errors": []interface{}{
+				resourceNotFound("user", "id", id, "Could not find user with id: ["+id+"]."),
+			},
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+// writeUserPage gửi một trang users với meta result_count/next_token/previous_token
+func (s *Server) writeUserPage(w http.ResponseWriter, r *http.Request, users []User, lo, hi, def int) {
+	limit, ok := parseMaxResults(w, r, lo, hi, def)
+	if !ok {
+		return
+	}
+
+	page, info, ok := paginate(w, r, users, limit, "pagination_token")
+	if !ok {
+		return
+	}
+
+	meta := map[string]interface{}{"result_count": len(page)}
+	if info.next != "" {
+		meta["next_token"] = info.next
+	}
+	if info.previous != "" {
+		meta["previous_token"] = info.previous
+	}
+
+	resp := map[string]interface{}{"meta": meta}
+	if len(page) > 0 {
+		resp["data"] = page
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
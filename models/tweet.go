@@ -4,12 +4,12 @@ import "time"
 
 // Tweet đại diện cho một tweet từ Twitter/X
 type Tweet struct {
-	ID               string          `json:"id"`
-	Text             string          `json:"text"`
-	AuthorID         string          `json:"author_id"`
-	CreatedAt        time.Time       `json:"created_at"`
-	Metrics          *TweetMetrics   `json:"metrics,omitempty"`
-	Entities         *TweetEntities  `json:"entities,omitempty"`
+	ID               string            `json:"id"`
+	Text             string            `json:"text"`
+	AuthorID         string            `json:"author_id"`
+	CreatedAt        time.Time         `json:"created_at"`
+	Metrics          *TweetMetrics     `json:"metrics,omitempty"`
+	Entities         *TweetEntities    `json:"entities,omitempty"`
 	ReferencedTweets []ReferencedTweet `json:"referenced_tweets,omitempty"`
 }
 
@@ -55,13 +55,13 @@ type ReferencedTweet struct {
 
 // User đại diện cho thông tin user Twitter/X
 type User struct {
-	ID              string      `json:"id"`
-	Username        string      `json:"username"`
-	Name            string      `json:"name"`
-	Description     string      `json:"description,omitempty"`
-	ProfileImageURL string      `json:"profile_image_url,omitempty"`
-	Verified        bool        `json:"verified"`
-	CreatedAt       time.Time   `json:"created_at"`
+	ID              string       `json:"id"`
+	Username        string       `json:"username"`
+	Name            string       `json:"name"`
+	Description     string       `json:"description,omitempty"`
+	ProfileImageURL string       `json:"profile_image_url,omitempty"`
+	Verified        bool         `json:"verified"`
+	CreatedAt       time.Time    `json:"created_at"`
 	Metrics         *UserMetrics `json:"metrics,omitempty"`
 }
 
@@ -85,6 +85,10 @@ type Meta struct {
 	ResultCount   int    `json:"result_count"`
 	NextToken     string `json:"next_token,omitempty"`
 	PreviousToken string `json:"previous_token,omitempty"`
+	// Pages và StopReason chỉ có khi auto-pagination (all=true): số trang đã gộp và lý do
+	// dừng trước khi hết dữ liệu (khi đó NextToken là cursor để gọi tiếp)
+	Pages      int    `json:"pages,omitempty"`
+	StopReason string `json:"stop_reason,omitempty"`
 }
 
 // FollowingResponse là response structure cho API lấy danh sách followings
@@ -96,9 +100,37 @@ type FollowingResponse struct {
 
 // ErrorResponse là response structure cho lỗi
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"` // chi tiết lỗi từ X API
+	Code      int    `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// StreamTrailer là record cuối của response NDJSON: meta của cả stream (next_token là
+// cursor để gọi tiếp), kèm lỗi nếu stream bị ngắt sau khi đã gửi một phần items
+type StreamTrailer struct {
+	Meta  *Meta          `json:"meta"`
+	Error *ErrorResponse `json:"error,omitempty"`
+}
+
+// ProblemDetails là error response theo RFC 7807 (application/problem+json)
+type ProblemDetails struct {
+	Type           string         `json:"type"`
+	Title          string         `json:"title"`
+	Status         int            `json:"status"`
+	Detail         string         `json:"detail,omitempty"`
+	Instance       string         `json:"instance,omitempty"`
+	Code           string         `json:"code"`
+	RequestID      string         `json:"request_id,omitempty"`
+	UpstreamDetail string         `json:"upstream_detail,omitempty"` // chi tiết lỗi từ X API
+	InvalidParams  []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// InvalidParam mô tả một parameter không hợp lệ trong ProblemDetails
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
 }
 
 // SearchTweetsResponse là response structure cho API tìm kiếm tweets
@@ -163,9 +195,14 @@ type RetweetedByResponse struct {
 
 // TweetCountsResponse là response structure cho API lấy tweet counts
 type TweetCountsResponse struct {
-	Query       string         `json:"query"`
-	Counts     []TweetCount   `json:"counts"`
-	Meta       *Meta          `json:"meta,omitempty"`
+	Query       string       `json:"query"`
+	Granularity string       `json:"granularity"`
+	Counts      []TweetCount `json:"counts"`
+	// TotalTweetCount là tổng tweet_count của mọi bucket, Peak là bucket nhiều tweets
+	// nhất (bucket sớm nhất nếu bằng nhau, không có nếu tổng bằng 0)
+	TotalTweetCount int         `json:"total_tweet_count"`
+	Peak            *TweetCount `json:"peak,omitempty"`
+	Meta            *Meta       `json:"meta,omitempty"`
 }
 
 // TweetCount chứa thông tin count của tweet trong một khoảng thời gian
@@ -183,16 +220,16 @@ type UsersListResponse struct {
 
 // BlockingUsersResponse là response structure cho API lấy blocking users
 type BlockingUsersResponse struct {
-	User   *User  `json:"user"`
-	Users  []User `json:"users"`
-	Meta   *Meta  `json:"meta,omitempty"`
+	User  *User  `json:"user"`
+	Users []User `json:"users"`
+	Meta  *Meta  `json:"meta,omitempty"`
 }
 
 // MutingUsersResponse là response structure cho API lấy muting users
 type MutingUsersResponse struct {
-	User   *User  `json:"user"`
-	Users  []User `json:"users"`
-	Meta   *Meta  `json:"meta,omitempty"`
+	User  *User  `json:"user"`
+	Users []User `json:"users"`
+	Meta  *Meta  `json:"meta,omitempty"`
 }
 
 // HideTweetResponse là response structure cho API hide/unhide tweet
@@ -209,3 +246,70 @@ type RepostsResponse struct {
 	Meta   *Meta   `json:"meta,omitempty"`
 }
 
+// RateLimitStatus là trạng thái rate limit của X API cho một endpoint family
+type RateLimitStatus struct {
+	Endpoint       string    `json:"endpoint"`
+	Limit          int       `json:"limit"`
+	Remaining      int       `json:"remaining"`
+	ResetAt        time.Time `json:"reset_at"`
+	ResetInSeconds int       `json:"reset_in_seconds"`
+	Exhausted      bool      `json:"exhausted"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// RateLimitsResponse là response structure cho API xem rate limits
+type RateLimitsResponse struct {
+	RateLimits []RateLimitStatus `json:"rate_limits"`
+}
+
+// CircuitBreakerStatus là trạng thái circuit breaker của một endpoint family
+type CircuitBreakerStatus struct {
+	Endpoint            string     `json:"endpoint"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Trips               int        `json:"trips"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// CircuitBreakersResponse là danh sách trạng thái circuit breakers
+type CircuitBreakersResponse struct {
+	CircuitBreakers []CircuitBreakerStatus `json:"circuit_breakers"`
+}
+
+// BearerTokenStatus là trạng thái sử dụng của một Bearer Token trong pool. Token
+// không bao giờ được trả về, chỉ có tên và fingerprint (8 ký tự đầu của SHA-256).
+type BearerTokenStatus struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	// State: active, quarantined (mọi endpoint) hoặc degraded (một số endpoint)
+	State       string            `json:"state"`
+	Requests    int64             `json:"requests"`
+	Errors      int64             `json:"errors"`
+	LastStatus  int               `json:"last_status,omitempty"`
+	LastUsedAt  *time.Time        `json:"last_used_at,omitempty"`
+	Quarantines []TokenQuarantine `json:"quarantines,omitempty"`
+	RateLimits  []RateLimitStatus `json:"rate_limits"`
+}
+
+// TokenQuarantine là một lần cách ly Bearer Token sau khi X API trả 401/403;
+// Endpoint "*" nghĩa là token bị bỏ qua với mọi endpoint
+type TokenQuarantine struct {
+	Endpoint string    `json:"endpoint"`
+	Status   int       `json:"status"`
+	Reason   string    `json:"reason,omitempty"`
+	Until    time.Time `json:"until"`
+}
+
+// TokenPoolResponse là response của API xem mức sử dụng các Bearer Token
+type TokenPoolResponse struct {
+	Tokens []BearerTokenStatus `json:"tokens"`
+}
+
+// SessionResponse là response của /auth/callback khi đăng nhập thành công;
+// ExpiresAt là hạn của access token hiện tại (được refresh tự động)
+type SessionResponse struct {
+	User      *User     `json:"user"`
+	Scope     string    `json:"scope,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Trạng thái cache của một request (header X-Cache)
+const (
+	CacheHit  = "HIT"
+	CacheMiss = "MISS"
+)
+
+// Nhóm TTL của cache
+const (
+	cacheCategoryProfile  = "profile"
+	cacheCategoryTimeline = "timeline"
+	cacheCategorySearch   = "search"
+	cacheCategoryCounts   = "counts"
+)
+
+var cacheRequests = metrics.NewCounter("x_cache_requests_total",
+	"Số lần tra cứu response cache theo nhóm TTL và kết quả (hit, miss, bypass)",
+	"category", "result")
+
+type cacheBypassKey struct{}
+
+type cacheRecorderKey struct{}
+
+// WithCacheBypass đánh dấu request bỏ qua cache (ví dụ Cache-Control: no-cache).
+// Response mới vẫn được ghi lại vào cache.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CacheRecorder ghi nhận request có được phục vụ từ cache không
+type CacheRecorder struct {
+	mu     sync.Mutex
+	status string
+}
+
+// WithCacheRecorder gắn recorder vào context để CachedTwitterService ghi kết quả
+func WithCacheRecorder(ctx context.Context, rec *CacheRecorder) context.Context {
+	return context.WithValue(ctx, cacheRecorderKey{}, rec)
+}
+
+// Status trả về HIT, MISS hoặc rỗng nếu request không đi qua cache.
+// Nếu request tra cache nhiều lần, chỉ HIT khi tất cả đều HIT.
+func (r *CacheRecorder) Status() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func recordCacheStatus(ctx context.Context, status string) {
+	rec, ok := ctx.Value(cacheRecorderKey{}).(*CacheRecorder)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.status != CacheMiss {
+		rec.status = status
+	}
+}
+
+// CachedTwitterService là decorator của TwitterAPI, cache responses của các API đọc
+// dữ liệu trong CacheStore (in-memory hoặc Redis dùng chung) với TTL theo nhóm endpoint
+// (profile, timeline, search, counts)
+type CachedTwitterService struct {
+	next  TwitterAPI
+	store CacheStore
+	ttls  map[string]time.Duration
+
+	// Giới hạn maxResults giống TwitterService để các count cho cùng một upstream call dùng chung key
+	defaultCount int
+	maxTweets    int
+}
+
+// NewCachedTwitterService bọc next bằng response cache lưu trong store, TTL theo cấu hình CACHE_TTL_*
+func NewCachedTwitterService(next TwitterAPI, store CacheStore, cfg *config.Config) *CachedTwitterService {
+	log.WithFields(log.Fields{
+		"backend":      store.Backend(),
+		"max_entries":  cfg.CacheMaxEntries,
+		"ttl_profile":  cfg.CacheTTLProfile.String(),
+		"ttl_timeline": cfg.CacheTTLTimeline.String(),
+		"ttl_search":   cfg.CacheTTLSearch.String(),
+		"ttl_counts":   cfg.CacheTTLCounts.String(),
+	}).Info("🗄️  Response cache đã được bật")
+
+	return &CachedTwitterService{
+		next:         next,
+		store:        store,
+		defaultCount: cfg.DefaultTweetsCount,
+		maxTweets:    cfg.MaxTweetsPerRequest,
+		ttls: map[string]time.Duration{
+			cacheCategoryProfile:  cfg.CacheTTLProfile,
+			cacheCategoryTimeline: cfg.CacheTTLTimeline,
+			cacheCategorySearch:   cfg.CacheTTLSearch,
+			cacheCategoryCounts:   cfg.CacheTTLCounts,
+		},
+	}
+}
+
+// cached trả về response đã cache của key nếu còn hạn, nếu không thì gọi fetch và
+// lưu kết quả (dạng JSON để các request không chia sẻ cùng một object). Lỗi không được cache.
+func cached[T any](ctx context.Context, c *CachedTwitterService, category, key string, fetch func() (T, error)) (T, error) {
+	ttl := c.ttls[category]
+	if ttl <= 0 {
+		return fetch()
+	}
+	// Response gọi bằng token của end user (có thể khác response của app) không dùng chung giữa các user
+	key = userScopedKey(ctx, key)
+
+	if !cacheBypassed(ctx) {
+		data, ok, err := c.store.Get(ctx, key)
+		if err != nil {
+			c.storeError("get", key, err)
+		} else if ok {
+			var value T
+			if err := json.Unmarshal(data, &value); err == nil {
+				cacheRequests.Inc(category, "hit")
+				recordCacheStatus(ctx, CacheHit)
+				return value, nil
+			}
+			c.delete(ctx, key)
+		}
+		cacheRequests.Inc(category, "miss")
+	} else {
+		cacheRequests.Inc(category, "bypass")
+	}
+	recordCacheStatus(ctx, CacheMiss)
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.WithError(err).WithField("cache_key", key).Warn("Không thể lưu response vào cache")
+		return value, nil
+	}
+	// Vẫn lưu response khi client đã hủy request vì quota X API đã được dùng
+	if err := c.store.Set(context.WithoutCancel(ctx), key, data, ttl); err != nil {
+		c.storeError("set", key, err)
+	}
+	return value, nil
+}
+
+// delete xóa key khỏi store; lỗi chỉ được log
+func (c *CachedTwitterService) delete(ctx context.Context, key string) {
+	if err := c.store.Delete(context.WithoutCancel(ctx), key); err != nil {
+		c.storeError("delete", key, err)
+	}
+}
+
+func (c *CachedTwitterService) storeError(op, key string, err error) {
+	cacheStoreErrors.Inc(c.store.Backend(), op)
+	log.WithError(err).WithFields(log.Fields{
+		"backend":   c.store.Backend(),
+		"op":        op,
+		"cache_key": key,
+	}).Warn("Lỗi cache store, bỏ qua cache")
+}
+
+// cacheKey ghép tên method và các tham số đã chuẩn hóa thành cache key
+func cacheKey(method string, params ...interface{}) string {
+	parts := make([]string, 0, len(params)+1)
+	parts = append(parts, method)
+	for _, p := range params {
+		parts = append(parts, fmt.Sprint(p))
+	}
+	return strings.Join(parts, "|")
+}
+
+// normalizeUsername bỏ @ và chuyển về chữ thường vì username không phân biệt hoa thường
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+}
+
+// limit chuẩn hóa maxResults theo cách TwitterService làm: <= 0 dùng default, tối đa max
+func (c *CachedTwitterService) limit(maxResults, max int) int {
+	if maxResults <= 0 {
+		maxResults = c.defaultCount
+	}
+	if maxResults > max {
+		maxResults = max
+	}
+	return maxResults
+}
+
+// normalizeIDs sắp xếp danh sách IDs để thứ tự không ảnh hưởng cache key
+func normalizeIDs(ids []string) string {
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// normalizeQuery gộp khoảng trắng thừa trong query tìm kiếm
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Users
+
+func (c *CachedTwitterService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("GetUserByUsername", normalizeUsername(username)), func() (*models.User, error) {
+		return c.next.GetUserByUsername(ctx, username)
+	})
+}
+
+func (c *CachedTwitterService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("GetUserByID", userID), func() (*models.User, error) {
+		return c.next.GetUserByID(ctx, userID)
+	})
+}
+
+func (c *CachedTwitterService) ListUsers(ctx context.Context, userIDs []string) (*models.UsersListResponse, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("ListUsers", normalizeIDs(userIDs)), func() (*models.UsersListResponse, error) {
+		return c.next.ListUsers(ctx, userIDs)
+	})
+}
+
+func (c *CachedTwitterService) GetMe(ctx context.Context) (*models.User, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("GetMe"), func() (*models.User, error) {
+		return c.next.GetMe(ctx)
+	})
+}
+
+func (c *CachedTwitterService) SearchUsers(ctx context.Context, query string, maxResults int, paginationToken string) (*models.SearchUsersResponse, error) {
+	return cached(ctx, c, cacheCategorySearch, cacheKey("SearchUsers", normalizeQuery(query), c.limit(maxResults, 100), paginationToken), func() (*models.SearchUsersResponse, error) {
+		return c.next.SearchUsers(ctx, query, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetUserFollowing(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowingResponse, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("GetUserFollowing", normalizeUsername(username), c.limit(maxResults, 1000), paginationToken), func() (*models.FollowingResponse, error) {
+		return c.next.GetUserFollowing(ctx, username, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetUserFollowers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowersResponse, error) {
+	return cached(ctx, c, cacheCategoryProfile, cacheKey("GetUserFollowers", normalizeUsername(username), c.limit(maxResults, 1000), paginationToken), func() (*models.FollowersResponse, error) {
+		return c.next.GetUserFollowers(ctx, username, maxResults, paginationToken)
+	})
+}
+
+// GetBlockingUsers không được cache vì là dữ liệu riêng của authenticated user
+func (c *CachedTwitterService) GetBlockingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.BlockingUsersResponse, error) {
+	return c.next.GetBlockingUsers(ctx, username, maxResults, paginationToken)
+}
+
+// GetMutingUsers không được cache vì là dữ liệu riêng của authenticated user
+func (c *CachedTwitterService) GetMutingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.MutingUsersResponse, error) {
+	return c.next.GetMutingUsers(ctx, username, maxResults, paginationToken)
+}
+
+// Timelines
+
+func (c *CachedTwitterService) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetUserTweets", normalizeUsername(username), c.limit(maxResults, c.maxTweets), paginationToken, filter.key()), func() (*models.TweetsResponse, error) {
+		return c.next.GetUserTweets(ctx, username, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CachedTwitterService) GetTweetsByUserID(ctx context.Context, userID string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetTweetsByUserID", userID, c.limit(maxResults, c.maxTweets), paginationToken), func() (*models.TweetsResponse, error) {
+		return c.next.GetTweetsByUserID(ctx, userID, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetUserTimelineReverseChronological", normalizeUsername(username), c.limit(maxResults, c.maxTweets), paginationToken), func() (*models.TweetsResponse, error) {
+		return c.next.GetUserTimelineReverseChronological(ctx, username, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetUserMentions(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.MentionsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetUserMentions", normalizeUsername(username), c.limit(maxResults, 100), paginationToken, filter.key()), func() (*models.MentionsResponse, error) {
+		return c.next.GetUserMentions(ctx, username, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CachedTwitterService) GetLikedTweets(ctx context.Context, username string, maxResults int, paginationToken string) (*models.LikedTweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetLikedTweets", normalizeUsername(username), c.limit(maxResults, 100), paginationToken), func() (*models.LikedTweetsResponse, error) {
+		return c.next.GetLikedTweets(ctx, username, maxResults, paginationToken)
+	})
+}
+
+// GetRepostsOfMe không được cache vì là dữ liệu riêng của authenticated user
+func (c *CachedTwitterService) GetRepostsOfMe(ctx context.Context, maxResults int, paginationToken string) (*models.RepostsResponse, error) {
+	return c.next.GetRepostsOfMe(ctx, maxResults, paginationToken)
+}
+
+// Tweets
+
+func (c *CachedTwitterService) GetTweetByID(ctx context.Context, tweetID string) (*models.TweetDetailResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetTweetByID", tweetID), func() (*models.TweetDetailResponse, error) {
+		return c.next.GetTweetByID(ctx, tweetID)
+	})
+}
+
+func (c *CachedTwitterService) ListTweets(ctx context.Context, tweetIDs []string) (*models.SearchTweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("ListTweets", normalizeIDs(tweetIDs)), func() (*models.SearchTweetsResponse, error) {
+		return c.next.ListTweets(ctx, tweetIDs)
+	})
+}
+
+func (c *CachedTwitterService) SearchTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	return cached(ctx, c, cacheCategorySearch, cacheKey("SearchTweets", normalizeQuery(query), c.limit(maxResults, 100), paginationToken, filter.key()), func() (*models.SearchTweetsResponse, error) {
+		return c.next.SearchTweets(ctx, query, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CachedTwitterService) GetTweetCounts(ctx context.Context, query string, opts TweetCountsOptions) (*models.TweetCountsResponse, error) {
+	return cached(ctx, c, cacheCategoryCounts, cacheKey("GetTweetCounts", normalizeQuery(query), opts.key()), func() (*models.TweetCountsResponse, error) {
+		return c.next.GetTweetCounts(ctx, query, opts)
+	})
+}
+
+func (c *CachedTwitterService) SearchAllTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	return cached(ctx, c, cacheCategorySearch, cacheKey("SearchAllTweets", normalizeQuery(query), c.limit(maxResults, 500), paginationToken, filter.key()), func() (*models.SearchTweetsResponse, error) {
+		return c.next.SearchAllTweets(ctx, query, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CachedTwitterService) GetAllTweetCounts(ctx context.Context, query string, opts TweetCountsOptions, paginationToken string) (*models.TweetCountsResponse, error) {
+	return cached(ctx, c, cacheCategoryCounts, cacheKey("GetAllTweetCounts", normalizeQuery(query), opts.key(), paginationToken), func() (*models.TweetCountsResponse, error) {
+		return c.next.GetAllTweetCounts(ctx, query, opts, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetLikingUsers(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.LikingUsersResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetLikingUsers", tweetID, c.limit(maxResults, 100), paginationToken), func() (*models.LikingUsersResponse, error) {
+		return c.next.GetLikingUsers(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetRetweetedBy(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.RetweetedByResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetRetweetedBy", tweetID, c.limit(maxResults, 100), paginationToken), func() (*models.RetweetedByResponse, error) {
+		return c.next.GetRetweetedBy(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+func (c *CachedTwitterService) GetQuoteTweets(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.QuoteTweetsResponse, error) {
+	return cached(ctx, c, cacheCategoryTimeline, cacheKey("GetQuoteTweets", tweetID, c.limit(maxResults, 100), paginationToken), func() (*models.QuoteTweetsResponse, error) {
+		return c.next.GetQuoteTweets(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+// HideTweet không được cache; chi tiết tweet đã cache bị xóa để lần đọc sau lấy trạng thái mới
+func (c *CachedTwitterService) HideTweet(ctx context.Context, tweetID string, hidden bool) (*models.HideTweetResponse, error) {
+	resp, err := c.next.HideTweet(ctx, tweetID, hidden)
+	if err == nil {
+		key := cacheKey("GetTweetByID", tweetID)
+		c.delete(ctx, key)
+		if scoped := userScopedKey(ctx, key); scoped != key {
+			c.delete(ctx, scoped)
+		}
+	}
+	return resp, err
+}
+
+// Trạng thái upstream luôn được đọc trực tiếp
+
+func (c *CachedTwitterService) GetRateLimits(ctx context.Context) (*models.RateLimitsResponse, error) {
+	return c.next.GetRateLimits(ctx)
+}
+
+func (c *CachedTwitterService) GetCircuitBreakers(ctx context.Context) (*models.CircuitBreakersResponse, error) {
+	return c.next.GetCircuitBreakers(ctx)
+}
+
+func (c *CachedTwitterService) GetTokenPool(ctx context.Context) (*models.TokenPoolResponse, error) {
+	return c.next.GetTokenPool(ctx)
+}
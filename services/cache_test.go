@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/models"
+)
+
+// countingTwitterAPI đếm số lần GetUserTweets được gọi tới implementation bên dưới
+type countingTwitterAPI struct {
+	TwitterAPI
+	userTweetsCalls int
+}
+
+func (c *countingTwitterAPI) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error) {
+	c.userTweetsCalls++
+	return c.TwitterAPI.GetUserTweets(ctx, username, maxResults, paginationToken, filter)
+}
+
+func newTestCachedService(next TwitterAPI) *CachedTwitterService {
+	return newTestCachedServiceWithStore(next, NewMemoryCacheStore(100))
+}
+
+func newTestCachedServiceWithStore(next TwitterAPI, store CacheStore) *CachedTwitterService {
+	return NewCachedTwitterService(next, store, &config.Config{
+		CacheMaxEntries:     100,
+		CacheTTLProfile:     time.Minute,
+		CacheTTLTimeline:    time.Minute,
+		CacheTTLSearch:      time.Minute,
+		CacheTTLCounts:      time.Minute,
+		DefaultTweetsCount:  10,
+		MaxTweetsPerRequest: 100,
+	})
+}
+
+func TestCachedServiceNormalizesMaxResults(t *testing.T) {
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+	svc := newTestCachedService(counting)
+	ctx := context.Background()
+
+	// count 0, -1 và 10 (default) cùng là một upstream call; "@Alice" và "alice" cũng vậy
+	for _, req := range []struct {
+		username string
+		count    int
+	}{{"alice", 0}, {"alice", -1}, {"Alice", 10}, {"@alice", 10}} {
+		if _, err := svc.GetUserTweets(ctx, req.username, req.count, "", TweetFilter{}); err != nil {
+			t.Fatalf("GetUserTweets(%q, %d): %v", req.username, req.count, err)
+		}
+	}
+	if counting.userTweetsCalls != 1 {
+		t.Errorf("upstream calls = %d, want 1", counting.userTweetsCalls)
+	}
+
+	// count vượt quá MaxTweetsPerRequest dùng chung key với max
+	for _, count := range []int{100, 500} {
+		if _, err := svc.GetUserTweets(ctx, "alice", count, "", TweetFilter{}); err != nil {
+			t.Fatalf("GetUserTweets(alice, %d): %v", count, err)
+		}
+	}
+	if counting.userTweetsCalls != 2 {
+		t.Errorf("upstream calls = %d, want 2", counting.userTweetsCalls)
+	}
+}
+
+func TestCachedServiceBypass(t *testing.T) {
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+	svc := newTestCachedService(counting)
+
+	rec := &CacheRecorder{}
+	ctx := WithCacheRecorder(context.Background(), rec)
+	if _, err := svc.GetUserTweets(ctx, "alice", 10, "", TweetFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Status() != CacheMiss {
+		t.Errorf("status = %q, want %s", rec.Status(), CacheMiss)
+	}
+
+	rec = &CacheRecorder{}
+	ctx = WithCacheRecorder(context.Background(), rec)
+	if _, err := svc.GetUserTweets(ctx, "alice", 10, "", TweetFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Status() != CacheHit {
+		t.Errorf("status = %q, want %s", rec.Status(), CacheHit)
+	}
+
+	if _, err := svc.GetUserTweets(WithCacheBypass(context.Background()), "alice", 10, "", TweetFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if counting.userTweetsCalls != 2 {
+		t.Errorf("upstream calls = %d, want 2", counting.userTweetsCalls)
+	}
+}
+
+func TestCachedServiceKeysByFilter(t *testing.T) {
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+	svc := newTestCachedService(counting)
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, filter := range []TweetFilter{
+		{},
+		{SinceID: "100"},
+		{StartTime: start},
+		// Cùng thời điểm ở múi giờ khác dùng chung key
+		{StartTime: start.In(time.FixedZone("ICT", 7*60*60))},
+	} {
+		if _, err := svc.GetUserTweets(ctx, "alice", 10, "", filter); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counting.userTweetsCalls != 3 {
+		t.Errorf("upstream calls = %d, want 3", counting.userTweetsCalls)
+	}
+}
+
+func TestCachedServiceScopesByUserToken(t *testing.T) {
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+	svc := newTestCachedService(counting)
+
+	alice := WithUserToken(context.Background(), UserToken{AccessToken: "token-a", UserID: "1"})
+	bob := WithUserToken(context.Background(), UserToken{AccessToken: "token-b", UserID: "2"})
+	// Token mới của cùng user (sau khi refresh) vẫn dùng chung cache của user đó
+	aliceRefreshed := WithUserToken(context.Background(), UserToken{AccessToken: "token-a2", UserID: "1"})
+
+	for _, ctx := range []context.Context{context.Background(), alice, bob, aliceRefreshed, context.Background()} {
+		if _, err := svc.GetUserTweets(ctx, "alice", 10, "", TweetFilter{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counting.userTweetsCalls != 3 {
+		t.Errorf("upstream calls = %d, want 3 (app, alice, bob)", counting.userTweetsCalls)
+	}
+}
+
+func TestTweetFilterMatch(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tweet := &models.Tweet{ID: "1000", CreatedAt: created}
+
+	for _, tc := range []struct {
+		filter TweetFilter
+		want   bool
+	}{
+		{TweetFilter{}, true},
+		{TweetFilter{StartTime: created}, true},
+		{TweetFilter{EndTime: created}, false},
+		{TweetFilter{StartTime: created.Add(-time.Hour), EndTime: created.Add(time.Second)}, true},
+		{TweetFilter{SinceID: "999"}, true},
+		{TweetFilter{SinceID: "1000"}, false},
+		{TweetFilter{UntilID: "1001"}, true},
+		{TweetFilter{UntilID: "999"}, false},
+	} {
+		if got := tc.filter.Match(tweet); got != tc.want {
+			t.Errorf("%+v.Match = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
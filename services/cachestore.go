@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Backend của response cache (CACHE_BACKEND)
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+var cacheStoreErrors = metrics.NewCounter("x_cache_store_errors_total",
+	"Số lần thao tác với cache store thất bại theo backend và thao tác (get, set, delete)",
+	"backend", "op")
+
+// CacheStore lưu responses đã serialize với TTL. Lỗi của store không được làm hỏng
+// request: CachedTwitterService coi lỗi Get là miss và bỏ qua lỗi Set/Delete.
+type CacheStore interface {
+	// Get trả về value còn hạn của key; ok = false nếu không có hoặc đã hết hạn
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set lưu value với thời hạn ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete xóa key khỏi store
+	Delete(ctx context.Context, key string) error
+	// Backend trả về tên backend dùng cho logs và metrics
+	Backend() string
+}
+
+// NewCacheStore tạo cache store theo CACHE_BACKEND
+func NewCacheStore(cfg *config.Config) (CacheStore, error) {
+	switch cfg.CacheBackend {
+	case "", CacheBackendMemory:
+		return NewMemoryCacheStore(cfg.CacheMaxEntries), nil
+	case CacheBackendRedis:
+		store := NewRedisCacheStore(RedisOptions{
+			Addr:      cfg.CacheRedisAddr,
+			Password:  cfg.CacheRedisPassword,
+			DB:        cfg.CacheRedisDB,
+			KeyPrefix: cfg.CacheRedisKeyPrefix,
+			Timeout:   cfg.CacheRedisTimeout,
+			PoolSize:  cfg.CacheRedisPoolSize,
+		})
+
+		// Redis không sẵn sàng lúc khởi động không chặn server: mọi lookup sẽ là miss
+		if err := store.Ping(context.Background()); err != nil {
+			log.WithError(err).WithField("addr", cfg.CacheRedisAddr).Warn("⚠️  Không thể kết nối Redis, cache sẽ miss cho tới khi Redis sẵn sàng")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND không hợp lệ: %s", cfg.CacheBackend)
+	}
+}
+
+// memoryCacheStore là CacheStore dùng LRU in-memory của từng process
+type memoryCacheStore struct {
+	lru *lruCache
+}
+
+// NewMemoryCacheStore tạo CacheStore in-memory giữ tối đa maxEntries responses
+func NewMemoryCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{lru: newLRUCache(maxEntries)}
+}
+
+func (s *memoryCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := s.lru.Get(key)
+	return value, ok, nil
+}
+
+func (s *memoryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.lru.Set(key, value, ttl)
+	return nil
+}
+
+func (s *memoryCacheStore) Delete(ctx context.Context, key string) error {
+	s.lru.Delete(key)
+	return nil
+}
+
+func (s *memoryCacheStore) Backend() string {
+	return CacheBackendMemory
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Các chế độ của HTTP transport (config TWITTER_HTTP_MODE)
+const (
+	HTTPModeLive   = "live"
+	HTTPModeRecord = "record"
+	HTTPModeReplay = "replay"
+)
+
+// redactedToken thay thế Bearer token và OAuth credentials trong cassette
+const redactedToken = "[REDACTED]"
+
+// cassette là một cặp request/response được ghi lại từ X API
+type cassette struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+}
+
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// cassetteTransport ghi lại (record) hoặc phát lại (replay) các responses của X API
+// dưới dạng file JSON trong dir, mỗi request một file
+type cassetteTransport struct {
+	mode    string
+	dir     string
+	secrets []string
+	next    http.RoundTripper
+	mu      sync.Mutex
+}
+
+// newCassetteTransport tạo cassetteTransport; secrets là các giá trị phải được xóa
+// khỏi cassette (giá trị rỗng bị bỏ qua)
+func newCassetteTransport(mode, dir string, secrets []string, next http.RoundTripper) (*cassetteTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("TWITTER_CASSETTE_DIR là bắt buộc khi TWITTER_HTTP_MODE=%s", mode)
+	}
+	if mode == HTTPModeRecord {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("không thể tạo thư mục cassette %s: %w", dir, err)
+		}
+	}
+	nonEmpty := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+	return &cassetteTransport{mode: mode, dir: dir, secrets: nonEmpty, next: next}, nil
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == HTTPModeReplay {
+		return t.replay(req)
+	}
+	return t.record(req)
+}
+
+// replay trả về response đã ghi; request không khớp cassette nào là lỗi
+func (t *cassetteTransport) replay(req *http.Request) (*http.Response, error) {
+	path := t.path(req)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"method":   req.Method,
+			"url":      t.scrub(req.URL.String()),
+			"cassette": path,
+		}).Error("❌ Cassette replay: không có bản ghi cho request")
+		return nil, fmt.Errorf("cassette replay: không có bản ghi cho %s %s (file %s)", req.Method, t.scrub(req.URL.RequestURI()), path)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette %s không hợp lệ: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.Response.StatusCode, http.StatusText(c.Response.StatusCode)),
+		StatusCode:    c.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Response.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(c.Response.Body)),
+		ContentLength: int64(len(c.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record gửi request thật rồi ghi cặp request/response (đã xóa token) vào cassette
+func (t *cassetteTransport) record(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	c := cassette{
+		Request: cassetteRequest{
+			Method:  req.Method,
+			URL:     t.scrub(req.URL.String()),
+			Headers: t.scrubHeaders(req.Header),
+		},
+		Response: cassetteResponse{
+			StatusCode: res.StatusCode,
+			Headers:    t.scrubHeaders(res.Header),
+			Body:       t.scrub(string(body)),
+		},
+	}
+
+	if err := t.write(t.path(req), &c); err != nil {
+		log.WithError(err).Warn("Cassette record: không thể ghi cassette")
+	}
+
+	return res, nil
+}
+
+func (t *cassetteTransport) write(path string, c *cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return os.WriteFile(path, data, 0o644)
+}
+
+// path trả về file cassette của request, dựa trên method, path và query đã sắp xếp.
+// Host không nằm trong key để cassettes dùng được với mọi base URL.
+func (t *cassetteTransport) path(req *http.Request) string {
+	query := req.URL.Query().Encode()
+	key := req.Method + " " + req.URL.Path + "?" + query
+	// Cùng một endpoint có thể trả về khác nhau theo loại auth (ví dụ users/me), nên
+	// request OAuth 1.0a user context được ghi vào cassette riêng
+	if strings.HasPrefix(req.Header.Get("Authorization"), "OAuth ") {
+		key += " oauth"
+	}
+	sum := sha256.Sum256([]byte(key))
+
+	name := strings.Trim(strings.NewReplacer("/", "_", ":", "_").Replace(req.URL.Path), "_")
+	return filepath.Join(t.dir, fmt.Sprintf("%s_%s_%s.json", strings.ToLower(req.Method), name, hex.EncodeToString(sum[:6])))
+}
+
+// scrub xóa Bearer token và OAuth credentials khỏi chuỗi
+func (t *cassetteTransport) scrub(s string) string {
+	for _, secret := range t.secrets {
+		s = strings.ReplaceAll(s, secret, redactedToken)
+	}
+	return s
+}
+
+func (t *cassetteTransport) scrubHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for key, values := range h {
+		for _, v := range values {
+			if strings.EqualFold(key, "Authorization") {
+				// Giữ lại scheme (Bearer hoặc OAuth) để biết request dùng loại auth nào
+				scheme, _, _ := strings.Cut(v, " ")
+				v = scheme + " " + redactedToken
+			}
+			out.Add(key, t.scrub(v))
+		}
+	}
+	return out
+}
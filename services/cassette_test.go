@@ -0,0 +1,48 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteReplayUnmatchedRequestFails(t *testing.T) {
+	if *recordCassettes {
+		t.Skip("chỉ chạy ở chế độ replay")
+	}
+	svc := newCassetteTwitterService(t)
+
+	_, err := svc.GetUserByUsername(context.Background(), "no_such_cassette")
+	if err == nil {
+		t.Fatal("request không có cassette phải trả về lỗi")
+	}
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Err == nil || !strings.Contains(apiErr.Err.Error(), "cassette replay") {
+		t.Errorf("err = %v, want lỗi cassette replay", err)
+	}
+}
+
+func TestCassettesAreScrubbed(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(testCassetteDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("không có cassette nào trong %s", testCassetteDir)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range []string{testBearerToken, testAccessToken, testTokenSecret} {
+			if bytes.Contains(data, []byte(secret)) {
+				t.Errorf("%s chứa credential chưa bị xóa", file)
+			}
+		}
+	}
+}
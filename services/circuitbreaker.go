@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Các trạng thái của circuit breaker
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CodeCircuitOpen là mã lỗi khi circuit breaker đang mở
+const CodeCircuitOpen = "UPSTREAM_CIRCUIT_OPEN"
+
+// circuitBreaker là trạng thái breaker của một endpoint family
+type circuitBreaker struct {
+	state               string
+	consecutiveFailures int
+	trips               int
+	openedAt            time.Time
+	probing             bool // đang có một probe ở trạng thái half-open
+}
+
+// CircuitBreakers quản lý circuit breaker theo endpoint family. Sau threshold lỗi
+// liên tiếp (5xx, lỗi network) circuit mở và requests bị từ chối ngay; hết cooldown
+// thì cho một probe đi qua (half-open) để quyết định đóng lại hay mở tiếp.
+type CircuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewCircuitBreakers tạo registry breakers; threshold <= 0 tắt circuit breaker
+func NewCircuitBreakers(threshold int, cooldown time.Duration) *CircuitBreakers {
+	return &CircuitBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		breakers:  make(map[string]*circuitBreaker),
+	}
+}
+
+// Enabled cho biết circuit breaker có được bật không
+func (c *CircuitBreakers) Enabled() bool {
+	return c.threshold > 0
+}
+
+// allow kiểm tra request tới family có được đi qua không; trả về thời điểm
+// có thể thử lại nếu bị từ chối
+func (c *CircuitBreakers) allow(family string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.breaker(family)
+	switch b.state {
+	case CircuitOpen:
+		retryAt := b.openedAt.Add(c.cooldown)
+		if c.now().Before(retryAt) {
+			return retryAt, false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		log.WithField("endpoint", family).Info("🔌 Circuit breaker half-open, gửi probe tới X API")
+		return time.Time{}, true
+	case CircuitHalfOpen:
+		if b.probing {
+			return c.now().Add(time.Second), false
+		}
+		b.probing = true
+		return time.Time{}, true
+	default:
+		return time.Time{}, true
+	}
+}
+
+// record ghi nhận kết quả của một request đã được allow
+func (c *CircuitBreakers) record(family string, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.breaker(family)
+	b.probing = false
+
+	if !failed {
+		if b.state != CircuitClosed {
+			log.WithField("endpoint", family).Info("✅ Circuit breaker đóng lại, X API đã hoạt động bình thường")
+		}
+		b.state = CircuitClosed
+		b.consecutiveFailures = 0
+		return
+	}
+
+	b.consecutiveFailures++
+	if b.state == CircuitHalfOpen || b.consecutiveFailures >= c.threshold {
+		if b.state != CircuitOpen {
+			b.trips++
+		}
+		b.state = CircuitOpen
+		b.openedAt = c.now()
+		log.WithFields(log.Fields{
+			"endpoint":             family,
+			"consecutive_failures": b.consecutiveFailures,
+			"cooldown":             c.cooldown.String(),
+		}).Warn("🔌 Circuit breaker mở, tạm ngừng gọi X API")
+	}
+}
+
+// release bỏ qua kết quả của request (ví dụ client hủy request), giải phóng probe
+func (c *CircuitBreakers) release(family string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breaker(family).probing = false
+}
+
+// breaker trả về breaker của family; caller phải giữ lock
+func (c *CircuitBreakers) breaker(family string) *circuitBreaker {
+	b, ok := c.breakers[family]
+	if !ok {
+		b = &circuitBreaker{state: CircuitClosed}
+		c.breakers[family] = b
+	}
+	return b
+}
+
+// Snapshot trả về trạng thái của tất cả breakers đã biết, sắp xếp theo endpoint
+func (c *CircuitBreakers) Snapshot() []models.CircuitBreakerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]models.CircuitBreakerStatus, 0, len(c.breakers))
+	for family, b := range c.breakers {
+		status := models.CircuitBreakerStatus{
+			Endpoint:            family,
+			State:               b.state,
+			ConsecutiveFailures: b.consecutiveFailures,
+			Trips:               b.trips,
+		}
+		if b.state == CircuitOpen {
+			openedAt := b.openedAt.UTC()
+			retryAt := openedAt.Add(c.cooldown)
+			status.OpenedAt = &openedAt
+			status.RetryAt = &retryAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
+
+// registerMetrics xuất trạng thái breakers ra /metrics
+func (c *CircuitBreakers) registerMetrics() {
+	stateValue := map[string]float64{CircuitClosed: 0, CircuitHalfOpen: 1, CircuitOpen: 2}
+
+	metrics.RegisterGaugeFunc("x_api_circuit_breaker_state",
+		"Trạng thái circuit breaker theo endpoint family của X API (0=closed, 1=half_open, 2=open)",
+		func() []metrics.Sample {
+			statuses := c.Snapshot()
+			samples := make([]metrics.Sample, 0, len(statuses))
+			for _, s := range statuses {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"endpoint": s.Endpoint},
+					Value:  stateValue[s.State],
+				})
+			}
+			return samples
+		})
+
+	metrics.RegisterCounterFunc("x_api_circuit_breaker_trips_total",
+		"Số lần circuit breaker chuyển sang open theo endpoint family của X API",
+		func() []metrics.Sample {
+			statuses := c.Snapshot()
+			samples := make([]metrics.Sample, 0, len(statuses))
+			for _, s := range statuses {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"endpoint": s.Endpoint},
+					Value:  float64(s.Trips),
+				})
+			}
+			return samples
+		})
+}
+
+// circuitBreakerTransport từ chối ngay requests tới endpoint family có circuit đang mở
+type circuitBreakerTransport struct {
+	breakers *CircuitBreakers
+	next     http.RoundTripper
+}
+
+func (t *circuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	family := endpointFamily(req.Method, req.URL.Path)
+
+	if retryAt, ok := t.breakers.allow(family); !ok {
+		return nil, &APIError{
+			Kind:       ErrorKindUnavailable,
+			Code:       CodeCircuitOpen,
+			Message:    fmt.Sprintf("X API đang gặp sự cố, tạm ngừng gọi %s", family),
+			RetryAfter: retryAt.Sub(t.breakers.now()),
+		}
+	}
+
+	res, err := t.next.RoundTrip(req)
+
+	switch {
+	case err != nil && errors.Is(err, context.Canceled):
+		t.breakers.release(family)
+	case err != nil:
+		_, typed := AsAPIError(err)
+		if typed {
+			t.breakers.release(family)
+		} else {
+			t.breakers.record(family, true)
+		}
+	default:
+		t.breakers.record(family, res.StatusCode >= http.StatusInternalServerError)
+	}
+
+	return res, err
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestBreakers(threshold int, cooldown time.Duration, now *time.Time) *CircuitBreakers {
+	breakers := NewCircuitBreakers(threshold, cooldown)
+	breakers.now = func() time.Time { return *now }
+	return breakers
+}
+
+func breakerState(breakers *CircuitBreakers) string {
+	statuses := breakers.Snapshot()
+	if len(statuses) == 0 {
+		return ""
+	}
+	return statuses[0].State
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	// step là một request qua circuitBreakerTransport; upstream là kết quả của X API,
+	// rỗng nếu request phải bị từ chối trước khi tới X API
+	type step struct {
+		advance    time.Duration
+		upstream   string
+		state      string
+		retryAfter time.Duration
+	}
+	trip := []step{
+		{upstream: "5xx", state: CircuitClosed},
+		{upstream: "network", state: CircuitClosed},
+		{upstream: "5xx", state: CircuitOpen},
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"mở khi đủ threshold lỗi liên tiếp", trip},
+		{"thành công reset số lỗi liên tiếp", []step{
+			{upstream: "5xx", state: CircuitClosed},
+			{upstream: "5xx", state: CircuitClosed},
+			{upstream: "ok", state: CircuitClosed},
+			{upstream: "5xx", state: CircuitClosed},
+			{upstream: "5xx", state: CircuitClosed},
+		}},
+		{"4xx và lỗi đã phân loại không tính là lỗi", []step{
+			{upstream: "4xx", state: CircuitClosed},
+			{upstream: "typed", state: CircuitClosed},
+			{upstream: "4xx", state: CircuitClosed},
+			{upstream: "4xx", state: CircuitClosed},
+		}},
+		{"open từ chối ngay với Retry-After", append(trip,
+			step{state: CircuitOpen, retryAfter: 30 * time.Second},
+			step{advance: 20 * time.Second, state: CircuitOpen, retryAfter: 10 * time.Second},
+		)},
+		{"probe thành công đóng breaker", append(trip,
+			step{advance: 30 * time.Second, upstream: "ok", state: CircuitClosed},
+			step{upstream: "ok", state: CircuitClosed},
+		)},
+		{"probe lỗi mở lại breaker", append(trip,
+			step{advance: 30 * time.Second, upstream: "5xx", state: CircuitOpen},
+			step{advance: 29 * time.Second, state: CircuitOpen, retryAfter: time.Second},
+			step{advance: time.Second, upstream: "ok", state: CircuitClosed},
+		)},
+		{"probe bị hủy giải phóng slot", append(trip,
+			step{advance: 30 * time.Second, upstream: "canceled", state: CircuitHalfOpen},
+			step{upstream: "ok", state: CircuitClosed},
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			breakers := newTestBreakers(3, 30*time.Second, &now)
+			var called bool
+			var upstream string
+			transport := &circuitBreakerTransport{
+				breakers: breakers,
+				next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					called = true
+					switch upstream {
+					case "ok":
+						return stubResponse(http.StatusOK, nil), nil
+					case "4xx":
+						return stubResponse(http.StatusBadRequest, nil), nil
+					case "5xx":
+						return stubResponse(http.StatusServiceUnavailable, nil), nil
+					case "network":
+						return nil, errors.New("connection reset by peer")
+					case "typed":
+						return nil, &APIError{Kind: ErrorKindRateLimited, Code: CodeRateLimited}
+					case "canceled":
+						return nil, req.Context().Err()
+					}
+					t.Fatalf("upstream không hợp lệ: %q", upstream)
+					return nil, nil
+				}),
+			}
+
+			for i, st := range tt.steps {
+				now = now.Add(st.advance)
+				called, upstream = false, st.upstream
+
+				ctx, cancel := context.WithCancel(context.Background())
+				if st.upstream == "canceled" {
+					cancel()
+				}
+				req := httptest.NewRequest("GET", "https://api.twitter.com/2/users/by/username/alice", nil).WithContext(ctx)
+				_, err := transport.RoundTrip(req)
+				cancel()
+
+				blocked := st.upstream == ""
+				if called == blocked {
+					t.Fatalf("step %d: tới X API = %v, want %v", i, called, !blocked)
+				}
+				if blocked {
+					apiErr, ok := AsAPIError(err)
+					if !ok || apiErr.Code != CodeCircuitOpen || apiErr.Kind != ErrorKindUnavailable {
+						t.Fatalf("step %d: err = %v, want %s", i, err, CodeCircuitOpen)
+					}
+					if apiErr.RetryAfter != st.retryAfter {
+						t.Errorf("step %d: RetryAfter = %s, want %s", i, apiErr.RetryAfter, st.retryAfter)
+					}
+				}
+				if got := breakerState(breakers); got != st.state {
+					t.Fatalf("step %d: state = %s, want %s", i, got, st.state)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breakers := newTestBreakers(1, time.Minute, &now)
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	transport := &circuitBreakerTransport{
+		breakers: breakers,
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return stubResponse(http.StatusBadGateway, nil), nil
+			}
+			close(probing)
+			<-release
+			return stubResponse(http.StatusOK, nil), nil
+		}),
+	}
+	newReq := func() *http.Request {
+		return httptest.NewRequest("GET", "https://api.twitter.com/2/tweets/1", nil)
+	}
+
+	transport.RoundTrip(newReq())
+	if got := breakerState(breakers); got != CircuitOpen {
+		t.Fatalf("state = %s, want open", got)
+	}
+
+	now = now.Add(time.Minute)
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(newReq())
+		done <- err
+	}()
+	<-probing
+
+	// Trong lúc probe chưa xong, các request khác bị từ chối mà không tới X API
+	for i := 0; i < 3; i++ {
+		_, err := transport.RoundTrip(newReq())
+		if apiErr, ok := AsAPIError(err); !ok || apiErr.Code != CodeCircuitOpen {
+			t.Errorf("request #%d khi đang probe: err = %v, want %s", i+1, err, CodeCircuitOpen)
+		}
+	}
+	if got := breakerState(breakers); got != CircuitHalfOpen {
+		t.Errorf("state khi đang probe = %s, want half_open", got)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if calls != 2 || breakerState(breakers) != CircuitClosed {
+		t.Errorf("calls = %d, state = %s; want 2, closed", calls, breakerState(breakers))
+	}
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var coalescedRequests = metrics.NewCounter("x_coalesced_requests_total",
+	"Số lần gọi TwitterAPI theo method và vai trò: leader (gọi X API) hoặc shared (dùng chung kết quả đang chờ)",
+	"method", "role")
+
+// flight là một upstream call đang chạy, dùng chung cho mọi caller có cùng key
+type flight struct {
+	done   chan struct{}
+	value  interface{}
+	err    error
+	cancel context.CancelFunc
+
+	waiters int // số caller còn đang chờ; bảo vệ bởi flightGroup.mu
+}
+
+// flightGroup gộp các call đồng thời có cùng key thành một call duy nhất
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do chạy fn một lần cho mỗi key đang in-flight. fn nhận context tách khỏi cancel
+// của từng caller (vẫn giữ values như request ID) và chỉ bị hủy khi mọi caller đều
+// đã bỏ cuộc. Caller có ctx bị hủy nhận ctx.Err() ngay mà không ảnh hưởng caller khác.
+func (g *flightGroup) do(ctx context.Context, method, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f, shared := g.flights[key]
+	if !shared {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+
+		go func() {
+			defer cancel()
+			f.value, f.err = fn(flightCtx)
+
+			g.mu.Lock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	if shared {
+		coalescedRequests.Inc(method, "shared")
+		log.WithFields(log.Fields{"method": method, "key": key}).Debug("Dùng chung upstream call đang chạy")
+	} else {
+		coalescedRequests.Inc(method, "leader")
+	}
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Không còn ai chờ: hủy upstream call và để caller sau bắt đầu flight mới
+			log.WithFields(log.Fields{"method": method, "key": key}).Debug("Mọi caller đã hủy, hủy upstream call")
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// coalesced là phiên bản có kiểu của flightGroup.do
+func coalesced[T any](ctx context.Context, c *CoalescedTwitterService, method, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	// Request bỏ qua cache không dùng chung flight với request thường vì
+	// TwitterService đọc cờ bypass từ context của leader
+	if cacheBypassed(ctx) {
+		key += "|bypass"
+	}
+	// Mỗi end user chỉ dùng chung flight với request của chính mình
+	key = userScopedKey(ctx, key)
+	value, err := c.group.do(ctx, method, key, func(ctx context.Context) (interface{}, error) {
+		return fetch(ctx)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// CoalescedTwitterService là decorator của TwitterAPI, gộp các call đọc dữ liệu giống
+// hệt nhau (cùng method và tham số đã chuẩn hóa) đang chạy đồng thời thành một upstream
+// request. Kết quả được chia sẻ giữa các caller nên không được sửa đổi.
+type CoalescedTwitterService struct {
+	next  TwitterAPI
+	group flightGroup
+}
+
+// NewCoalescedTwitterService bọc next bằng request coalescing
+func NewCoalescedTwitterService(next TwitterAPI) *CoalescedTwitterService {
+	log.Info("🔀 Request coalescing đã được bật")
+	return &CoalescedTwitterService{next: next}
+}
+
+// Users
+
+func (c *CoalescedTwitterService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return coalesced(ctx, c, "GetUserByUsername", cacheKey("GetUserByUsername", normalizeUsername(username)), func(ctx context.Context) (*models.User, error) {
+		return c.next.GetUserByUsername(ctx, username)
+	})
+}
+
+func (c *CoalescedTwitterService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	return coalesced(ctx, c, "GetUserByID", cacheKey("GetUserByID", userID), func(ctx context.Context) (*models.User, error) {
+		return c.next.GetUserByID(ctx, userID)
+	})
+}
+
+func (c *CoalescedTwitterService) ListUsers(ctx context.Context, userIDs []string) (*models.UsersListResponse, error) {
+	return coalesced(ctx, c, "ListUsers", cacheKey("ListUsers", normalizeIDs(userIDs)), func(ctx context.Context) (*models.UsersListResponse, error) {
+		return c.next.ListUsers(ctx, userIDs)
+	})
+}
+
+func (c *CoalescedTwitterService) GetMe(ctx context.Context) (*models.User, error) {
+	return coalesced(ctx, c, "GetMe", cacheKey("GetMe"), func(ctx context.Context) (*models.User, error) {
+		return c.next.GetMe(ctx)
+	})
+}
+
+func (c *CoalescedTwitterService) SearchUsers(ctx context.Context, query string, maxResults int, paginationToken string) (*models.SearchUsersResponse, error) {
+	return coalesced(ctx, c, "SearchUsers", cacheKey("SearchUsers", normalizeQuery(query), maxResults, paginationToken), func(ctx context.Context) (*models.SearchUsersResponse, error) {
+		return c.next.SearchUsers(ctx, query, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetUserFollowing(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowingResponse, error) {
+	return coalesced(ctx, c, "GetUserFollowing", cacheKey("GetUserFollowing", normalizeUsername(username), maxResults, paginationToken), func(ctx context.Context) (*models.FollowingResponse, error) {
+		return c.next.GetUserFollowing(ctx, username, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetUserFollowers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowersResponse, error) {
+	return coalesced(ctx, c, "GetUserFollowers", cacheKey("GetUserFollowers", normalizeUsername(username), maxResults, paginationToken), func(ctx context.Context) (*models.FollowersResponse, error) {
+		return c.next.GetUserFollowers(ctx, username, maxResults, paginationToken)
+	})
+}
+
+// GetBlockingUsers không được gộp vì là dữ liệu riêng của authenticated user
+func (c *CoalescedTwitterService) GetBlockingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.BlockingUsersResponse, error) {
+	return c.next.GetBlockingUsers(ctx, username, maxResults, paginationToken)
+}
+
+// GetMutingUsers không được gộp vì là dữ liệu riêng của authenticated user
+func (c *CoalescedTwitterService) GetMutingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.MutingUsersResponse, error) {
+	return c.next.GetMutingUsers(ctx, username, maxResults, paginationToken)
+}
+
+// Timelines
+
+func (c *CoalescedTwitterService) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error) {
+	return coalesced(ctx, c, "GetUserTweets", cacheKey("GetUserTweets", normalizeUsername(username), maxResults, paginationToken, filter.key()), func(ctx context.Context) (*models.TweetsResponse, error) {
+		return c.next.GetUserTweets(ctx, username, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CoalescedTwitterService) GetTweetsByUserID(ctx context.Context, userID string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	return coalesced(ctx, c, "GetTweetsByUserID", cacheKey("GetTweetsByUserID", userID, maxResults, paginationToken), func(ctx context.Context) (*models.TweetsResponse, error) {
+		return c.next.GetTweetsByUserID(ctx, userID, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	return coalesced(ctx, c, "GetUserTimelineReverseChronological", cacheKey("GetUserTimelineReverseChronological", normalizeUsername(username), maxResults, paginationToken), func(ctx context.Context) (*models.TweetsResponse, error) {
+		return c.next.GetUserTimelineReverseChronological(ctx, username, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetUserMentions(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.MentionsResponse, error) {
+	return coalesced(ctx, c, "GetUserMentions", cacheKey("GetUserMentions", normalizeUsername(username), maxResults, paginationToken, filter.key()), func(ctx context.Context) (*models.MentionsResponse, error) {
+		return c.next.GetUserMentions(ctx, username, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CoalescedTwitterService) GetLikedTweets(ctx context.Context, username string, maxResults int, paginationToken string) (*models.LikedTweetsResponse, error) {
+	return coalesced(ctx, c, "GetLikedTweets", cacheKey("GetLikedTweets", normalizeUsername(username), maxResults, paginationToken), func(ctx context.Context) (*models.LikedTweetsResponse, error) {
+		return c.next.GetLikedTweets(ctx, username, maxResults, paginationToken)
+	})
+}
+
+// GetRepostsOfMe không được gộp vì là dữ liệu riêng của authenticated user
+func (c *CoalescedTwitterService) GetRepostsOfMe(ctx context.Context, maxResults int, paginationToken string) (*models.RepostsResponse, error) {
+	return c.next.GetRepostsOfMe(ctx, maxResults, paginationToken)
+}
+
+// Tweets
+
+func (c *CoalescedTwitterService) GetTweetByID(ctx context.Context, tweetID string) (*models.TweetDetailResponse, error) {
+	return coalesced(ctx, c, "GetTweetByID", cacheKey("GetTweetByID", tweetID), func(ctx context.Context) (*models.TweetDetailResponse, error) {
+		return c.next.GetTweetByID(ctx, tweetID)
+	})
+}
+
+func (c *CoalescedTwitterService) ListTweets(ctx context.Context, tweetIDs []string) (*models.SearchTweetsResponse, error) {
+	return coalesced(ctx, c, "ListTweets", cacheKey("ListTweets", normalizeIDs(tweetIDs)), func(ctx context.Context) (*models.SearchTweetsResponse, error) {
+		return c.next.ListTweets(ctx, tweetIDs)
+	})
+}
+
+func (c *CoalescedTwitterService) SearchTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	return coalesced(ctx, c, "SearchTweets", cacheKey("SearchTweets", normalizeQuery(query), maxResults, paginationToken, filter.key()), func(ctx context.Context) (*models.SearchTweetsResponse, error) {
+		return c.next.SearchTweets(ctx, query, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CoalescedTwitterService) GetTweetCounts(ctx context.Context, query string, opts TweetCountsOptions) (*models.TweetCountsResponse, error) {
+	return coalesced(ctx, c, "GetTweetCounts", cacheKey("GetTweetCounts", normalizeQuery(query), opts.key()), func(ctx context.Context) (*models.TweetCountsResponse, error) {
+		return c.next.GetTweetCounts(ctx, query, opts)
+	})
+}
+
+func (c *CoalescedTwitterService) SearchAllTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	return coalesced(ctx, c, "SearchAllTweets", cacheKey("SearchAllTweets", normalizeQuery(query), maxResults, paginationToken, filter.key()), func(ctx context.Context) (*models.SearchTweetsResponse, error) {
+		return c.next.SearchAllTweets(ctx, query, maxResults, paginationToken, filter)
+	})
+}
+
+func (c *CoalescedTwitterService) GetAllTweetCounts(ctx context.Context, query string, opts TweetCountsOptions, paginationToken string) (*models.TweetCountsResponse, error) {
+	return coalesced(ctx, c, "GetAllTweetCounts", cacheKey("GetAllTweetCounts", normalizeQuery(query), opts.key(), paginationToken), func(ctx context.Context) (*models.TweetCountsResponse, error) {
+		return c.next.GetAllTweetCounts(ctx, query, opts, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetLikingUsers(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.LikingUsersResponse, error) {
+	return coalesced(ctx, c, "GetLikingUsers", cacheKey("GetLikingUsers", tweetID, maxResults, paginationToken), func(ctx context.Context) (*models.LikingUsersResponse, error) {
+		return c.next.GetLikingUsers(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetRetweetedBy(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.RetweetedByResponse, error) {
+	return coalesced(ctx, c, "GetRetweetedBy", cacheKey("GetRetweetedBy", tweetID, maxResults, paginationToken), func(ctx context.Context) (*models.RetweetedByResponse, error) {
+		return c.next.GetRetweetedBy(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+func (c *CoalescedTwitterService) GetQuoteTweets(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.QuoteTweetsResponse, error) {
+	return coalesced(ctx, c, "GetQuoteTweets", cacheKey("GetQuoteTweets", tweetID, maxResults, paginationToken), func(ctx context.Context) (*models.QuoteTweetsResponse, error) {
+		return c.next.GetQuoteTweets(ctx, tweetID, maxResults, paginationToken)
+	})
+}
+
+// HideTweet là thao tác ghi nên không được gộp
+func (c *CoalescedTwitterService) HideTweet(ctx context.Context, tweetID string, hidden bool) (*models.HideTweetResponse, error) {
+	return c.next.HideTweet(ctx, tweetID, hidden)
+}
+
+// Trạng thái upstream luôn được đọc trực tiếp
+
+func (c *CoalescedTwitterService) GetRateLimits(ctx context.Context) (*models.RateLimitsResponse, error) {
+	return c.next.GetRateLimits(ctx)
+}
+
+func (c *CoalescedTwitterService) GetCircuitBreakers(ctx context.Context) (*models.CircuitBreakersResponse, error) {
+	return c.next.GetCircuitBreakers(ctx)
+}
+
+func (c *CoalescedTwitterService) GetTokenPool(ctx context.Context) (*models.TokenPoolResponse, error) {
+	return c.next.GetTokenPool(ctx)
+}
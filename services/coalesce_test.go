@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// blockingTwitterAPI chặn GetUserByUsername cho tới khi release bị đóng
+type blockingTwitterAPI struct {
+	TwitterAPI
+	calls    int32
+	started  chan struct{}
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func newBlockingTwitterAPI() *blockingTwitterAPI {
+	return &blockingTwitterAPI{
+		started:  make(chan struct{}, 10),
+		release:  make(chan struct{}),
+		canceled: make(chan struct{}, 10),
+	}
+}
+
+func (b *blockingTwitterAPI) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	atomic.AddInt32(&b.calls, 1)
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return &models.User{ID: "1", Username: username}, nil
+	case <-ctx.Done():
+		b.canceled <- struct{}{}
+		return nil, ctx.Err()
+	}
+}
+
+func TestCoalescedServiceSharesInFlightCall(t *testing.T) {
+	upstream := newBlockingTwitterAPI()
+	svc := NewCoalescedTwitterService(upstream)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]*models.User, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// "@Alice" và "alice" là cùng một key sau khi chuẩn hóa
+			username := "alice"
+			if i%2 == 1 {
+				username = "@Alice"
+			}
+			results[i], errs[i] = svc.GetUserByUsername(context.Background(), username)
+		}(i)
+	}
+
+	<-upstream.started
+	waitForWaiters(t, svc, callers)
+	close(upstream.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&upstream.calls); calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+	for i := range results {
+		if errs[i] != nil || results[i] == nil || results[i].ID != "1" {
+			t.Errorf("caller %d: %+v, %v", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestCoalescedServiceCancelOneWaiter(t *testing.T) {
+	upstream := newBlockingTwitterAPI()
+	svc := NewCoalescedTwitterService(upstream)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := svc.GetUserByUsername(leaderCtx, "alice")
+		leaderErr <- err
+	}()
+	<-upstream.started
+
+	followerDone := make(chan error, 1)
+	go func() {
+		_, err := svc.GetUserByUsername(context.Background(), "alice")
+		followerDone <- err
+	}()
+	waitForWaiters(t, svc, 2)
+
+	// Caller đầu tiên hủy: nó nhận lỗi ngay, upstream call vẫn tiếp tục cho caller còn lại
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+	close(upstream.release)
+	if err := <-followerDone; err != nil {
+		t.Errorf("follower err = %v, want nil", err)
+	}
+	select {
+	case <-upstream.canceled:
+		t.Error("upstream call bị hủy dù vẫn còn caller chờ")
+	default:
+	}
+}
+
+func TestCoalescedServiceCancelAllWaiters(t *testing.T) {
+	upstream := newBlockingTwitterAPI()
+	svc := NewCoalescedTwitterService(upstream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.GetUserByUsername(ctx, "alice")
+		done <- err
+	}()
+	<-upstream.started
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-upstream.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream call không bị hủy khi mọi caller đã hủy")
+	}
+
+	// Caller mới bắt đầu flight mới thay vì nhận lỗi canceled của flight cũ
+	close(upstream.release)
+	if _, err := svc.GetUserByUsername(context.Background(), "alice"); err != nil {
+		t.Errorf("call sau khi hủy: %v", err)
+	}
+	if calls := atomic.LoadInt32(&upstream.calls); calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", calls)
+	}
+}
+
+func TestCoalescedServiceBypassDoesNotShare(t *testing.T) {
+	upstream := newBlockingTwitterAPI()
+	svc := NewCoalescedTwitterService(upstream)
+
+	var wg sync.WaitGroup
+	for _, ctx := range []context.Context{context.Background(), WithCacheBypass(context.Background())} {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			svc.GetUserByUsername(ctx, "alice")
+		}(ctx)
+	}
+	<-upstream.started
+	<-upstream.started
+	close(upstream.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&upstream.calls); calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", calls)
+	}
+}
+
+func TestCoalescedServiceUserTokensDoNotShare(t *testing.T) {
+	upstream := newBlockingTwitterAPI()
+	svc := NewCoalescedTwitterService(upstream)
+
+	var wg sync.WaitGroup
+	for _, ctx := range []context.Context{
+		context.Background(),
+		WithUserToken(context.Background(), UserToken{AccessToken: "token-a", UserID: "1"}),
+	} {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			svc.GetUserByUsername(ctx, "alice")
+		}(ctx)
+	}
+	<-upstream.started
+	<-upstream.started
+	close(upstream.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&upstream.calls); calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", calls)
+	}
+}
+
+// waitForWaiters chờ tới khi flight đang chạy có đủ n caller
+func waitForWaiters(t *testing.T, svc *CoalescedTwitterService, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		svc.group.mu.Lock()
+		waiters := 0
+		for _, f := range svc.group.flights {
+			waiters += f.waiters
+		}
+		svc.group.mu.Unlock()
+		if waiters >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("không đủ %d caller chờ flight", n)
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/michimani/gotwi"
+	"github.com/michimani/gotwi/resources"
+)
+
+// ErrorKind phân loại lỗi để handlers chọn HTTP status phù hợp
+type ErrorKind string
+
+const (
+	ErrorKindInvalidRequest ErrorKind = "invalid_request"
+	ErrorKindUnauthorized   ErrorKind = "unauthorized"
+	ErrorKindForbidden      ErrorKind = "forbidden"
+	ErrorKindNotFound       ErrorKind = "not_found"
+	ErrorKindRateLimited    ErrorKind = "rate_limited"
+	ErrorKindUnavailable    ErrorKind = "unavailable"
+	ErrorKindTimeout        ErrorKind = "timeout"
+	ErrorKindUpstream       ErrorKind = "upstream"
+)
+
+// Mã lỗi ổn định (machine-readable) trả về cho client
+const (
+	CodeInvalidRequest        = "INVALID_REQUEST"
+	CodeUpstreamUnauthorized  = "UPSTREAM_UNAUTHORIZED"
+	CodeUpstreamForbidden     = "UPSTREAM_FORBIDDEN"
+	CodeUserContextRequired   = "USER_CONTEXT_REQUIRED"
+	CodeArchiveAccessRequired = "ARCHIVE_ACCESS_REQUIRED"
+	CodeUserSuspended         = "USER_SUSPENDED"
+	CodeUserNotFound          = "USER_NOT_FOUND"
+	CodeTweetNotFound         = "TWEET_NOT_FOUND"
+	CodeNotFound              = "NOT_FOUND"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeUpstreamUnavailable   = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamTimeout       = "UPSTREAM_TIMEOUT"
+	CodeUpstreamError         = "UPSTREAM_ERROR"
+)
+
+// APIError là lỗi có kiểu mà TwitterAPI trả về thay cho chuỗi lỗi tự do
+type APIError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string // thông báo an toàn để trả cho client
+	Detail  string // chi tiết lỗi từ X API (title/detail/errors), nếu có
+
+	// UpstreamStatus là HTTP status code mà X API trả về (0 nếu không gọi tới được)
+	UpstreamStatus int
+	// RetryAfter là thời gian nên chờ trước khi thử lại (chỉ với rate limit/unavailable)
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return e.Message + ": " + e.Detail
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// AsAPIError trả về *APIError nằm trong chuỗi lỗi err, nếu có
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// NewNotFoundError tạo lỗi not found với mã lỗi code
+func NewNotFoundError(code, message string) *APIError {
+	return &APIError{Kind: ErrorKindNotFound, Code: code, Message: message}
+}
+
+// newUserContextRequiredError là lỗi cho các API cần OAuth user context
+func newUserContextRequiredError(message string) *APIError {
+	return &APIError{Kind: ErrorKindForbidden, Code: CodeUserContextRequired, Message: message}
+}
+
+// wrapArchiveError như wrapUpstreamError, nhưng lỗi 403 của các endpoint full-archive
+// được đổi thành CodeArchiveAccessRequired để client biết Bearer Token thiếu quyền
+func wrapArchiveError(err error, message string) error {
+	err = wrapUpstreamError(err, message)
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Kind != ErrorKindForbidden {
+		return err
+	}
+	wrapped := *apiErr
+	wrapped.Code = CodeArchiveAccessRequired
+	wrapped.Message = message + ": Bearer Token không có quyền truy cập full-archive (cần gói Pro hoặc Academic Research)"
+	return &wrapped
+}
+
+// wrapUpstreamError chuyển lỗi của gotwi (non-2xx hoặc lỗi network) thành *APIError
+func wrapUpstreamError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	if apiErr, ok := AsAPIError(err); ok {
+		if _, direct := err.(*APIError); direct {
+			return err
+		}
+		// Lỗi có kiểu từ transport (ví dụ rate limit fail-fast) bị gotwi bọc lại
+		wrapped := *apiErr
+		wrapped.Message = message + ": " + apiErr.Message
+		return &wrapped
+	}
+
+	var gotwiErr *gotwi.GotwiError
+	if errors.As(err, &gotwiErr) && gotwiErr.OnAPI {
+		return fromNon2XX(&gotwiErr.Non2XXError, message, err)
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &APIError{Kind: ErrorKindTimeout, Code: CodeUpstreamTimeout, Message: message + ": X API không phản hồi kịp", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &APIError{Kind: ErrorKindUnavailable, Code: CodeUpstreamUnavailable, Message: message + ": request đã bị hủy", Err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return &APIError{Kind: ErrorKindTimeout, Code: CodeUpstreamTimeout, Message: message + ": X API không phản hồi kịp", Err: err}
+		}
+		return &APIError{Kind: ErrorKindUnavailable, Code: CodeUpstreamUnavailable, Message: message + ": không thể kết nối tới X API", Err: err}
+	}
+
+	return &APIError{Kind: ErrorKindUpstream, Code: CodeUpstreamError, Message: message + ": lỗi không xác định từ X API", Err: err}
+}
+
+// fromNon2XX phân loại response non-2xx của X API
+func fromNon2XX(n *resources.Non2XXError, message string, cause error) *APIError {
+	apiErr := &APIError{
+		Detail:         non2XXDetail(n),
+		UpstreamStatus: n.StatusCode,
+		Err:            cause,
+	}
+
+	switch status := n.StatusCode; {
+	case status == http.StatusBadRequest:
+		apiErr.Kind, apiErr.Code = ErrorKindInvalidRequest, CodeInvalidRequest
+		message += ": X API từ chối request vì tham số không hợp lệ"
+	case status == http.StatusUnauthorized:
+		apiErr.Kind, apiErr.Code = ErrorKindUnauthorized, CodeUpstreamUnauthorized
+		message += ": X API từ chối xác thực, kiểm tra lại Bearer Token"
+	case status == http.StatusForbidden:
+		apiErr.Kind, apiErr.Code = ErrorKindForbidden, CodeUpstreamForbidden
+		message += ": không có quyền truy cập resource này"
+		if strings.Contains(strings.ToLower(apiErr.Detail), "suspended") {
+			apiErr.Code = CodeUserSuspended
+		}
+	case status == http.StatusNotFound:
+		apiErr.Kind, apiErr.Code = ErrorKindNotFound, CodeNotFound
+		message += ": không tìm thấy resource"
+	case status == http.StatusTooManyRequests:
+		apiErr.Kind, apiErr.Code = ErrorKindRateLimited, CodeRateLimited
+		message += ": đã vượt quá rate limit của X API"
+		if n.RateLimitInfo != nil && n.RateLimitInfo.ResetAt != nil {
+			apiErr.RetryAfter = time.Until(*n.RateLimitInfo.ResetAt)
+		}
+	case status == http.StatusInternalServerError, status == http.StatusBadGateway,
+		status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		apiErr.Kind, apiErr.Code = ErrorKindUnavailable, CodeUpstreamUnavailable
+		message += ": X API tạm thời không khả dụng"
+	default:
+		apiErr.Kind, apiErr.Code = ErrorKindUpstream, CodeUpstreamError
+		message += ": lỗi không xác định từ X API"
+	}
+
+	apiErr.Message = message
+
+	return apiErr
+}
+
+// non2XXDetail gom title/detail/errors của X API thành một chuỗi ngắn gọn
+func non2XXDetail(n *resources.Non2XXError) string {
+	parts := make([]string, 0, 2+len(n.APIErrors))
+	if n.Title != "" {
+		parts = append(parts, n.Title)
+	}
+	if n.Detail != "" && n.Detail != n.Title {
+		parts = append(parts, n.Detail)
+	}
+	for _, e := range n.APIErrors {
+		if e.Message != "" {
+			parts = append(parts, e.Message)
+		}
+	}
+	if len(parts) == 0 && n.Status != "" {
+		parts = append(parts, n.Status)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// lookupError chuyển partial errors của một lookup (status 200 nhưng không có data)
+// thành *APIError. notFoundCode là mã lỗi khi resource không tồn tại.
+func lookupError(partials []resources.PartialError, notFoundCode, message string) *APIError {
+	for _, p := range partials {
+		title := gotwi.StringValue(p.Title)
+		detail := gotwi.StringValue(p.Detail)
+		problem := gotwi.StringValue(p.Type)
+
+		switch {
+		case strings.Contains(strings.ToLower(detail), "suspended"):
+			return &APIError{Kind: ErrorKindForbidden, Code: CodeUserSuspended, Message: message, Detail: detail}
+		case strings.HasSuffix(problem, "/resource-not-found"):
+			return &APIError{Kind: ErrorKindNotFound, Code: notFoundCode, Message: message, Detail: detail}
+		case strings.HasSuffix(problem, "/not-authorized-for-resource"), title == "Authorization Error", title == "Forbidden":
+			return &APIError{Kind: ErrorKindForbidden, Code: CodeUpstreamForbidden, Message: message, Detail: detail}
+		}
+	}
+
+	detail := ""
+	if len(partials) > 0 {
+		detail = gotwi.StringValue(partials[0].Detail)
+	}
+	return &APIError{Kind: ErrorKindNotFound, Code: notFoundCode, Message: message, Detail: detail}
+}
+
+// RetryAfterSeconds trả về giá trị header Retry-After (làm tròn lên theo giây), 0 nếu không có
+func (e *APIError) RetryAfterSeconds() int {
+	if e.RetryAfter <= 0 {
+		return 0
+	}
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,871 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// FakeTwitterService là implementation in-memory của TwitterAPI.
+// Dữ liệu được seed qua AddUser, AddTweet, AddFollowing... nên handlers
+// có thể chạy và test mà không cần Bearer Token thật.
+type FakeTwitterService struct {
+	mu sync.RWMutex
+
+	users     map[string]*models.User  // user ID -> user
+	usernames map[string]string        // username (lowercase) -> user ID
+	tweets    map[string]*models.Tweet // tweet ID -> tweet
+
+	following map[string][]string // user ID -> user IDs đang follow
+	likes     map[string][]string // user ID -> tweet IDs đã like
+	retweets  map[string][]string // tweet ID -> user IDs đã retweet
+	blocking  map[string][]string // user ID -> user IDs bị block
+	muting    map[string][]string // user ID -> user IDs bị mute
+	hidden    map[string]bool     // tweet ID -> trạng thái hidden
+
+	meID       string
+	errors     map[string]error // tên method -> lỗi sẽ trả về
+	rateLimits *RateLimitRegistry
+	tokens     []models.BearerTokenStatus
+}
+
+// NewFakeTwitterService tạo một FakeTwitterService rỗng
+func NewFakeTwitterService() *FakeTwitterService {
+	return &FakeTwitterService{
+		users:      make(map[string]*models.User),
+		usernames:  make(map[string]string),
+		tweets:     make(map[string]*models.Tweet),
+		following:  make(map[string][]string),
+		likes:      make(map[string][]string),
+		retweets:   make(map[string][]string),
+		blocking:   make(map[string][]string),
+		muting:     make(map[string][]string),
+		hidden:     make(map[string]bool),
+		errors:     make(map[string]error),
+		rateLimits: NewRateLimitRegistry(),
+	}
+}
+
+// AddUser seed một user
+func (f *FakeTwitterService) AddUser(user models.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u := user
+	f.users[u.ID] = &u
+	f.usernames[strings.ToLower(u.Username)] = u.ID
+}
+
+// AddTweet seed một tweet
+func (f *FakeTwitterService) AddTweet(tweet models.Tweet) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := tweet
+	f.tweets[t.ID] = &t
+}
+
+// AddFollowing ghi nhận userID đang follow targetID
+func (f *FakeTwitterService) AddFollowing(userID, targetID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.following[userID] = append(f.following[userID], targetID)
+}
+
+// AddLike ghi nhận userID đã like tweetID
+func (f *FakeTwitterService) AddLike(userID, tweetID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.likes[userID] = append(f.likes[userID], tweetID)
+}
+
+// AddRetweet ghi nhận userID đã retweet tweetID
+func (f *FakeTwitterService) AddRetweet(tweetID, userID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retweets[tweetID] = append(f.retweets[tweetID], userID)
+}
+
+// AddBlocking ghi nhận userID đã block targetID
+func (f *FakeTwitterService) AddBlocking(userID, targetID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocking[userID] = append(f.blocking[userID], targetID)
+}
+
+// AddMuting ghi nhận userID đã mute targetID
+func (f *FakeTwitterService) AddMuting(userID, targetID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.muting[userID] = append(f.muting[userID], targetID)
+}
+
+// SetMe chọn user đóng vai authenticated user
+func (f *FakeTwitterService) SetMe(userID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.meID = userID
+}
+
+// SetError buộc method (ví dụ "GetUserTweets") trả về err; truyền nil để xóa
+func (f *FakeTwitterService) SetError(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errors, method)
+		return
+	}
+	f.errors[method] = err
+}
+
+// SetRateLimit seed trạng thái rate limit của một endpoint family
+func (f *FakeTwitterService) SetRateLimit(family string, limit, remaining int, resetAt time.Time) {
+	f.rateLimits.Update(family, limit, remaining, resetAt)
+}
+
+// AddTokenStatus seed trạng thái một Bearer Token cho GetTokenPool
+func (f *FakeTwitterService) AddTokenStatus(status models.BearerTokenStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tokens = append(f.tokens, status)
+}
+
+// GetUserByUsername lấy user theo username (không phân biệt hoa thường)
+func (f *FakeTwitterService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserByUsername"); err != nil {
+		return nil, err
+	}
+	return f.userByUsername(username)
+}
+
+// GetUserByID lấy user theo ID
+func (f *FakeTwitterService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserByID"); err != nil {
+		return nil, err
+	}
+	return f.userByID(userID)
+}
+
+// ListUsers lấy danh sách users theo IDs, bỏ qua các ID không tồn tại
+func (f *FakeTwitterService) ListUsers(ctx context.Context, userIDs []string) (*models.UsersListResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("ListUsers"); err != nil {
+		return nil, err
+	}
+
+	users := f.collectUsers(userIDs)
+	return &models.UsersListResponse{
+		Users: users,
+		Meta:  &models.Meta{ResultCount: len(users)},
+	}, nil
+}
+
+// GetMe trả về user đã chọn bằng SetMe
+func (f *FakeTwitterService) GetMe(ctx context.Context) (*models.User, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetMe"); err != nil {
+		return nil, err
+	}
+	return f.me()
+}
+
+// SearchUsers trả về tác giả của các tweets khớp query
+func (f *FakeTwitterService) SearchUsers(ctx context.Context, query string, maxResults int, paginationToken string) (*models.SearchUsersResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("SearchUsers"); err != nil {
+		return nil, err
+	}
+
+	// Giống TwitterService: phân trang theo tweets khớp query rồi lấy authors
+	tweets, meta, err := pageTweets(f.searchTweets(query), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	users := make([]models.User, 0)
+	for _, tweet := range tweets {
+		if seen[tweet.AuthorID] {
+			continue
+		}
+		seen[tweet.AuthorID] = true
+		if user, ok := f.users[tweet.AuthorID]; ok {
+			users = append(users, *user)
+		}
+	}
+
+	return &models.SearchUsersResponse{
+		Users: users,
+		Meta:  &models.Meta{ResultCount: len(users), NextToken: meta.NextToken},
+	}, nil
+}
+
+// GetUserFollowing lấy danh sách tài khoản mà user đang theo dõi
+func (f *FakeTwitterService) GetUserFollowing(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowingResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserFollowing"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.userByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	users, meta, err := pageUsers(f.collectUsers(f.following[user.ID]), fakeLimit(maxResults, 1000), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FollowingResponse{User: user, Following: users, Meta: meta}, nil
+}
+
+// GetUserFollowers lấy danh sách followers của user
+func (f *FakeTwitterService) GetUserFollowers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowersResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserFollowers"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.userByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	followerIDs := make([]string, 0)
+	for followerID, targets := range f.following {
+		for _, target := range targets {
+			if target == user.ID {
+				followerIDs = append(followerIDs, followerID)
+				break
+			}
+		}
+	}
+	sort.Strings(followerIDs)
+
+	users, meta, err := pageUsers(f.collectUsers(followerIDs), fakeLimit(maxResults, 1000), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FollowersResponse{User: user, Followers: users, Meta: meta}, nil
+}
+
+// GetBlockingUsers lấy danh sách users bị block bởi authenticated user
+func (f *FakeTwitterService) GetBlockingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.BlockingUsersResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetBlockingUsers"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.meNamed(username)
+	if err != nil {
+		return nil, err
+	}
+
+	users, meta, err := pageUsers(f.collectUsers(f.blocking[user.ID]), fakeLimit(maxResults, 1000), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BlockingUsersResponse{User: user, Users: users, Meta: meta}, nil
+}
+
+// GetMutingUsers lấy danh sách users bị mute bởi authenticated user
+func (f *FakeTwitterService) GetMutingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.MutingUsersResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetMutingUsers"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.meNamed(username)
+	if err != nil {
+		return nil, err
+	}
+
+	users, meta, err := pageUsers(f.collectUsers(f.muting[user.ID]), fakeLimit(maxResults, 1000), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MutingUsersResponse{User: user, Users: users, Meta: meta}, nil
+}
+
+// GetUserTweets lấy tweets mới nhất của user
+func (f *FakeTwitterService) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserTweets"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.userByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	tweets, meta, err := pageTweets(filterBy(f.tweetsByAuthor(user.ID), filter), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TweetsResponse{Tweets: tweets, User: user, Meta: meta}, nil
+}
+
+// GetTweetsByUserID lấy tweets theo user ID
+func (f *FakeTwitterService) GetTweetsByUserID(ctx context.Context, userID string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetTweetsByUserID"); err != nil {
+		return nil, err
+	}
+
+	tweets, meta, err := pageTweets(f.tweetsByAuthor(userID), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TweetsResponse{Tweets: tweets, Meta: meta}, nil
+}
+
+// GetUserTimelineReverseChronological dùng lại GetUserTweets giống TwitterService
+func (f *FakeTwitterService) GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
+	if err := f.failureLocked("GetUserTimelineReverseChronological"); err != nil {
+		return nil, err
+	}
+	return f.GetUserTweets(ctx, username, maxResults, paginationToken, TweetFilter{})
+}
+
+// GetUserMentions lấy tweets có mention đến user
+func (f *FakeTwitterService) GetUserMentions(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.MentionsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetUserMentions"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.userByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := f.filterTweets(func(t *models.Tweet) bool {
+		return mentionsUser(t, user.Username) && filter.Match(t)
+	})
+
+	tweets, meta, err := pageTweets(mentions, fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MentionsResponse{User: user, Tweets: tweets, Meta: meta}, nil
+}
+
+// GetLikedTweets lấy tweets mà user đã like
+func (f *FakeTwitterService) GetLikedTweets(ctx context.Context, username string, maxResults int, paginationToken string) (*models.LikedTweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetLikedTweets"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.userByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	tweets, meta, err := pageTweets(f.collectTweets(f.likes[user.ID]), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LikedTweetsResponse{User: user, Tweets: tweets, Meta: meta}, nil
+}
+
+// GetRepostsOfMe lấy tweets của authenticated user đã được người khác retweet
+func (f *FakeTwitterService) GetRepostsOfMe(ctx context.Context, maxResults int, paginationToken string) (*models.RepostsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetRepostsOfMe"); err != nil {
+		return nil, err
+	}
+
+	user, err := f.me()
+	if err != nil {
+		return nil, err
+	}
+
+	reposted := f.filterTweets(func(t *models.Tweet) bool {
+		return t.AuthorID == user.ID && len(f.retweets[t.ID]) > 0
+	})
+
+	tweets, meta, err := pageTweets(reposted, fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RepostsResponse{User: user, Tweets: tweets, Meta: meta}, nil
+}
+
+// GetTweetByID lấy chi tiết tweet kèm author
+func (f *FakeTwitterService) GetTweetByID(ctx context.Context, tweetID string) (*models.TweetDetailResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetTweetByID"); err != nil {
+		return nil, err
+	}
+
+	tweet, ok := f.tweets[tweetID]
+	if !ok {
+		return nil, NewNotFoundError(CodeTweetNotFound, fmt.Sprintf("không tìm thấy tweet với ID: %s", tweetID))
+	}
+
+	result := &models.TweetDetailResponse{Tweet: *tweet}
+	if author, ok := f.users[tweet.AuthorID]; ok {
+		a := *author
+		result.Author = &a
+	}
+	return result, nil
+}
+
+// ListTweets lấy danh sách tweets theo IDs, bỏ qua các ID không tồn tại
+func (f *FakeTwitterService) ListTweets(ctx context.Context, tweetIDs []string) (*models.SearchTweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("ListTweets"); err != nil {
+		return nil, err
+	}
+
+	tweets := f.collectTweets(tweetIDs)
+	return &models.SearchTweetsResponse{
+		Tweets: tweets,
+		Meta:   &models.Meta{ResultCount: len(tweets)},
+	}, nil
+}
+
+// SearchTweets tìm tweets có text chứa tất cả các từ trong query
+func (f *FakeTwitterService) SearchTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("SearchTweets"); err != nil {
+		return nil, err
+	}
+
+	tweets, meta, err := pageTweets(filterBy(f.searchTweets(query), filter), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SearchTweetsResponse{Tweets: tweets, Meta: meta}, nil
+}
+
+// GetTweetCounts đếm tweets khớp query theo bucket của granularity
+func (f *FakeTwitterService) GetTweetCounts(ctx context.Context, query string, opts TweetCountsOptions) (*models.TweetCountsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetTweetCounts"); err != nil {
+		return nil, err
+	}
+	return f.countTweets(query, opts, 7*24*time.Hour)
+}
+
+// SearchAllTweets giống SearchTweets nhưng cho phép tối đa 500 tweets mỗi trang
+func (f *FakeTwitterService) SearchAllTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("SearchAllTweets"); err != nil {
+		return nil, err
+	}
+
+	tweets, meta, err := pageTweets(filterBy(f.searchTweets(query), filter), fakeLimit(maxResults, 500), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SearchTweetsResponse{Tweets: tweets, Meta: meta}, nil
+}
+
+// GetAllTweetCounts giống GetTweetCounts với khoảng mặc định 30 ngày, trả về mọi bucket
+// trong một trang
+func (f *FakeTwitterService) GetAllTweetCounts(ctx context.Context, query string, opts TweetCountsOptions, paginationToken string) (*models.TweetCountsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetAllTweetCounts"); err != nil {
+		return nil, err
+	}
+	if paginationToken != "" {
+		return nil, &APIError{Kind: ErrorKindInvalidRequest, Code: CodeInvalidRequest, Message: fmt.Sprintf("pagination token không hợp lệ: %s", paginationToken)}
+	}
+	return f.countTweets(query, opts, 30*24*time.Hour)
+}
+
+// GetLikingUsers lấy users đã like tweet
+func (f *FakeTwitterService) GetLikingUsers(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.LikingUsersResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetLikingUsers"); err != nil {
+		return nil, err
+	}
+
+	likerIDs := make([]string, 0)
+	for userID, tweetIDs := range f.likes {
+		for _, id := range tweetIDs {
+			if id == tweetID {
+				likerIDs = append(likerIDs, userID)
+				break
+			}
+		}
+	}
+	sort.Strings(likerIDs)
+
+	users, meta, err := pageUsers(f.collectUsers(likerIDs), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LikingUsersResponse{TweetID: tweetID, Users: users, Meta: meta}, nil
+}
+
+// GetRetweetedBy lấy users đã retweet tweet
+func (f *FakeTwitterService) GetRetweetedBy(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.RetweetedByResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetRetweetedBy"); err != nil {
+		return nil, err
+	}
+
+	users, meta, err := pageUsers(f.collectUsers(f.retweets[tweetID]), fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RetweetedByResponse{TweetID: tweetID, Users: users, Meta: meta}, nil
+}
+
+// GetQuoteTweets lấy các tweets quote tweetID
+func (f *FakeTwitterService) GetQuoteTweets(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.QuoteTweetsResponse, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if err := f.failure("GetQuoteTweets"); err != nil {
+		return nil, err
+	}
+
+	quotes := f.filterTweets(func(t *models.Tweet) bool {
+		for _, ref := range t.ReferencedTweets {
+			if ref.Type == "quoted" && ref.ID == tweetID {
+				return true
+			}
+		}
+		return false
+	})
+
+	tweets, meta, err := pageTweets(quotes, fakeLimit(maxResults, 100), paginationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuoteTweetsResponse{TweetID: tweetID, Tweets: tweets, Meta: meta}, nil
+}
+
+// HideTweet ghi nhận trạng thái hidden của tweet
+func (f *FakeTwitterService) HideTweet(ctx context.Context, tweetID string, hidden bool) (*models.HideTweetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.failure("HideTweet"); err != nil {
+		return nil, err
+	}
+
+	if _, ok := f.tweets[tweetID]; !ok {
+		return nil, NewNotFoundError(CodeTweetNotFound, fmt.Sprintf("không tìm thấy tweet với ID: %s", tweetID))
+	}
+
+	f.hidden[tweetID] = hidden
+	return &models.HideTweetResponse{TweetID: tweetID, Hidden: hidden}, nil
+}
+
+// GetRateLimits trả về các rate limit đã seed qua SetRateLimit
+func (f *FakeTwitterService) GetRateLimits(ctx context.Context) (*models.RateLimitsResponse, error) {
+	if err := f.failureLocked("GetRateLimits"); err != nil {
+		return nil, err
+	}
+	return &models.RateLimitsResponse{RateLimits: f.rateLimits.Snapshot()}, nil
+}
+
+// GetCircuitBreakers luôn trả về danh sách rỗng vì fake không gọi upstream
+func (f *FakeTwitterService) GetCircuitBreakers(ctx context.Context) (*models.CircuitBreakersResponse, error) {
+	if err := f.failureLocked("GetCircuitBreakers"); err != nil {
+		return nil, err
+	}
+	return &models.CircuitBreakersResponse{CircuitBreakers: []models.CircuitBreakerStatus{}}, nil
+}
+
+// GetTokenPool trả về các Bearer Token đã seed qua AddTokenStatus
+func (f *FakeTwitterService) GetTokenPool(ctx context.Context) (*models.TokenPoolResponse, error) {
+	if err := f.failureLocked("GetTokenPool"); err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	tokens := append([]models.BearerTokenStatus{}, f.tokens...)
+	return &models.TokenPoolResponse{Tokens: tokens}, nil
+}
+
+// Hidden trả về trạng thái hidden đã ghi nhận của tweet
+func (f *FakeTwitterService) Hidden(tweetID string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.hidden[tweetID]
+}
+
+// failure trả về lỗi đã đăng ký cho method; caller phải giữ lock
+func (f *FakeTwitterService) failure(method string) error {
+	return f.errors[method]
+}
+
+// failureLocked giống failure nhưng tự lấy read lock
+func (f *FakeTwitterService) failureLocked(method string) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.failure(method)
+}
+
+func (f *FakeTwitterService) userByUsername(username string) (*models.User, error) {
+	id, ok := f.usernames[strings.ToLower(username)]
+	if !ok {
+		return nil, NewNotFoundError(CodeUserNotFound, fmt.Sprintf("không tìm thấy user với username: %s", username))
+	}
+	return f.userByID(id)
+}
+
+func (f *FakeTwitterService) userByID(userID string) (*models.User, error) {
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, NewNotFoundError(CodeUserNotFound, fmt.Sprintf("không tìm thấy user với ID: %s", userID))
+	}
+	u := *user
+	return &u, nil
+}
+
+func (f *FakeTwitterService) me() (*models.User, error) {
+	if f.meID == "" {
+		return nil, newUserContextRequiredError("chưa cấu hình authenticated user cho FakeTwitterService")
+	}
+	return f.userByID(f.meID)
+}
+
+// meNamed trả về authenticated user, với điều kiện username trùng với user đó
+func (f *FakeTwitterService) meNamed(username string) (*models.User, error) {
+	user, err := f.me()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Username, username) {
+		return nil, &APIError{Kind: ErrorKindForbidden, Code: CodeUpstreamForbidden, Message: fmt.Sprintf("chỉ có thể xem danh sách của authenticated user (%s)", user.Username)}
+	}
+	return user, nil
+}
+
+func (f *FakeTwitterService) collectUsers(ids []string) []models.User {
+	users := make([]models.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := f.users[id]; ok {
+			users = append(users, *user)
+		}
+	}
+	return users
+}
+
+func (f *FakeTwitterService) collectTweets(ids []string) []models.Tweet {
+	tweets := make([]models.Tweet, 0, len(ids))
+	for _, id := range ids {
+		if tweet, ok := f.tweets[id]; ok {
+			tweets = append(tweets, *tweet)
+		}
+	}
+	return tweets
+}
+
+func (f *FakeTwitterService) tweetsByAuthor(userID string) []models.Tweet {
+	return f.filterTweets(func(t *models.Tweet) bool {
+		return t.AuthorID == userID
+	})
+}
+
+func (f *FakeTwitterService) searchTweets(query string) []models.Tweet {
+	terms := strings.Fields(strings.ToLower(query))
+	return f.filterTweets(func(t *models.Tweet) bool {
+		text := strings.ToLower(t.Text)
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+		return len(terms) > 0
+	})
+}
+
+// filterTweets trả về các tweets thỏa điều kiện, mới nhất trước
+func (f *FakeTwitterService) filterTweets(match func(t *models.Tweet) bool) []models.Tweet {
+	tweets := make([]models.Tweet, 0)
+	for _, tweet := range f.tweets {
+		if match(tweet) {
+			tweets = append(tweets, *tweet)
+		}
+	}
+	sort.Slice(tweets, func(i, j int) bool {
+		if tweets[i].CreatedAt.Equal(tweets[j].CreatedAt) {
+			return tweets[i].ID > tweets[j].ID
+		}
+		return tweets[i].CreatedAt.After(tweets[j].CreatedAt)
+	})
+	return tweets
+}
+
+// countTweets đếm tweets khớp query theo bucket của granularity, mặc định trong khoảng
+// defaultWindow trước hiện tại. Giống X API, bucket được căn theo granularity nên bucket
+// đầu và cuối có thể ngắn hơn. Caller phải giữ f.mu.
+func (f *FakeTwitterService) countTweets(query string, opts TweetCountsOptions, defaultWindow time.Duration) (*models.TweetCountsResponse, error) {
+	step, ok := GranularityStep(opts.granularity())
+	if !ok {
+		return nil, &APIError{Kind: ErrorKindInvalidRequest, Code: CodeInvalidRequest, Message: fmt.Sprintf("granularity không hợp lệ: %s", opts.Granularity)}
+	}
+	end := opts.EndTime
+	if end.IsZero() {
+		end = time.Now().UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+	start := opts.StartTime
+	if start.IsZero() {
+		start = end.Add(-defaultWindow)
+	}
+
+	matches := f.searchTweets(query)
+	counts := make([]models.TweetCount, 0)
+	for bucket := start; bucket.Before(end); {
+		count := models.TweetCount{Start: bucket, End: bucket.Truncate(step).Add(step)}
+		if count.End.After(end) {
+			count.End = end
+		}
+		for _, tweet := range matches {
+			if !tweet.CreatedAt.Before(count.Start) && tweet.CreatedAt.Before(count.End) {
+				count.TweetCount++
+			}
+		}
+		counts = append(counts, count)
+		bucket = count.End
+	}
+
+	return newTweetCountsResponse(query, opts.granularity(), counts), nil
+}
+
+// filterBy giữ lại các tweets nằm trong filter
+func filterBy(tweets []models.Tweet, filter TweetFilter) []models.Tweet {
+	matched := make([]models.Tweet, 0, len(tweets))
+	for i := range tweets {
+		if filter.Match(&tweets[i]) {
+			matched = append(matched, tweets[i])
+		}
+	}
+	return matched
+}
+
+// mentionsUser kiểm tra tweet có mention đến username không
+func mentionsUser(tweet *models.Tweet, username string) bool {
+	if tweet.Entities != nil {
+		for _, mention := range tweet.Entities.Mentions {
+			if strings.EqualFold(mention.Username, username) {
+				return true
+			}
+		}
+	}
+	return contains(tweet.Text, "@"+username)
+}
+
+// fakeLimit áp dụng default và giới hạn max cho maxResults
+func fakeLimit(maxResults, max int) int {
+	if maxResults <= 0 {
+		return 10
+	}
+	if maxResults > max {
+		return max
+	}
+	return maxResults
+}
+
+func pageUsers(users []models.User, limit int, token string) ([]models.User, *models.Meta, error) {
+	start, end, meta, err := fakePage(len(users), limit, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users[start:end], meta, nil
+}
+
+func pageTweets(tweets []models.Tweet, limit int, token string) ([]models.Tweet, *models.Meta, error) {
+	start, end, meta, err := fakePage(len(tweets), limit, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tweets[start:end], meta, nil
+}
+
+// fakePage tính khoảng [start, end) của trang hiện tại; token là offset dạng số
+func fakePage(total, limit int, token string) (int, int, *models.Meta, error) {
+	start := 0
+	if token != "" {
+		offset, err := strconv.Atoi(token)
+		if err != nil || offset < 0 || offset > total {
+			return 0, 0, nil, &APIError{Kind: ErrorKindInvalidRequest, Code: CodeInvalidRequest, Message: fmt.Sprintf("pagination token không hợp lệ: %s", token)}
+		}
+		start = offset
+	}
+
+	end := min(start+limit, total)
+	meta := &models.Meta{ResultCount: end - start}
+	if end < total {
+		meta.NextToken = strconv.Itoa(end)
+	}
+	if start > 0 {
+		meta.PreviousToken = strconv.Itoa(max(start-limit, 0))
+	}
+	return start, end, meta, nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"x-twitter-backend/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CodeOAuthTokenError là mã lỗi khi token endpoint của X từ chối code/refresh token
+const CodeOAuthTokenError = "OAUTH_TOKEN_ERROR"
+
+// OAuth2Token là response của token endpoint (authorization_code và refresh_token)
+type OAuth2Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauth2ErrorResponse là lỗi của token endpoint theo RFC 6749 mục 5.2
+type oauth2ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OAuth2Client chạy OAuth 2.0 Authorization Code with PKCE với authorization
+// server của X (hoặc server thay thế cấu hình qua TWITTER_OAUTH2_*_URL)
+type OAuth2Client struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+	authURL      string
+	tokenURL     string
+	revokeURL    string
+	httpClient   *http.Client
+}
+
+// NewOAuth2Client tạo OAuth2Client từ config
+func NewOAuth2Client(cfg *config.Config) *OAuth2Client {
+	return &OAuth2Client{
+		clientID:     cfg.OAuth2ClientID,
+		clientSecret: cfg.OAuth2ClientSecret,
+		redirectURL:  cfg.OAuth2RedirectURL,
+		scopes:       cfg.OAuth2Scopes,
+		authURL:      cfg.OAuth2AuthURL,
+		tokenURL:     cfg.OAuth2TokenURL,
+		revokeURL:    cfg.OAuth2RevokeURL,
+		httpClient:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// AuthCodeURL trả về URL authorize để redirect end user, kèm state và code
+// challenge S256 của codeVerifier
+func (c *OAuth2Client) AuthCodeURL(state, codeVerifier string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.clientID)
+	query.Set("redirect_uri", c.redirectURL)
+	query.Set("scope", c.scopes)
+	query.Set("state", state)
+	query.Set("code_challenge", pkceChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(c.authURL, "?") {
+		sep = "&"
+	}
+	return c.authURL + sep + query.Encode()
+}
+
+// Exchange đổi authorization code lấy access token (và refresh token nếu có scope offline.access)
+func (c *OAuth2Client) Exchange(ctx context.Context, code, codeVerifier string) (*OAuth2Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	var token OAuth2Token
+	if err := c.post(ctx, c.tokenURL, form, &token, "không thể đổi authorization code lấy access token"); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Refresh lấy access token mới bằng refresh token. X xoay vòng refresh token nên
+// token trả về thường có RefreshToken mới thay cho token cũ.
+func (c *OAuth2Client) Refresh(ctx context.Context, refreshToken string) (*OAuth2Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+
+	var token OAuth2Token
+	if err := c.post(ctx, c.tokenURL, form, &token, "không thể refresh access token"); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Revoke thu hồi access hoặc refresh token
+func (c *OAuth2Client) Revoke(ctx context.Context, token, tokenTypeHint string) error {
+	form := url.Values{}
+	form.Set("token", token)
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+	return c.post(ctx, c.revokeURL, form, nil, "không thể thu hồi token")
+}
+
+// post gửi form tới endpoint của authorization server. Public client gửi client_id
+// trong form, confidential client xác thực bằng HTTP Basic.
+func (c *OAuth2Client) post(ctx context.Context, endpoint string, form url.Values, out interface{}, message string) error {
+	if c.clientSecret == "" {
+		form.Set("client_id", c.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return &APIError{Kind: ErrorKindUpstream, Code: CodeUpstreamError, Message: message, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return wrapUpstreamError(err, message)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return wrapUpstreamError(err, message)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return oauth2Error(res.StatusCode, body, message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return &APIError{Kind: ErrorKindUpstream, Code: CodeUpstreamError, Message: message + ": response của token endpoint không hợp lệ", UpstreamStatus: res.StatusCode, Err: err}
+	}
+	if token, ok := out.(*OAuth2Token); ok && token.AccessToken == "" {
+		return &APIError{Kind: ErrorKindUpstream, Code: CodeUpstreamError, Message: message + ": token endpoint không trả về access_token", UpstreamStatus: res.StatusCode}
+	}
+	return nil
+}
+
+// oauth2Error phân loại response lỗi của token/revoke endpoint. Lỗi 4xx do code,
+// verifier hoặc refresh token không còn hợp lệ (invalid_grant) là unauthorized.
+func oauth2Error(status int, body []byte, message string) *APIError {
+	var payload oauth2ErrorResponse
+	_ = json.Unmarshal(body, &payload)
+
+	detail := payload.Error
+	if payload.ErrorDescription != "" {
+		detail = strings.TrimSpace(detail + " " + payload.ErrorDescription)
+	}
+
+	apiErr := &APIError{Message: message, Detail: detail, UpstreamStatus: status}
+	switch {
+	case status == http.StatusTooManyRequests:
+		apiErr.Kind, apiErr.Code = ErrorKindRateLimited, CodeRateLimited
+	case status >= 500:
+		apiErr.Kind, apiErr.Code = ErrorKindUnavailable, CodeUpstreamUnavailable
+	case payload.Error == "invalid_client" || payload.Error == "unauthorized_client":
+		// Lỗi cấu hình client ID/secret của backend, không phải lỗi của end user
+		apiErr.Kind, apiErr.Code = ErrorKindUpstream, CodeUpstreamUnauthorized
+	default:
+		apiErr.Kind, apiErr.Code = ErrorKindUnauthorized, CodeOAuthTokenError
+	}
+
+	log.WithFields(log.Fields{
+		"status": status,
+		"error":  payload.Error,
+	}).Warn("Authorization server trả về lỗi")
+	return apiErr
+}
+
+// randomToken tạo chuỗi ngẫu nhiên base64url (không padding) từ n bytes, dùng cho
+// state, PKCE code verifier và session ID
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("không thể tạo chuỗi ngẫu nhiên: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// pkceChallenge tính code_challenge S256 của verifier (RFC 7636 mục 4.2)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/mockserver"
+)
+
+const (
+	testClientID    = "test-oauth2-client"
+	testRedirectURL = "http://localhost:8080/auth/callback"
+	testBobID       = "1000000000000000003"
+)
+
+// newOAuth2TestEnv chạy mock server (đóng vai cả X API lẫn authorization server) và
+// trả về TwitterService, SessionManager trỏ tới nó. Token OAuth 2.0 là ngẫu nhiên nên
+// các tests này gọi mock server trực tiếp thay vì dùng cassettes.
+func newOAuth2TestEnv(t *testing.T, opts mockserver.Options, refreshBefore time.Duration) (*TwitterService, *SessionManager) {
+	t.Helper()
+
+	fixtures, err := mockserver.LoadFixtures("../mockserver/fixtures")
+	if err != nil {
+		t.Fatalf("không load được fixtures: %v", err)
+	}
+	opts.BearerToken = testBearerToken
+	opts.OAuth2ClientID = testClientID
+	server := httptest.NewServer(mockserver.New(fixtures, opts))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		TwitterBearerToken:      testBearerToken,
+		TwitterAPIBaseURL:       server.URL,
+		TwitterHTTPMode:         "live",
+		TwitterRetryMaxAttempts: 1,
+		MaxTweetsPerRequest:     100,
+		DefaultTweetsCount:      10,
+		OAuth2ClientID:          testClientID,
+		OAuth2RedirectURL:       testRedirectURL,
+		OAuth2Scopes:            "tweet.read users.read block.read offline.access",
+		OAuth2AuthURL:           server.URL + "/i/oauth2/authorize",
+		OAuth2TokenURL:          server.URL + "/2/oauth2/token",
+		OAuth2RevokeURL:         server.URL + "/2/oauth2/revoke",
+	}
+	svc, err := NewTwitterService(cfg)
+	if err != nil {
+		t.Fatalf("NewTwitterService: %v", err)
+	}
+	sessions := newSessionManager(NewOAuth2Client(cfg), NewMemoryCacheStore(100), time.Hour, refreshBefore)
+	return svc, sessions
+}
+
+// authorize mở URL authorize như trình duyệt và trả về code, state trong redirect về callback
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("GET authorize: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", res.StatusCode)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Location không hợp lệ: %v", err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testRedirectURL {
+		t.Fatalf("redirect tới %s, want %s", got, testRedirectURL)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+// login chạy trọn flow PKCE và tạo session cho user đăng nhập
+func login(t *testing.T, svc *TwitterService, sessions *SessionManager) *Session {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, _, err := sessions.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code, state := authorize(t, authURL)
+
+	token, _, ok, err := sessions.CompleteLogin(ctx, state, code)
+	if err != nil || !ok {
+		t.Fatalf("CompleteLogin: ok=%v err=%v", ok, err)
+	}
+	user, err := svc.GetMe(WithUserToken(ctx, UserToken{AccessToken: token.AccessToken}))
+	if err != nil {
+		t.Fatalf("GetMe bằng token của user: %v", err)
+	}
+	sess, err := sessions.Create(ctx, token, user.ID, user.Username)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return sess
+}
+
+func TestOAuth2LoginUsesUserToken(t *testing.T) {
+	svc, sessions := newOAuth2TestEnv(t, mockserver.Options{OAuth2User: "bobtran"}, time.Minute)
+	ctx := context.Background()
+
+	sess := login(t, svc, sessions)
+	if sess.UserID != testBobID || sess.Username != "bobtran" || sess.RefreshToken == "" {
+		t.Fatalf("session = %+v, want bobtran với refresh token", sess)
+	}
+
+	userCtx := WithUserToken(ctx, sess.UserToken())
+	me, err := svc.GetMe(userCtx)
+	if err != nil || me.Username != "bobtran" {
+		t.Fatalf("GetMe = %v, %v; want bobtran", me, err)
+	}
+
+	// API cần user context chạy bằng token của user, không cần OAuth 1.0a
+	if _, err := svc.GetBlockingUsers(userCtx, "bobtran", 10, ""); err != nil {
+		t.Errorf("GetBlockingUsers bằng token của user: %v", err)
+	}
+	// X API chỉ cho xem blocking list của chính mình
+	if _, err := svc.GetBlockingUsers(userCtx, "alice_dev", 10, ""); err == nil {
+		t.Error("GetBlockingUsers của user khác phải lỗi")
+	}
+
+	// Không có session thì vẫn là Bearer Token của app
+	if _, err := svc.GetMe(ctx); err == nil {
+		t.Error("GetMe không có session phải trả USER_CONTEXT_REQUIRED")
+	}
+}
+
+func TestOAuth2StateAndCodeAreSingleUse(t *testing.T) {
+	_, sessions := newOAuth2TestEnv(t, mockserver.Options{}, time.Minute)
+	ctx := context.Background()
+
+	authURL, wantState, err := sessions.BeginLogin(ctx, "/test")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	code, state := authorize(t, authURL)
+	if state != wantState {
+		t.Fatalf("authorize trả về state %q, BeginLogin trả về %q", state, wantState)
+	}
+
+	if _, redirect, ok, err := sessions.CompleteLogin(ctx, state, code); err != nil || !ok || redirect != "/test" {
+		t.Fatalf("CompleteLogin = %q, %v, %v", redirect, ok, err)
+	}
+	if _, _, ok, err := sessions.CompleteLogin(ctx, state, code); ok || err != nil {
+		t.Errorf("state dùng lại: ok=%v err=%v, want ok=false", ok, err)
+	}
+	if _, _, ok, _ := sessions.CompleteLogin(ctx, "unknown-state", code); ok {
+		t.Error("state lạ phải bị từ chối")
+	}
+}
+
+func TestOAuth2ExchangeRejectsWrongVerifier(t *testing.T) {
+	_, sessions := newOAuth2TestEnv(t, mockserver.Options{}, time.Minute)
+	ctx := context.Background()
+
+	code, _ := authorize(t, sessions.oauth.AuthCodeURL("state", "verifier-of-the-real-client-0123456789abcdef"))
+	_, err := sessions.oauth.Exchange(ctx, code, "verifier-of-an-attacker-0123456789abcdefghij")
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Kind != ErrorKindUnauthorized || apiErr.Code != CodeOAuthTokenError || apiErr.UpstreamStatus != http.StatusBadRequest {
+		t.Fatalf("err = %#v, want unauthorized %s", err, CodeOAuthTokenError)
+	}
+}
+
+func TestSessionRefreshesBeforeExpiry(t *testing.T) {
+	// Access token sống 1 phút, refresh khi còn dưới 2 phút: mọi lần Get đều refresh
+	svc, sessions := newOAuth2TestEnv(t, mockserver.Options{OAuth2TokenTTL: time.Minute}, 2*time.Minute)
+	ctx := context.Background()
+	sess := login(t, svc, sessions)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]*Session, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = sessions.Get(ctx, sess.ID)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("Get #%d: %v", i, errs[i])
+		}
+		if results[i].AccessToken == sess.AccessToken || results[i].RefreshToken == sess.RefreshToken {
+			t.Fatalf("Get #%d không refresh token", i)
+		}
+	}
+
+	// Token mới dùng được, refresh token cũ đã bị xoay vòng
+	refreshed := results[0]
+	if me, err := svc.GetMe(WithUserToken(ctx, refreshed.UserToken())); err != nil || me.ID != sess.UserID {
+		t.Errorf("GetMe bằng token đã refresh = %v, %v", me, err)
+	}
+	if _, err := sessions.oauth.Refresh(ctx, sess.RefreshToken); err == nil {
+		t.Error("refresh token cũ vẫn dùng được sau khi xoay vòng")
+	}
+}
+
+func TestSessionExpiredWhenRefreshTokenRevoked(t *testing.T) {
+	svc, sessions := newOAuth2TestEnv(t, mockserver.Options{OAuth2TokenTTL: time.Minute}, 2*time.Minute)
+	ctx := context.Background()
+	sess := login(t, svc, sessions)
+
+	if err := sessions.oauth.Revoke(ctx, sess.RefreshToken, "refresh_token"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	_, err := sessions.Get(ctx, sess.ID)
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Code != CodeSessionExpired || apiErr.Kind != ErrorKindUnauthorized {
+		t.Fatalf("Get = %v, want %s", err, CodeSessionExpired)
+	}
+	// Session đã bị xóa
+	if got, err := sessions.Get(ctx, sess.ID); got != nil || err != nil {
+		t.Errorf("Get sau khi hết hạn = %v, %v; want nil", got, err)
+	}
+}
+
+func TestSessionDeleteRevokesTokens(t *testing.T) {
+	svc, sessions := newOAuth2TestEnv(t, mockserver.Options{}, time.Minute)
+	ctx := context.Background()
+	sess := login(t, svc, sessions)
+
+	if err := sessions.Delete(ctx, sess.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, _ := sessions.Get(ctx, sess.ID); got != nil {
+		t.Error("session vẫn còn sau Delete")
+	}
+
+	_, err := svc.GetMe(WithUserToken(ctx, sess.UserToken()))
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Kind != ErrorKindUnauthorized {
+		t.Errorf("GetMe bằng token đã thu hồi = %v, want unauthorized", err)
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Lý do auto-pagination dừng trước khi hết dữ liệu (meta.stop_reason)
+const (
+	StopReasonMaxItems    = "max_items"
+	StopReasonDeadline    = "deadline"
+	StopReasonRateLimited = "rate_limited"
+	StopReasonUnavailable = "upstream_unavailable"
+)
+
+// PaginateOptions giới hạn một lần auto-pagination
+type PaginateOptions struct {
+	// MaxItems là tổng số items tối đa gộp vào response
+	MaxItems int
+	// PageSize là max_results của mỗi trang; 0 = lớn nhất mà endpoint cho phép
+	PageSize int
+	// Deadline là thời điểm phải dừng và trả về cursor cho client gọi tiếp
+	Deadline time.Time
+}
+
+// PageFetcher lấy một trang tối đa maxResults items bắt đầu từ paginationToken
+type PageFetcher[R any] func(ctx context.Context, maxResults int, paginationToken string) (*R, error)
+
+// Paginate đi theo next_token từ paginationToken và gộp items của mọi trang vào
+// response của trang đầu. page trả về con trỏ tới field items và meta của một response.
+// Điều kiện dừng và lỗi giống WalkPages; meta của response là meta tổng hợp.
+func Paginate[R any, T any](ctx context.Context, opts PaginateOptions, paginationToken string, fetch PageFetcher[R], page func(*R) (*[]T, **models.Meta)) (*R, error) {
+	var (
+		merged *R
+		items  = make([]T, 0)
+	)
+	meta, err := WalkPages(ctx, opts, paginationToken, fetch, page, func(resp *R, pageItems []T) error {
+		if merged == nil {
+			// Sao chép vì response có thể được chia sẻ (coalescing) với request khác
+			first := *resp
+			merged = &first
+		}
+		items = append(items, pageItems...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mergedItems, mergedMeta := page(merged)
+	*mergedItems = items
+	*mergedMeta = meta
+	return merged, nil
+}
+
+// WalkPages đi theo next_token từ paginationToken và gọi visit với từng trang ngay khi
+// lấy được, không giữ lại items của các trang trước.
+//
+// Khi quota của X API hết, WalkPages chờ tới lúc reset nếu còn kịp trước Deadline.
+// Nếu hết MaxItems, tới Deadline, hết rate limit hoặc X API tạm thời lỗi sau trang đầu,
+// WalkPages dừng và trả về meta với stop_reason và next_token là cursor để gọi tiếp.
+// Trang vượt MaxItems được cắt bớt để không trả về quá MaxItems items; khi đó next_token
+// là cursor dạng "<skip>_<token>" mà chỉ WalkPages đọc được (xem SplitCursor).
+// Lỗi ở trang đầu, lỗi của visit và các lỗi khác được trả về nguyên vẹn.
+func WalkPages[R any, T any](ctx context.Context, opts PaginateOptions, paginationToken string, fetch PageFetcher[R], page func(*R) (*[]T, **models.Meta), visit func(resp *R, items []T) error) (*models.Meta, error) {
+	meta := &models.Meta{}
+	token, skip := SplitCursor(paginationToken)
+
+	for {
+		if meta.Pages > 0 {
+			if meta.ResultCount >= opts.MaxItems {
+				meta.StopReason = StopReasonMaxItems
+				break
+			}
+			if !time.Now().Before(opts.Deadline) {
+				meta.StopReason = StopReasonDeadline
+				break
+			}
+		}
+
+		// Trang đầu của cursor gọi tiếp lấy lại cả skip items đã trả về lần trước
+		remaining := opts.MaxItems - meta.ResultCount
+		maxResults := remaining + skip
+		if opts.PageSize > 0 {
+			maxResults = min(maxResults, opts.PageSize)
+		}
+
+		pageCtx, cancel := context.WithDeadline(ctx, opts.Deadline)
+		resp, err := fetch(pageCtx, maxResults, token)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if wait, ok := rateLimitWait(err, opts.Deadline); ok {
+				log.WithFields(log.Fields{
+					"pages":   meta.Pages,
+					"wait_ms": wait.Milliseconds(),
+				}).Info("Auto-pagination chờ rate limit của X API reset")
+				if err := sleepContext(ctx, wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			meta.StopReason = paginationStopReason(err, opts.Deadline)
+			if meta.Pages == 0 || meta.StopReason == "" {
+				return nil, err
+			}
+			break
+		}
+
+		pageItems, pageMeta := page(resp)
+		if meta.Pages == 0 && *pageMeta != nil {
+			meta.PreviousToken = (*pageMeta).PreviousToken
+		}
+		items := (*pageItems)[min(skip, len(*pageItems)):]
+		trimmed := len(items) > remaining
+		if trimmed {
+			items = items[:remaining]
+		}
+		if err := visit(resp, items); err != nil {
+			return nil, err
+		}
+		meta.ResultCount += len(items)
+		meta.Pages++
+
+		if trimmed {
+			// Trang có nhiều items hơn max_results (max_results nhỏ hơn mức tối thiểu
+			// của endpoint): cursor gọi tiếp trỏ vào giữa trang này
+			meta.StopReason = StopReasonMaxItems
+			token = resumeCursor(token, skip+len(items))
+			break
+		}
+		skip = 0
+		token = ""
+		if *pageMeta != nil {
+			token = (*pageMeta).NextToken
+		}
+		if token == "" {
+			break
+		}
+	}
+
+	if meta.StopReason != "" {
+		meta.NextToken = token
+		log.WithFields(log.Fields{
+			"pages":       meta.Pages,
+			"items":       meta.ResultCount,
+			"stop_reason": meta.StopReason,
+		}).Info("Auto-pagination dừng trước khi hết dữ liệu")
+	}
+	return meta, nil
+}
+
+// resumeCursor là cursor gọi tiếp sau trang bị cắt bớt vì max_items: bỏ qua skip items
+// đầu của trang bắt đầu từ pagination token. Pagination token của X API không chứa '_'
+// nên không nhầm với token thông thường.
+func resumeCursor(token string, skip int) string {
+	return strconv.Itoa(skip) + "_" + token
+}
+
+// SplitCursor tách cursor do auto-pagination trả về thành pagination token của X API
+// và số items đầu trang đã trả về trước đó (0 với pagination token thông thường)
+func SplitCursor(cursor string) (string, int) {
+	skipStr, token, ok := strings.Cut(cursor, "_")
+	if !ok {
+		return cursor, 0
+	}
+	skip, err := strconv.Atoi(skipStr)
+	if err != nil || skip < 1 {
+		return cursor, 0
+	}
+	return token, skip
+}
+
+// rateLimitWait trả về thời gian cần chờ nếu err là rate limit và quota reset trước deadline
+func rateLimitWait(err error, deadline time.Time) (time.Duration, bool) {
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Kind != ErrorKindRateLimited || apiErr.RetryAfter <= 0 {
+		return 0, false
+	}
+	return apiErr.RetryAfter, time.Now().Add(apiErr.RetryAfter).Before(deadline)
+}
+
+// paginationStopReason phân loại lỗi cho phép dừng sớm và trả về cursor; rỗng nếu
+// lỗi phải được trả nguyên vẹn cho client
+func paginationStopReason(err error, deadline time.Time) string {
+	if !time.Now().Before(deadline) {
+		return StopReasonDeadline
+	}
+	apiErr, ok := AsAPIError(err)
+	if !ok {
+		return ""
+	}
+	switch apiErr.Kind {
+	case ErrorKindRateLimited:
+		return StopReasonRateLimited
+	case ErrorKindUnavailable, ErrorKindTimeout:
+		return StopReasonUnavailable
+	}
+	return ""
+}
+
+// sleepContext chờ d hoặc tới khi ctx bị hủy
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
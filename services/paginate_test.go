@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// newPaginateFake seed alice với n tweets
+func newPaginateFake(n int) *FakeTwitterService {
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		fake.AddTweet(models.Tweet{ID: fmt.Sprint(1000 + i), AuthorID: "1", CreatedAt: created.Add(time.Duration(i) * time.Minute)})
+	}
+	return fake
+}
+
+func tweetsPage(r *models.TweetsResponse) (*[]models.Tweet, **models.Meta) {
+	return &r.Tweets, &r.Meta
+}
+
+func userTweetsFetcher(api TwitterAPI, calls *int) PageFetcher[models.TweetsResponse] {
+	return func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		*calls++
+		return api.GetUserTweets(ctx, "alice", min(maxResults, 3), token, TweetFilter{})
+	}
+}
+
+func TestPaginateMergesAllPages(t *testing.T) {
+	fake := newPaginateFake(7)
+	calls := 0
+	opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(time.Second)}
+
+	resp, err := Paginate(context.Background(), opts, "", userTweetsFetcher(fake, &calls), tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Tweets) != 7 || calls != 3 {
+		t.Fatalf("tweets = %d, calls = %d; want 7, 3", len(resp.Tweets), calls)
+	}
+	if resp.User == nil || resp.User.Username != "alice" {
+		t.Errorf("User = %+v, want alice từ trang đầu", resp.User)
+	}
+	want := models.Meta{ResultCount: 7, Pages: 3}
+	if *resp.Meta != want {
+		t.Errorf("Meta = %+v, want %+v", *resp.Meta, want)
+	}
+}
+
+func TestPaginateMaxItemsResumableCursor(t *testing.T) {
+	fake := newPaginateFake(7)
+	calls := 0
+	opts := PaginateOptions{MaxItems: 4, Deadline: time.Now().Add(time.Second)}
+
+	first, err := Paginate(context.Background(), opts, "", userTweetsFetcher(fake, &calls), tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Tweets) != 4 || first.Meta.StopReason != StopReasonMaxItems || first.Meta.NextToken == "" {
+		t.Fatalf("trang đầu: %d tweets, meta = %+v", len(first.Tweets), first.Meta)
+	}
+
+	// Gọi tiếp từ cursor lấy đúng phần còn lại, không trùng và không sót
+	rest, err := Paginate(context.Background(), opts, first.Meta.NextToken, userTweetsFetcher(fake, &calls), tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest.Tweets) != 3 || rest.Meta.StopReason != "" || rest.Meta.NextToken != "" {
+		t.Fatalf("phần còn lại: %d tweets, meta = %+v", len(rest.Tweets), rest.Meta)
+	}
+	seen := map[string]bool{}
+	for _, tweet := range append(first.Tweets, rest.Tweets...) {
+		if seen[tweet.ID] {
+			t.Errorf("tweet %s bị trùng", tweet.ID)
+		}
+		seen[tweet.ID] = true
+	}
+}
+
+func TestPaginateWaitsForRateLimitReset(t *testing.T) {
+	fake := newPaginateFake(5)
+	calls := 0
+	fetch := userTweetsFetcher(fake, &calls)
+	limited := false
+	opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(time.Second)}
+
+	resp, err := Paginate(context.Background(), opts, "", func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		if token != "" && !limited {
+			limited = true
+			return nil, &APIError{Kind: ErrorKindRateLimited, Code: CodeRateLimited, RetryAfter: 20 * time.Millisecond}
+		}
+		return fetch(ctx, maxResults, token)
+	}, tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Tweets) != 5 || resp.Meta.StopReason != "" {
+		t.Errorf("tweets = %d, meta = %+v; want 5 tweets đầy đủ", len(resp.Tweets), resp.Meta)
+	}
+}
+
+func TestPaginateStopsEarly(t *testing.T) {
+	fake := newPaginateFake(9)
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rate limit reset sau deadline", &APIError{Kind: ErrorKindRateLimited, Code: CodeRateLimited, RetryAfter: time.Hour}, StopReasonRateLimited},
+		{"upstream unavailable", &APIError{Kind: ErrorKindUnavailable, Code: CodeUpstreamUnavailable}, StopReasonUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			fetch := userTweetsFetcher(fake, &calls)
+			var failedToken string
+			opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(time.Second)}
+
+			resp, err := Paginate(context.Background(), opts, "", func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+				if calls == 2 {
+					failedToken = token
+					return nil, tc.err
+				}
+				return fetch(ctx, maxResults, token)
+			}, tweetsPage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Tweets) != 6 || resp.Meta.StopReason != tc.want || resp.Meta.NextToken != failedToken {
+				t.Errorf("tweets = %d, meta = %+v; want 6 tweets, %s, cursor %q", len(resp.Tweets), resp.Meta, tc.want, failedToken)
+			}
+		})
+	}
+}
+
+func TestPaginateDeadline(t *testing.T) {
+	fake := newPaginateFake(9)
+	calls := 0
+	fetch := userTweetsFetcher(fake, &calls)
+	opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(30 * time.Millisecond)}
+
+	resp, err := Paginate(context.Background(), opts, "", func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		if token != "" {
+			// Trang sau chậm hơn deadline: context của trang bị hủy
+			<-ctx.Done()
+			return nil, wrapUpstreamError(ctx.Err(), "không thể lấy tweets")
+		}
+		return fetch(ctx, maxResults, token)
+	}, tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Tweets) != 3 || resp.Meta.StopReason != StopReasonDeadline || resp.Meta.NextToken != "3" {
+		t.Errorf("tweets = %d, meta = %+v; want 3 tweets, deadline, cursor 3", len(resp.Tweets), resp.Meta)
+	}
+}
+
+func TestPaginateFirstPageError(t *testing.T) {
+	fake := newPaginateFake(5)
+	injected := &APIError{Kind: ErrorKindUnavailable, Code: CodeUpstreamUnavailable}
+	fake.SetError("GetUserTweets", injected)
+	calls := 0
+	opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(time.Second)}
+
+	if _, err := Paginate(context.Background(), opts, "", userTweetsFetcher(fake, &calls), tweetsPage); !errors.Is(err, injected) {
+		t.Errorf("err = %v, want lỗi của trang đầu", err)
+	}
+}
+
+func TestPaginateDoesNotMutateSharedResponse(t *testing.T) {
+	shared := &models.TweetsResponse{
+		Tweets: []models.Tweet{{ID: "1"}, {ID: "2"}},
+		Meta:   &models.Meta{ResultCount: 2, NextToken: "next"},
+	}
+	second := &models.TweetsResponse{Tweets: []models.Tweet{{ID: "3"}}, Meta: &models.Meta{ResultCount: 1}}
+	opts := PaginateOptions{MaxItems: 100, Deadline: time.Now().Add(time.Second)}
+
+	_, err := Paginate(context.Background(), opts, "", func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		if token == "" {
+			return shared, nil
+		}
+		return second, nil
+	}, tweetsPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shared.Tweets) != 2 || shared.Meta.NextToken != "next" {
+		t.Errorf("response trang đầu bị sửa: %+v, %+v", shared.Tweets, shared.Meta)
+	}
+}
+
+func TestPaginateTrimsPagesAboveMaxItems(t *testing.T) {
+	fake := newPaginateFake(12)
+	// Giống timeline của X API: max_results nhỏ hơn 5 bị bỏ qua
+	fetch := func(ctx context.Context, maxResults int, token string) (*models.TweetsResponse, error) {
+		return fake.GetUserTweets(ctx, "alice", max(maxResults, 5), token, TweetFilter{})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     PaginateOptions
+		wantNext string
+	}{
+		{"max_items nhỏ hơn trang tối thiểu", PaginateOptions{MaxItems: 3}, "3_"},
+		{"max_items giữa hai kích thước trang", PaginateOptions{MaxItems: 7, PageSize: 5}, "2_5"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Deadline = time.Now().Add(time.Second)
+			first, err := Paginate(context.Background(), tc.opts, "", fetch, tweetsPage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(first.Tweets) != tc.opts.MaxItems || first.Meta.ResultCount != tc.opts.MaxItems ||
+				first.Meta.StopReason != StopReasonMaxItems || first.Meta.NextToken != tc.wantNext {
+				t.Fatalf("%d tweets, meta = %+v; want %d tweets, cursor %q", len(first.Tweets), first.Meta, tc.opts.MaxItems, tc.wantNext)
+			}
+
+			// Đi theo cursor tới hết: mỗi tweet xuất hiện đúng một lần
+			seen := map[string]bool{}
+			resp, cursor := first, first.Meta.NextToken
+			for {
+				for _, tweet := range resp.Tweets {
+					if seen[tweet.ID] {
+						t.Fatalf("tweet %s bị trùng", tweet.ID)
+					}
+					seen[tweet.ID] = true
+				}
+				if cursor == "" {
+					break
+				}
+				if resp, err = Paginate(context.Background(), tc.opts, cursor, fetch, tweetsPage); err != nil {
+					t.Fatal(err)
+				}
+				if len(resp.Tweets) > tc.opts.MaxItems {
+					t.Fatalf("%d tweets vượt max_items %d", len(resp.Tweets), tc.opts.MaxItems)
+				}
+				cursor = resp.Meta.NextToken
+			}
+			if len(seen) != 12 {
+				t.Errorf("lấy được %d tweets, want 12", len(seen))
+			}
+		})
+	}
+}
+
+func TestSplitCursor(t *testing.T) {
+	for _, tc := range []struct {
+		cursor, token string
+		skip          int
+	}{
+		{"", "", 0},
+		{"7140dibdnow9c7btw3w29grvxfcgvpb9n9coehpk7xz5i", "7140dibdnow9c7btw3w29grvxfcgvpb9n9coehpk7xz5i", 0},
+		{"3_", "", 3},
+		{"2_b26v89c19zqg8o3fp", "b26v89c19zqg8o3fp", 2},
+		{"0_abc", "0_abc", 0},
+		{"x_abc", "x_abc", 0},
+	} {
+		if token, skip := SplitCursor(tc.cursor); token != tc.token || skip != tc.skip {
+			t.Errorf("SplitCursor(%q) = %q, %d; want %q, %d", tc.cursor, token, skip, tc.token, tc.skip)
+		}
+	}
+}
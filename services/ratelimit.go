@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers rate limit mà X API v2 trả về trên mỗi response
+const (
+	headerRateLimitLimit     = "x-rate-limit-limit"
+	headerRateLimitRemaining = "x-rate-limit-remaining"
+	headerRateLimitReset     = "x-rate-limit-reset"
+)
+
+// numericSegment khớp các path segment là ID (user ID, tweet ID)
+var numericSegment = regexp.MustCompile(`^[0-9]+$`)
+
+// rateLimitWindow là trạng thái rate limit đã biết của một endpoint family
+type rateLimitWindow struct {
+	limit     int
+	remaining int
+	resetAt   time.Time
+	updatedAt time.Time
+}
+
+// RateLimitRegistry lưu quota còn lại của X API theo endpoint family
+// (ví dụ "GET /2/users/{id}/tweets"), được cập nhật từ x-rate-limit-* headers
+type RateLimitRegistry struct {
+	now func() time.Time
+
+	mu      sync.RWMutex
+	windows map[string]*rateLimitWindow
+}
+
+// NewRateLimitRegistry tạo một registry rỗng
+func NewRateLimitRegistry() *RateLimitRegistry {
+	return &RateLimitRegistry{now: time.Now, windows: make(map[string]*rateLimitWindow)}
+}
+
+// Update ghi nhận trạng thái rate limit mới nhất của family
+func (r *RateLimitRegistry) Update(family string, limit, remaining int, resetAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.windows[family] = &rateLimitWindow{
+		limit:     limit,
+		remaining: remaining,
+		resetAt:   resetAt,
+		updatedAt: r.now(),
+	}
+}
+
+// Exhausted trả về thời điểm reset nếu quota của family đã hết và cửa sổ chưa reset
+func (r *RateLimitRegistry) Exhausted(family string) (time.Time, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.windows[family]
+	if !ok || w.remaining > 0 || !r.now().Before(w.resetAt) {
+		return time.Time{}, false
+	}
+	return w.resetAt, true
+}
+
+// quota trả về số request còn lại của family; known=false nếu chưa có response nào
+// của family hoặc cửa sổ đã reset (khi đó coi như còn đủ quota)
+func (r *RateLimitRegistry) quota(family string) (remaining int, resetAt time.Time, known bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.windows[family]
+	if !ok || !r.now().Before(w.resetAt) {
+		return 0, time.Time{}, false
+	}
+	return w.remaining, w.resetAt, true
+}
+
+// Snapshot trả về trạng thái rate limit của tất cả families đã biết, sắp xếp theo tên.
+// Family đã qua thời điểm reset được coi như còn đủ quota.
+func (r *RateLimitRegistry) Snapshot() []models.RateLimitStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := r.now()
+	statuses := make([]models.RateLimitStatus, 0, len(r.windows))
+	for family, w := range r.windows {
+		status := models.RateLimitStatus{
+			Endpoint:  family,
+			Limit:     w.limit,
+			Remaining: w.remaining,
+			ResetAt:   w.resetAt.UTC(),
+			UpdatedAt: w.updatedAt.UTC(),
+		}
+		if now.Before(w.resetAt) {
+			status.ResetInSeconds = int(w.resetAt.Sub(now).Round(time.Second) / time.Second)
+			status.Exhausted = w.remaining <= 0
+		} else {
+			status.Remaining = w.limit
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
+
+// mergeRateLimits gộp snapshot của nhiều registry (mỗi Bearer Token một registry)
+// theo endpoint family: limit và remaining được cộng dồn, family chỉ hết quota khi
+// mọi registry đều hết, reset_at là lúc sớm nhất có quota trở lại
+func mergeRateLimits(registries ...*RateLimitRegistry) []models.RateLimitStatus {
+	merged := make(map[string]*models.RateLimitStatus)
+	var order []string
+	for _, registry := range registries {
+		for _, status := range registry.Snapshot() {
+			current, ok := merged[status.Endpoint]
+			if !ok {
+				status := status
+				merged[status.Endpoint] = &status
+				order = append(order, status.Endpoint)
+				continue
+			}
+			current.Limit += status.Limit
+			current.Remaining += status.Remaining
+			current.Exhausted = current.Exhausted && status.Exhausted
+			if status.ResetInSeconds > 0 && (current.ResetInSeconds == 0 || status.ResetAt.Before(current.ResetAt)) {
+				current.ResetAt = status.ResetAt
+				current.ResetInSeconds = status.ResetInSeconds
+			}
+			if status.UpdatedAt.After(current.UpdatedAt) {
+				current.UpdatedAt = status.UpdatedAt
+			}
+		}
+	}
+
+	sort.Strings(order)
+	statuses := make([]models.RateLimitStatus, 0, len(order))
+	for _, family := range order {
+		statuses = append(statuses, *merged[family])
+	}
+	return statuses
+}
+
+// registerRateLimitMetrics xuất quota còn lại (đã gộp mọi Bearer Token) ra /metrics
+func registerRateLimitMetrics(snapshot func() []models.RateLimitStatus) {
+	metrics.RegisterGaugeFunc("x_api_rate_limit_remaining",
+		"Số request còn lại trong cửa sổ rate limit hiện tại theo endpoint family của X API",
+		func() []metrics.Sample {
+			statuses := snapshot()
+			samples := make([]metrics.Sample, 0, len(statuses))
+			for _, s := range statuses {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"endpoint": s.Endpoint},
+					Value:  float64(s.Remaining),
+				})
+			}
+			return samples
+		})
+}
+
+// endpointFamily chuẩn hóa request thành endpoint family, thay các ID và username
+// trong path bằng placeholder: GET /2/users/123/tweets -> GET /2/users/{id}/tweets
+func endpointFamily(method, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments {
+		switch {
+		case i >= 2 && segments[i-2] == "by" && segments[i-1] == "username":
+			segments[i] = "{username}"
+		case i > 0 && numericSegment.MatchString(seg): // segment đầu là version "2"
+			segments[i] = "{id}"
+		}
+	}
+	return method + " /" + strings.Join(segments, "/")
+}
+
+// rateLimitTransport đọc x-rate-limit-* headers của mỗi response vào registry và
+// trả lỗi RATE_LIMITED ngay (không gọi X API) khi quota của family đã hết
+type rateLimitTransport struct {
+	registry *RateLimitRegistry
+	next     http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Rate limit của token end user tính riêng cho từng user, không dùng registry của app
+	if _, ok := userTokenFromContext(req.Context()); ok {
+		return t.next.RoundTrip(req)
+	}
+
+	family := endpointFamily(req.Method, req.URL.Path)
+
+	if resetAt, exhausted := t.registry.Exhausted(family); exhausted {
+		log.WithFields(log.Fields{
+			"endpoint": family,
+			"reset_at": resetAt.UTC().Format(time.RFC3339),
+		}).Warn("Rate limit của X API đã hết, bỏ qua request")
+		return nil, &APIError{
+			Kind:       ErrorKindRateLimited,
+			Code:       CodeRateLimited,
+			Message:    fmt.Sprintf("rate limit của X API cho %s đã hết", family),
+			RetryAfter: resetAt.Sub(t.registry.now()),
+		}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, remaining, resetAt, ok := parseRateLimitHeaders(res.Header); ok {
+		t.registry.Update(family, limit, remaining, resetAt)
+		if remaining == 0 {
+			log.WithFields(log.Fields{
+				"endpoint": family,
+				"limit":    limit,
+				"reset_at": resetAt.UTC().Format(time.RFC3339),
+			}).Warn("Đã dùng hết rate limit của X API")
+		}
+	}
+
+	return res, nil
+}
+
+// parseRateLimitHeaders đọc x-rate-limit-limit/remaining/reset; ok=false nếu thiếu header
+func parseRateLimitHeaders(h http.Header) (limit, remaining int, resetAt time.Time, ok bool) {
+	limit, err := strconv.Atoi(h.Get(headerRateLimitLimit))
+	if err != nil {
+		return 0, 0, time.Time{}, false
+	}
+	remaining, err = strconv.Atoi(h.Get(headerRateLimitRemaining))
+	if err != nil {
+		return 0, 0, time.Time{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return 0, 0, time.Time{}, false
+	}
+	return limit, remaining, time.Unix(reset, 0), true
+}
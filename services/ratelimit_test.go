@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEndpointFamily(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"GET", "/2/users/by/username/alice", "GET /2/users/by/username/{username}"},
+		{"GET", "/2/users/by/username/12345", "GET /2/users/by/username/{username}"},
+		{"GET", "/2/users/by", "GET /2/users/by"},
+		{"GET", "/2/users/123/tweets", "GET /2/users/{id}/tweets"},
+		{"GET", "/2/users/123/", "GET /2/users/{id}"},
+		{"GET", "/2/tweets/1234567890123456789/liking_users", "GET /2/tweets/{id}/liking_users"},
+		{"PUT", "/2/tweets/42/hidden", "PUT /2/tweets/{id}/hidden"},
+		{"GET", "/2/tweets/search/recent", "GET /2/tweets/search/recent"},
+		{"GET", "/2/users/me", "GET /2/users/me"},
+	}
+	for _, tt := range tests {
+		if got := endpointFamily(tt.method, tt.path); got != tt.want {
+			t.Errorf("endpointFamily(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func rateLimitHeader(limit, remaining int, resetAt time.Time) http.Header {
+	h := http.Header{}
+	h.Set(headerRateLimitLimit, strconv.Itoa(limit))
+	h.Set(headerRateLimitRemaining, strconv.Itoa(remaining))
+	h.Set(headerRateLimitReset, strconv.FormatInt(resetAt.Unix(), 10))
+	return h
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	now := time.Unix(1704110400, 0)
+	registry := NewRateLimitRegistry()
+	registry.now = func() time.Time { return now }
+	resetAt := now.Add(time.Minute)
+
+	calls := 0
+	header := rateLimitHeader(15, 0, resetAt)
+	transport := &rateLimitTransport{
+		registry: registry,
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return stubResponse(http.StatusOK, header), nil
+		}),
+	}
+	roundTrip := func(ctx context.Context, path string) error {
+		req := httptest.NewRequest("GET", "https://api.twitter.com"+path, nil).WithContext(ctx)
+		_, err := transport.RoundTrip(req)
+		return err
+	}
+	ctx := context.Background()
+
+	// Response dùng hết quota được trả về bình thường và ghi vào registry
+	if err := roundTrip(ctx, "/2/users/123/followers"); err != nil || calls != 1 {
+		t.Fatalf("request đầu: err = %v, calls = %d", err, calls)
+	}
+	if _, exhausted := registry.Exhausted("GET /2/users/{id}/followers"); !exhausted {
+		t.Fatal("family phải hết quota sau response remaining=0")
+	}
+
+	// Cùng family (ID khác) bị chặn trước khi tới X API
+	err := roundTrip(ctx, "/2/users/456/followers")
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Code != CodeRateLimited || apiErr.Kind != ErrorKindRateLimited || apiErr.RetryAfter != time.Minute {
+		t.Fatalf("err = %v, want RATE_LIMITED với RetryAfter 1m", err)
+	}
+	if calls != 1 {
+		t.Errorf("request bị chặn vẫn tới X API: calls = %d", calls)
+	}
+
+	// Family khác không bị ảnh hưởng
+	header = rateLimitHeader(900, 899, resetAt)
+	if err := roundTrip(ctx, "/2/users/123/following"); err != nil || calls != 2 {
+		t.Errorf("family khác: err = %v, calls = %d", err, calls)
+	}
+
+	// Token của end user có rate limit riêng: không bị chặn và không ghi vào registry
+	userCtx := WithUserToken(ctx, UserToken{AccessToken: "user-token"})
+	header = rateLimitHeader(15, 14, resetAt)
+	if err := roundTrip(userCtx, "/2/users/123/followers"); err != nil || calls != 3 {
+		t.Errorf("user token: err = %v, calls = %d", err, calls)
+	}
+	if _, exhausted := registry.Exhausted("GET /2/users/{id}/followers"); !exhausted {
+		t.Error("response của user token không được ghi đè registry của app")
+	}
+
+	// Hết cửa sổ rate limit: request được gửi lại và registry cập nhật quota mới
+	now = resetAt
+	header = rateLimitHeader(15, 14, resetAt.Add(15*time.Minute))
+	if err := roundTrip(ctx, "/2/users/456/followers"); err != nil || calls != 4 {
+		t.Fatalf("sau reset: err = %v, calls = %d", err, calls)
+	}
+	if _, exhausted := registry.Exhausted("GET /2/users/{id}/followers"); exhausted {
+		t.Error("family vẫn hết quota sau khi reset")
+	}
+	if got := registry.Snapshot(); len(got) != 2 || got[0].Endpoint != "GET /2/users/{id}/followers" || got[0].Remaining != 14 {
+		t.Errorf("Snapshot = %+v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisOptions cấu hình kết nối tới server nói Redis protocol (Redis, Valkey, KeyDB...)
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix được thêm trước mọi key để nhiều ứng dụng dùng chung một Redis
+	KeyPrefix string
+	// Timeout cho mỗi lệnh (dial, ghi và đọc reply) khi ctx không có deadline sớm hơn
+	Timeout time.Duration
+	// PoolSize là số connection rảnh tối đa được giữ lại
+	PoolSize int
+}
+
+// redisError là error reply (-ERR ...) từ server; connection vẫn dùng tiếp được
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// RedisCacheStore là CacheStore dùng chung giữa các replicas qua Redis protocol (RESP2).
+// Client tối giản chỉ dùng GET, SET PX, DEL, PING, AUTH và SELECT.
+type RedisCacheStore struct {
+	opts RedisOptions
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	wr   *bufio.Writer
+}
+
+// NewRedisCacheStore tạo RedisCacheStore; connection được mở khi cần
+func NewRedisCacheStore(opts RedisOptions) *RedisCacheStore {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 500 * time.Millisecond
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	return &RedisCacheStore{opts: opts, pool: make(chan *redisConn, opts.PoolSize)}
+}
+
+func (s *RedisCacheStore) Backend() string {
+	return CacheBackendRedis
+}
+
+// Ping kiểm tra kết nối tới Redis
+func (s *RedisCacheStore) Ping(ctx context.Context) error {
+	_, err := s.do(ctx, "PING")
+	return err
+}
+
+func (s *RedisCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := s.do(ctx, "GET", s.opts.KeyPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: GET trả về kiểu không hợp lệ %T", reply)
+	}
+	return value, true, nil
+}
+
+func (s *RedisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		return nil
+	}
+	_, err := s.do(ctx, "SET", s.opts.KeyPrefix+key, string(value), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+func (s *RedisCacheStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.opts.KeyPrefix+key)
+	return err
+}
+
+// Close đóng các connection rảnh trong pool
+func (s *RedisCacheStore) Close() error {
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do gửi một lệnh và đọc reply. Connection lỗi I/O bị đóng thay vì trả về pool.
+func (s *RedisCacheStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(s.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	c, err := s.getConn(ctx, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(deadline, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	s.putConn(c)
+	return reply, err
+}
+
+func (s *RedisCacheStore) getConn(ctx context.Context, deadline time.Time) (*redisConn, error) {
+	select {
+	case c := <-s.pool:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", s.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn), wr: bufio.NewWriter(conn)}
+
+	if s.opts.Password != "" {
+		if _, err := c.do(deadline, "AUTH", s.opts.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.opts.DB != 0 {
+		if _, err := c.do(deadline, "SELECT", strconv.Itoa(s.opts.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (s *RedisCacheStore) putConn(c *redisConn) {
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+func (c *redisConn) do(deadline time.Time, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if err := writeRESPCommand(c.wr, args); err != nil {
+		return nil, err
+	}
+	if err := c.wr.Flush(); err != nil {
+		return nil, err
+	}
+	return readRESPReply(c.rd)
+}
+
+// writeRESPCommand ghi lệnh dạng array of bulk strings
+func writeRESPCommand(w *bufio.Writer, args []string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n", len(arg))
+		w.WriteString(arg)
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRESPReply đọc một reply RESP2: simple string -> string, integer -> int64,
+// bulk string -> []byte (nil nếu null), array -> []interface{}, error -> redisError
+func readRESPReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readRESPLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: reply rỗng")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: độ dài bulk string không hợp lệ: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: độ dài array không hợp lệ: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRESPReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: kiểu reply không hỗ trợ: %q", line)
+	}
+}
+
+// readRESPLine đọc một dòng kết thúc bằng \r\n (không gồm \r\n)
+func readRESPLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: dòng không kết thúc bằng CRLF: %q", line)
+	}
+	return line[:len(line)-2], nil
+}
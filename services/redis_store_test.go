@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// testRedisServer là Redis stand-in chạy trong process, hỗ trợ đủ lệnh mà
+// RedisCacheStore dùng (PING, AUTH, SELECT, GET, SET [PX|EX], DEL)
+type testRedisServer struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]testRedisEntry // key dạng "<db>/<key>"
+}
+
+type testRedisEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func newTestRedisServer(t *testing.T, password string) *testRedisServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testRedisServer{ln: ln, password: password, data: make(map[string]testRedisEntry)}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testRedisServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *testRedisServer) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *testRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	wr := bufio.NewWriter(conn)
+	authed := s.password == ""
+	db := 0
+
+	for {
+		reply, err := readRESPReply(rd)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				authed = true
+				wr.WriteString("+OK\r\n")
+			} else {
+				wr.WriteString("-WRONGPASS invalid password\r\n")
+			}
+		case !authed:
+			wr.WriteString("-NOAUTH Authentication required.\r\n")
+		case cmd == "PING":
+			wr.WriteString("+PONG\r\n")
+		case cmd == "SELECT":
+			db, _ = strconv.Atoi(args[1])
+			wr.WriteString("+OK\r\n")
+		case cmd == "GET":
+			if value, ok := s.get(db, args[1]); ok {
+				wr.WriteString("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")
+			} else {
+				wr.WriteString("$-1\r\n")
+			}
+		case cmd == "SET":
+			entry := testRedisEntry{value: args[2]}
+			if len(args) == 5 {
+				n, _ := strconv.Atoi(args[4])
+				unit := time.Millisecond
+				if strings.ToUpper(args[3]) == "EX" {
+					unit = time.Second
+				}
+				entry.expiresAt = time.Now().Add(time.Duration(n) * unit)
+			}
+			s.mu.Lock()
+			s.data[strconv.Itoa(db)+"/"+args[1]] = entry
+			s.mu.Unlock()
+			wr.WriteString("+OK\r\n")
+		case cmd == "DEL":
+			s.mu.Lock()
+			_, ok := s.data[strconv.Itoa(db)+"/"+args[1]]
+			delete(s.data, strconv.Itoa(db)+"/"+args[1])
+			s.mu.Unlock()
+			if ok {
+				wr.WriteString(":1\r\n")
+			} else {
+				wr.WriteString(":0\r\n")
+			}
+		default:
+			wr.WriteString("-ERR unknown command '" + args[0] + "'\r\n")
+		}
+		if err := wr.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *testRedisServer) get(db int, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.data[strconv.Itoa(db)+"/"+key]
+	if !ok {
+		return "", false
+	}
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		delete(s.data, strconv.Itoa(db)+"/"+key)
+		return "", false
+	}
+	return entry.value, true
+}
+
+func TestRedisCacheStoreGetSetDelete(t *testing.T) {
+	server := newTestRedisServer(t, "secret")
+	store := NewRedisCacheStore(RedisOptions{Addr: server.Addr(), Password: "secret", DB: 2, KeyPrefix: "test:"})
+	defer store.Close()
+	ctx := context.Background()
+
+	if err := store.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if _, ok, err := store.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Get(missing) = %v, %v; want miss", ok, err)
+	}
+
+	value := []byte("{\"text\":\"xin chào\\r\\n\"}")
+	if err := store.Set(ctx, "k", value, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, ok, err := store.Get(ctx, "k")
+	if err != nil || !ok || string(got) != string(value) {
+		t.Errorf("Get(k) = %q, %v, %v; want %q", got, ok, err, value)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "2/test:k" {
+		t.Errorf("keys = %v, want [2/test:k]", keys)
+	}
+
+	if err := store.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Error("key vẫn còn sau Delete")
+	}
+}
+
+func TestRedisCacheStoreTTL(t *testing.T) {
+	server := newTestRedisServer(t, "")
+	store := NewRedisCacheStore(RedisOptions{Addr: server.Addr()})
+	defer store.Close()
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "k", []byte("v"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok, _ := store.Get(ctx, "k"); ok {
+		t.Error("key chưa hết hạn sau TTL")
+	}
+}
+
+func TestRedisCacheStoreAuthError(t *testing.T) {
+	server := newTestRedisServer(t, "secret")
+	store := NewRedisCacheStore(RedisOptions{Addr: server.Addr(), Password: "wrong"})
+	defer store.Close()
+
+	if err := store.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Ping = %v, want WRONGPASS", err)
+	}
+}
+
+func TestCachedServiceSharedRedisAcrossReplicas(t *testing.T) {
+	server := newTestRedisServer(t, "")
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+
+	// Hai replicas dùng chung Redis: replica thứ hai đọc response replica đầu đã lưu
+	replicaA := newTestCachedServiceWithStore(counting, NewRedisCacheStore(RedisOptions{Addr: server.Addr()}))
+	replicaB := newTestCachedServiceWithStore(counting, NewRedisCacheStore(RedisOptions{Addr: server.Addr()}))
+	ctx := context.Background()
+
+	if _, err := replicaA.GetUserTweets(ctx, "alice", 10, "", TweetFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	rec := &CacheRecorder{}
+	resp, err := replicaB.GetUserTweets(WithCacheRecorder(ctx, rec), "alice", 10, "", TweetFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.userTweetsCalls != 1 || rec.Status() != CacheHit {
+		t.Errorf("upstream calls = %d, status = %q; want 1, HIT", counting.userTweetsCalls, rec.Status())
+	}
+	if resp.User == nil || resp.User.Username != "alice" {
+		t.Errorf("response từ Redis = %+v", resp)
+	}
+}
+
+func TestCachedServiceRedisUnavailable(t *testing.T) {
+	// Listener đã đóng: mọi lệnh Redis lỗi nhưng request vẫn thành công
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	fake := NewFakeTwitterService()
+	fake.AddUser(models.User{ID: "1", Username: "alice"})
+	counting := &countingTwitterAPI{TwitterAPI: fake}
+	svc := newTestCachedServiceWithStore(counting, NewRedisCacheStore(RedisOptions{Addr: addr, Timeout: 100 * time.Millisecond}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetUserTweets(context.Background(), "alice", 10, "", TweetFilter{}); err != nil {
+			t.Fatalf("GetUserTweets khi Redis lỗi: %v", err)
+		}
+	}
+	if counting.userTweetsCalls != 2 {
+		t.Errorf("upstream calls = %d, want 2", counting.userTweetsCalls)
+	}
+}
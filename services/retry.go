@@ -0,0 +1,146 @@
+package services
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// retryTransport thử lại các GET request khi X API trả về 429/5xx hoặc lỗi network,
+// với exponential backoff có jitter, và không bao giờ chờ quá deadline của request
+type retryTransport struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	next        http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.maxAttempts <= 1 || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	family := endpointFamily(req.Method, req.URL.Path)
+
+	for attempt := 1; ; attempt++ {
+		res, err := t.next.RoundTrip(req.Clone(ctx))
+		if attempt >= t.maxAttempts || !t.retryable(req, res, err) {
+			if attempt > 1 {
+				log.WithFields(log.Fields{
+					"endpoint": family,
+					"attempts": attempt,
+					"status":   statusOf(res),
+				}).Info("Kết thúc retry request tới X API")
+			}
+			return res, err
+		}
+
+		delay, ok := t.delay(attempt, res)
+		fields := log.Fields{
+			"endpoint":     family,
+			"attempt":      attempt,
+			"max_attempts": t.maxAttempts,
+			"status":       statusOf(res),
+			"delay_ms":     delay.Milliseconds(),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+
+		if !ok {
+			log.WithFields(fields).Warn("Không retry vì X API yêu cầu chờ lâu hơn TWITTER_RETRY_MAX_DELAY")
+			return res, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			log.WithFields(fields).Warn("Không retry vì thời gian chờ vượt quá deadline của request")
+			return res, err
+		}
+
+		log.WithFields(fields).Warn("Request tới X API thất bại, sẽ thử lại")
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryable kiểm tra kết quả có nên thử lại không: lỗi network, 429 hoặc 5xx.
+// Lỗi có kiểu (ví dụ rate limit fail-fast) và context đã hủy thì không retry.
+func (t *retryTransport) retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		_, typed := AsAPIError(err)
+		return !typed
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay tính thời gian chờ trước lần thử tiếp theo: ưu tiên Retry-After, sau đó
+// x-rate-limit-reset (với 429), còn lại là exponential backoff với jitter.
+// Trả về false nếu thời gian chờ X API yêu cầu vượt quá maxDelay (không nên retry).
+func (t *retryTransport) delay(attempt int, res *http.Response) (time.Duration, bool) {
+	if res != nil {
+		if d, ok := retryAfter(res.Header); ok {
+			return d, d <= t.maxDelay
+		}
+		if res.StatusCode == http.StatusTooManyRequests {
+			if _, _, resetAt, ok := parseRateLimitHeaders(res.Header); ok {
+				// Cộng thêm 1s vì reset được làm tròn theo giây
+				d := max(time.Until(resetAt)+time.Second, 0)
+				return d, d <= t.maxDelay
+			}
+		}
+	}
+
+	backoff := t.baseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > t.maxDelay {
+		backoff = t.maxDelay
+	}
+	// Equal jitter: chờ trong khoảng [backoff/2, backoff]
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)), true
+}
+
+// retryAfter đọc header Retry-After dạng số giây hoặc HTTP date
+func retryAfter(h http.Header) (time.Duration, bool) {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// statusOf trả về status code của response, 0 nếu không có response
+func statusOf(res *http.Response) int {
+	if res == nil {
+		return 0
+	}
+	return res.StatusCode
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// roundTripFunc cho phép dùng một function làm http.RoundTripper trong tests
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func stubResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: http.NoBody}
+}
+
+func TestRetryTransportRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	transport := &retryTransport{
+		maxAttempts: 3,
+		baseDelay:   time.Millisecond,
+		maxDelay:    5 * time.Millisecond,
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls < 3 {
+				return stubResponse(http.StatusServiceUnavailable, nil), nil
+			}
+			return stubResponse(http.StatusOK, nil), nil
+		}),
+	}
+
+	res, err := transport.RoundTrip(httptest.NewRequest("GET", "https://api.twitter.com/2/tweets/1", nil))
+	if err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("RoundTrip = %v, %v; want 200", statusOf(res), err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryTransportGivesUpOnLongRetryAfter(t *testing.T) {
+	for _, header := range []http.Header{
+		{"Retry-After": []string{"900"}},
+		{
+			"X-Rate-Limit-Limit":     []string{"900"},
+			"X-Rate-Limit-Remaining": []string{"0"},
+			"X-Rate-Limit-Reset":     []string{strconv.FormatInt(time.Now().Add(15*time.Minute).Unix(), 10)},
+		},
+	} {
+		calls := 0
+		transport := &retryTransport{
+			maxAttempts: 3,
+			baseDelay:   time.Millisecond,
+			maxDelay:    time.Second,
+			next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return stubResponse(http.StatusTooManyRequests, header), nil
+			}),
+		}
+
+		start := time.Now()
+		res, err := transport.RoundTrip(httptest.NewRequest("GET", "https://api.twitter.com/2/tweets/1", nil))
+		if err != nil || res.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("RoundTrip = %v, %v; want 429", statusOf(res), err)
+		}
+		if calls != 1 {
+			t.Errorf("header %v: calls = %d, want 1 (không retry)", header, calls)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("header %v: đã chờ %s", header, elapsed)
+		}
+	}
+}
+
+func TestRetryTransportDelayWithinBounds(t *testing.T) {
+	transport := &retryTransport{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		d, ok := transport.delay(attempt, nil)
+		if !ok || d < 0 || d > transport.maxDelay {
+			t.Errorf("attempt %d: delay = %s, %v; want trong [0, %s]", attempt, d, ok, transport.maxDelay)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"x-twitter-backend/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CodeSessionExpired là mã lỗi khi session không còn dùng được (refresh token bị thu hồi hoặc hết hạn)
+const CodeSessionExpired = "SESSION_EXPIRED"
+
+// pendingLoginTTL là thời gian tối đa từ /auth/login tới /auth/callback
+const pendingLoginTTL = 10 * time.Minute
+
+// Session là phiên đăng nhập OAuth 2.0 của một end user, chỉ lưu phía server
+type Session struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserToken trả về token của session để gắn vào context khi gọi TwitterAPI
+func (s *Session) UserToken() UserToken {
+	return UserToken{AccessToken: s.AccessToken, UserID: s.UserID}
+}
+
+// pendingLogin là trạng thái giữa /auth/login và /auth/callback, lưu theo state
+type pendingLogin struct {
+	CodeVerifier string `json:"code_verifier"`
+	Redirect     string `json:"redirect,omitempty"`
+}
+
+// SessionManager lưu sessions trong CacheStore (memory hoặc redis, theo CACHE_BACKEND)
+// và refresh access token trước khi hết hạn. Các request đồng thời của cùng một
+// session chỉ refresh một lần.
+type SessionManager struct {
+	oauth         *OAuth2Client
+	store         CacheStore
+	ttl           time.Duration
+	refreshBefore time.Duration
+	refreshes     flightGroup
+}
+
+// NewSessionManager tạo SessionManager với store riêng cho sessions: LRU
+// SESSION_MAX_ENTRIES entries, hoặc cùng Redis với response cache (keys "session:*"
+// và "oauth2_state:*" sau CACHE_REDIS_KEY_PREFIX) để mọi replica thấy cùng sessions
+func NewSessionManager(cfg *config.Config, oauth *OAuth2Client) (*SessionManager, error) {
+	var store CacheStore
+	switch cfg.CacheBackend {
+	case "", CacheBackendMemory:
+		store = NewMemoryCacheStore(cfg.SessionMaxEntries)
+	case CacheBackendRedis:
+		store = NewRedisCacheStore(RedisOptions{
+			Addr:      cfg.CacheRedisAddr,
+			Password:  cfg.CacheRedisPassword,
+			DB:        cfg.CacheRedisDB,
+			KeyPrefix: cfg.CacheRedisKeyPrefix,
+			Timeout:   cfg.CacheRedisTimeout,
+			PoolSize:  cfg.CacheRedisPoolSize,
+		})
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND không hợp lệ: %s", cfg.CacheBackend)
+	}
+
+	log.WithFields(log.Fields{
+		"backend":        store.Backend(),
+		"ttl":            cfg.SessionTTL.String(),
+		"refresh_before": cfg.SessionRefreshBefore.String(),
+	}).Info("🔐 OAuth 2.0 login đã được bật")
+
+	return newSessionManager(oauth, store, cfg.SessionTTL, cfg.SessionRefreshBefore), nil
+}
+
+func newSessionManager(oauth *OAuth2Client, store CacheStore, ttl, refreshBefore time.Duration) *SessionManager {
+	return &SessionManager{
+		oauth:         oauth,
+		store:         store,
+		ttl:           ttl,
+		refreshBefore: refreshBefore,
+	}
+}
+
+// TTL là thời gian sống của session (cũng là Max-Age của cookie)
+func (m *SessionManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// LoginTTL là thời gian tối đa từ /auth/login tới /auth/callback
+func (m *SessionManager) LoginTTL() time.Duration {
+	return pendingLoginTTL
+}
+
+// BeginLogin tạo state và PKCE code verifier cho một lần đăng nhập và trả về URL
+// authorize cùng state (để gắn lần đăng nhập với browser đã bắt đầu nó). redirect là
+// đường dẫn chuyển tới sau khi đăng nhập xong (có thể rỗng).
+func (m *SessionManager) BeginLogin(ctx context.Context, redirect string) (authURL, state string, err error) {
+	state, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(pendingLogin{CodeVerifier: verifier, Redirect: redirect})
+	if err != nil {
+		return "", "", err
+	}
+	if err := m.store.Set(ctx, loginKey(state), data, pendingLoginTTL); err != nil {
+		return "", "", sessionStoreError(err)
+	}
+
+	return m.oauth.AuthCodeURL(state, verifier), state, nil
+}
+
+// CompleteLogin kiểm tra state và đổi code lấy token. State chỉ dùng được một lần;
+// ok=false nếu state không tồn tại hoặc đã hết hạn.
+func (m *SessionManager) CompleteLogin(ctx context.Context, state, code string) (token *OAuth2Token, redirect string, ok bool, err error) {
+	login, ok, err := m.takeLogin(ctx, state)
+	if err != nil || !ok {
+		return nil, "", ok, err
+	}
+
+	token, err = m.oauth.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		return nil, "", true, err
+	}
+	return token, login.Redirect, true, nil
+}
+
+// CancelLogin xóa state khi authorization server trả về lỗi (ví dụ user từ chối)
+func (m *SessionManager) CancelLogin(ctx context.Context, state string) {
+	if _, _, err := m.takeLogin(ctx, state); err != nil {
+		log.WithError(err).Warn("Không thể xóa OAuth state")
+	}
+}
+
+func (m *SessionManager) takeLogin(ctx context.Context, state string) (*pendingLogin, bool, error) {
+	data, ok, err := m.store.Get(ctx, loginKey(state))
+	if err != nil {
+		return nil, false, sessionStoreError(err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	if err := m.store.Delete(ctx, loginKey(state)); err != nil {
+		return nil, false, sessionStoreError(err)
+	}
+
+	var login pendingLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, false, nil
+	}
+	return &login, true, nil
+}
+
+// Create lưu session mới cho user vừa đăng nhập
+func (m *SessionManager) Create(ctx context.Context, token *OAuth2Token, userID, username string) (*Session, error) {
+	id, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess := &Session{
+		ID:        id,
+		UserID:    userID,
+		Username:  username,
+		CreatedAt: now,
+	}
+	applyToken(sess, token, now)
+
+	if err := m.save(ctx, sess); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"user_id":    userID,
+		"username":   username,
+		"expires_at": sess.ExpiresAt.UTC().Format(time.RFC3339),
+	}).Info("Đã tạo session")
+	return sess, nil
+}
+
+// Get trả về session theo ID (nil nếu không tồn tại), refresh access token nếu sắp
+// hết hạn. Session không refresh được nữa bị xóa và trả về lỗi SESSION_EXPIRED.
+func (m *SessionManager) Get(ctx context.Context, id string) (*Session, error) {
+	sess, err := m.load(ctx, id)
+	if err != nil || sess == nil {
+		return nil, err
+	}
+	if !m.needsRefresh(sess) {
+		return sess, nil
+	}
+
+	value, err := m.refreshes.do(ctx, "RefreshSession", id, func(ctx context.Context) (interface{}, error) {
+		return m.refresh(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Session), nil
+}
+
+// Delete xóa session và thu hồi token (best-effort) khi user đăng xuất
+func (m *SessionManager) Delete(ctx context.Context, id string) error {
+	sess, err := m.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sess == nil {
+		return nil
+	}
+	if err := m.store.Delete(ctx, sessionKey(id)); err != nil {
+		return sessionStoreError(err)
+	}
+
+	for hint, token := range map[string]string{"refresh_token": sess.RefreshToken, "access_token": sess.AccessToken} {
+		if token == "" {
+			continue
+		}
+		if err := m.oauth.Revoke(ctx, token, hint); err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"user_id":         sess.UserID,
+				"token_type_hint": hint,
+			}).Warn("Không thể thu hồi token khi đăng xuất")
+		}
+	}
+
+	log.WithField("user_id", sess.UserID).Info("Đã xóa session")
+	return nil
+}
+
+// refresh chạy trong flight của session: đọc lại session vì request khác (hoặc
+// replica khác dùng chung Redis) có thể vừa refresh xong
+func (m *SessionManager) refresh(ctx context.Context, id string) (*Session, error) {
+	sess, err := m.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, newSessionExpiredError("session đã bị xóa")
+	}
+	if !m.needsRefresh(sess) {
+		return sess, nil
+	}
+
+	expired := !time.Now().Before(sess.ExpiresAt)
+	if sess.RefreshToken == "" {
+		if !expired {
+			return sess, nil
+		}
+		m.drop(ctx, sess)
+		return nil, newSessionExpiredError("access token đã hết hạn và session không có refresh token (thiếu scope offline.access)")
+	}
+
+	token, err := m.oauth.Refresh(ctx, sess.RefreshToken)
+	if err != nil {
+		apiErr, ok := AsAPIError(err)
+		if ok && apiErr.Code == CodeOAuthTokenError {
+			m.drop(ctx, sess)
+			expiredErr := newSessionExpiredError("refresh token không còn hợp lệ, cần đăng nhập lại")
+			expiredErr.Detail = apiErr.Detail
+			return nil, expiredErr
+		}
+		// Lỗi tạm thời của authorization server: dùng tiếp access token cũ nếu còn hạn
+		if !expired {
+			log.WithError(err).WithField("user_id", sess.UserID).Warn("Không thể refresh access token, dùng token hiện tại")
+			return sess, nil
+		}
+		return nil, err
+	}
+
+	applyToken(sess, token, time.Now())
+	if err := m.save(ctx, sess); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"user_id":    sess.UserID,
+		"expires_at": sess.ExpiresAt.UTC().Format(time.RFC3339),
+	}).Info("Đã refresh access token")
+	return sess, nil
+}
+
+func (m *SessionManager) needsRefresh(sess *Session) bool {
+	return time.Until(sess.ExpiresAt) <= m.refreshBefore
+}
+
+func (m *SessionManager) load(ctx context.Context, id string) (*Session, error) {
+	if id == "" {
+		return nil, nil
+	}
+	data, ok, err := m.store.Get(ctx, sessionKey(id))
+	if err != nil {
+		return nil, sessionStoreError(err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		log.WithError(err).Warn("Session trong store không hợp lệ, bỏ qua")
+		return nil, nil
+	}
+	return &sess, nil
+}
+
+// save ghi session với TTL tính từ lúc tạo, để refresh không kéo dài session mãi mãi
+func (m *SessionManager) save(ctx context.Context, sess *Session) error {
+	ttl := time.Until(sess.CreatedAt.Add(m.ttl))
+	if ttl <= 0 {
+		return newSessionExpiredError("session đã quá SESSION_TTL")
+	}
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Set(ctx, sessionKey(sess.ID), data, ttl); err != nil {
+		return sessionStoreError(err)
+	}
+	return nil
+}
+
+func (m *SessionManager) drop(ctx context.Context, sess *Session) {
+	if err := m.store.Delete(ctx, sessionKey(sess.ID)); err != nil {
+		log.WithError(err).WithField("user_id", sess.UserID).Warn("Không thể xóa session hết hạn")
+	}
+}
+
+// applyToken cập nhật token của session; refresh token chỉ bị thay khi server trả về token mới
+func applyToken(sess *Session, token *OAuth2Token, now time.Time) {
+	sess.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		sess.RefreshToken = token.RefreshToken
+	}
+	if token.Scope != "" {
+		sess.Scope = token.Scope
+	}
+	// X cấp access token 2 giờ; expires_in thiếu thì coi như hạn mặc định đó
+	expiresIn := time.Duration(token.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 2 * time.Hour
+	}
+	sess.ExpiresAt = now.Add(expiresIn)
+}
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+func loginKey(state string) string {
+	return "oauth2_state:" + state
+}
+
+// newSessionExpiredError là lỗi khi end user cần đăng nhập lại
+func newSessionExpiredError(message string) *APIError {
+	return &APIError{Kind: ErrorKindUnauthorized, Code: CodeSessionExpired, Message: message}
+}
+
+// sessionStoreError là lỗi khi không đọc/ghi được session store (ví dụ Redis không sẵn sàng)
+func sessionStoreError(err error) *APIError {
+	return &APIError{Kind: ErrorKindUnavailable, Code: CodeUpstreamUnavailable, Message: "session store không sẵn sàng", Err: err}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	"github.com/michimani/gotwi"
+	log "github.com/sirupsen/logrus"
+)
+
+// allEndpoints là key cách ly áp dụng cho mọi endpoint family (token bị 401)
+const allEndpoints = "*"
+
+// Trạng thái của một Bearer Token trong pool
+const (
+	TokenActive      = "active"
+	TokenQuarantined = "quarantined"
+	TokenDegraded    = "degraded"
+)
+
+// maxProblemBody giới hạn số byte đọc từ body lỗi 403 để tìm lý do
+const maxProblemBody = 64 << 10
+
+// quarantine là một lần cách ly token với một endpoint family
+type quarantine struct {
+	status int
+	reason string
+	until  time.Time
+}
+
+// pooledToken là một Bearer Token cùng gotwi client và rate limit registry riêng:
+// quota của X API tính theo app nên mỗi token có cửa sổ rate limit độc lập
+type pooledToken struct {
+	name        string
+	fingerprint string
+	client      *gotwi.Client
+	rateLimits  *RateLimitRegistry
+
+	// Các field dưới đây được bảo vệ bởi TokenPool.mu
+	requests    int64
+	errors      int64
+	lastStatus  int
+	lastUsedAt  time.Time
+	quarantines map[string]quarantine // endpoint family hoặc allEndpoints -> cách ly
+}
+
+// TokenPool giữ các Bearer Token của nhiều X app và chọn token còn nhiều quota nhất
+// cho mỗi endpoint family. Token bị X API trả 401 bị cách ly với mọi endpoint, 403
+// chỉ với endpoint đó (ví dụ app không có quyền full-archive) trong quarantineFor.
+type TokenPool struct {
+	tokens        []*pooledToken
+	quarantineFor time.Duration
+
+	mu sync.Mutex
+}
+
+// newTokenPool tạo một gotwi client cho mỗi token của cfg.BearerTokens(). Các client
+// dùng chung circuit breakers vì lỗi 5xx là lỗi của endpoint chứ không của token.
+func newTokenPool(cfg *config.Config, breakers *CircuitBreakers) (*TokenPool, error) {
+	tokens := cfg.BearerTokens()
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cần ít nhất một Bearer Token")
+	}
+
+	p := &TokenPool{quarantineFor: cfg.TwitterTokenQuarantine}
+	for i, token := range tokens {
+		sum := sha256.Sum256([]byte(token))
+		t := &pooledToken{
+			name:        fmt.Sprintf("token-%d", i+1),
+			fingerprint: hex.EncodeToString(sum[:4]),
+			rateLimits:  NewRateLimitRegistry(),
+			quarantines: make(map[string]quarantine),
+		}
+
+		httpClient, err := newHTTPClient(cfg, t.rateLimits, breakers)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = &tokenUsageTransport{pool: p, token: t, next: httpClient.Transport}
+
+		t.client, err = gotwi.NewClientWithAccessToken(&gotwi.NewClientWithAccessTokenInput{
+			HTTPClient:  httpClient,
+			AccessToken: token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("không thể khởi tạo Twitter client cho %s: %w", t.name, err)
+		}
+		p.tokens = append(p.tokens, t)
+	}
+	return p, nil
+}
+
+// client trả về client của token được chọn cho family
+func (p *TokenPool) client(family string) *gotwi.Client {
+	return p.pick(family).client
+}
+
+// pick chọn token không bị cách ly còn nhiều quota nhất cho family; token chưa gọi
+// family lần nào được coi như còn đủ quota. Khi hòa thì ưu tiên token đã lâu không
+// dùng để chia đều tải. Nếu mọi token đều hết quota, chọn token reset sớm nhất để
+// lỗi RATE_LIMITED có Retry-After ngắn nhất; nếu mọi token đều bị cách ly, chọn
+// token hết cách ly sớm nhất thay vì từ chối request.
+func (p *TokenPool) pick(family string) *pooledToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best, fallback *pooledToken
+	var bestRemaining int
+	var bestResetAt, fallbackUntil time.Time
+	for _, t := range p.tokens {
+		if until, ok := t.quarantinedUntil(family, now); ok {
+			if fallback == nil || until.Before(fallbackUntil) {
+				fallback, fallbackUntil = t, until
+			}
+			continue
+		}
+
+		remaining, resetAt, known := t.rateLimits.quota(family)
+		if !known {
+			remaining = int(^uint(0) >> 1)
+		}
+		better := best == nil ||
+			remaining > bestRemaining ||
+			(remaining == bestRemaining && remaining == 0 && resetAt.Before(bestResetAt)) ||
+			(remaining == bestRemaining && remaining > 0 && t.lastUsedAt.Before(best.lastUsedAt))
+		if better {
+			best, bestRemaining, bestResetAt = t, remaining, resetAt
+		}
+	}
+	if best == nil {
+		best = fallback
+	}
+
+	best.lastUsedAt = now
+	return best
+}
+
+// quarantinedUntil trả về thời điểm hết cách ly nếu token đang bị cách ly với family
+func (t *pooledToken) quarantinedUntil(family string, now time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, key := range []string{allEndpoints, family} {
+		if q, ok := t.quarantines[key]; ok && now.Before(q.until) && q.until.After(until) {
+			until = q.until
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// record ghi nhận response của X API cho token: đếm request, cách ly token khi
+// 401/403 do token (blame) và gỡ cách ly khi token gọi thành công trở lại
+func (p *TokenPool) record(t *pooledToken, family string, status int, reason string, blame bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.requests++
+	t.lastStatus = status
+	if status >= 400 {
+		t.errors++
+	}
+
+	switch {
+	case status < 400:
+		delete(t.quarantines, family)
+		delete(t.quarantines, allEndpoints)
+	case !blame || p.quarantineFor <= 0:
+	case status == http.StatusUnauthorized:
+		p.quarantineLocked(t, allEndpoints, status, reason)
+	case status == http.StatusForbidden:
+		p.quarantineLocked(t, family, status, reason)
+	}
+}
+
+func (p *TokenPool) quarantineLocked(t *pooledToken, family string, status int, reason string) {
+	until := time.Now().Add(p.quarantineFor)
+	t.quarantines[family] = quarantine{status: status, reason: reason, until: until}
+
+	fields := log.Fields{
+		"token":    t.name,
+		"endpoint": family,
+		"status":   status,
+		"until":    until.UTC().Format(time.RFC3339),
+	}
+	if reason != "" {
+		fields["reason"] = reason
+	}
+	log.WithFields(fields).Warn("Bearer Token bị X API từ chối, tạm ngừng sử dụng")
+}
+
+// Snapshot trả về mức sử dụng và trạng thái của từng token theo thứ tự cấu hình
+func (p *TokenPool) Snapshot() []models.BearerTokenStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]models.BearerTokenStatus, 0, len(p.tokens))
+	for _, t := range p.tokens {
+		status := models.BearerTokenStatus{
+			Name:        t.name,
+			Fingerprint: t.fingerprint,
+			State:       TokenActive,
+			Requests:    t.requests,
+			Errors:      t.errors,
+			LastStatus:  t.lastStatus,
+			RateLimits:  t.rateLimits.Snapshot(),
+		}
+		if !t.lastUsedAt.IsZero() {
+			lastUsedAt := t.lastUsedAt.UTC()
+			status.LastUsedAt = &lastUsedAt
+		}
+		for family, q := range t.quarantines {
+			if !now.Before(q.until) {
+				continue
+			}
+			status.Quarantines = append(status.Quarantines, models.TokenQuarantine{
+				Endpoint: family,
+				Status:   q.status,
+				Reason:   q.reason,
+				Until:    q.until.UTC(),
+			})
+			if family == allEndpoints {
+				status.State = TokenQuarantined
+			} else if status.State == TokenActive {
+				status.State = TokenDegraded
+			}
+		}
+		sort.Slice(status.Quarantines, func(i, j int) bool {
+			return status.Quarantines[i].Endpoint < status.Quarantines[j].Endpoint
+		})
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// registries trả về rate limit registry của mọi token
+func (p *TokenPool) registries() []*RateLimitRegistry {
+	registries := make([]*RateLimitRegistry, 0, len(p.tokens))
+	for _, t := range p.tokens {
+		registries = append(registries, t.rateLimits)
+	}
+	return registries
+}
+
+// registerMetrics xuất số request và trạng thái cách ly của từng token ra /metrics
+func (p *TokenPool) registerMetrics() {
+	metrics.RegisterCounterFunc("x_api_token_requests_total",
+		"Số request gửi tới X API theo Bearer Token",
+		func() []metrics.Sample {
+			statuses := p.Snapshot()
+			samples := make([]metrics.Sample, 0, len(statuses))
+			for _, s := range statuses {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"token": s.Name},
+					Value:  float64(s.Requests),
+				})
+			}
+			return samples
+		})
+
+	stateValue := map[string]float64{TokenActive: 0, TokenDegraded: 1, TokenQuarantined: 2}
+	metrics.RegisterGaugeFunc("x_api_token_state",
+		"Trạng thái Bearer Token (0=active, 1=degraded, 2=quarantined)",
+		func() []metrics.Sample {
+			statuses := p.Snapshot()
+			samples := make([]metrics.Sample, 0, len(statuses))
+			for _, s := range statuses {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"token": s.Name},
+					Value:  stateValue[s.State],
+				})
+			}
+			return samples
+		})
+}
+
+// tokenUsageTransport là transport ngoài cùng của client theo token: ghi nhận mọi
+// response của X API (sau retry) vào pool. Lỗi không có response (rate limit hết,
+// circuit breaker mở, lỗi network) không tính là request của token.
+type tokenUsageTransport struct {
+	pool  *TokenPool
+	token *pooledToken
+	next  http.RoundTripper
+}
+
+func (t *tokenUsageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, blame := "", true
+	switch res.StatusCode {
+	case http.StatusUnauthorized:
+		reason = "Unauthorized"
+	case http.StatusForbidden:
+		reason, blame = forbiddenReason(res)
+	}
+	t.pool.record(t.token, endpointFamily(req.Method, req.URL.Path), res.StatusCode, reason, blame)
+	return res, nil
+}
+
+// forbiddenReason đọc lý do từ problem body của response 403 (phần đã đọc được trả
+// lại cho gotwi); blame=false khi 403 là unsupported-authentication, tức endpoint
+// không nhận app-only auth với token nào, lỗi thuộc về request chứ không phải token
+func forbiddenReason(res *http.Response) (reason string, blame bool) {
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxProblemBody))
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+	if err != nil {
+		return "", true
+	}
+
+	var problem struct {
+		Title  string `json:"title"`
+		Reason string `json:"reason"`
+		Type   string `json:"type"`
+	}
+	if json.Unmarshal(body, &problem) != nil {
+		return "", true
+	}
+	if strings.HasSuffix(problem.Type, "/unsupported-authentication") {
+		return "", false
+	}
+	if problem.Reason != "" {
+		return problem.Reason, true
+	}
+	return problem.Title, true
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/mockserver"
+	"x-twitter-backend/models"
+)
+
+const testTweetID = "1792000000340563000"
+
+// newTokenPoolTestService chạy mock server chấp nhận accepted và trả về TwitterService
+// dùng tokens. Pool chọn token theo quota thực tế nên tests gọi mock server trực tiếp.
+func newTokenPoolTestService(t *testing.T, opts mockserver.Options, tokens ...string) *TwitterService {
+	t.Helper()
+
+	fixtures, err := mockserver.LoadFixtures("../mockserver/fixtures")
+	if err != nil {
+		t.Fatalf("không load được fixtures: %v", err)
+	}
+	server := httptest.NewServer(mockserver.New(fixtures, opts))
+	t.Cleanup(server.Close)
+
+	svc, err := NewTwitterService(&config.Config{
+		TwitterBearerToken:      tokens[0],
+		TwitterBearerTokens:     tokens[1:],
+		TwitterTokenQuarantine:  time.Minute,
+		TwitterAPIBaseURL:       server.URL,
+		TwitterHTTPMode:         "live",
+		TwitterRetryMaxAttempts: 1,
+		MaxTweetsPerRequest:     100,
+		DefaultTweetsCount:      10,
+	})
+	if err != nil {
+		t.Fatalf("NewTwitterService: %v", err)
+	}
+	return svc
+}
+
+func tokenStatuses(t *testing.T, svc *TwitterService) []models.BearerTokenStatus {
+	t.Helper()
+	resp, err := svc.GetTokenPool(context.Background())
+	if err != nil {
+		t.Fatalf("GetTokenPool: %v", err)
+	}
+	return resp.Tokens
+}
+
+func TestTokenPoolSpreadsQuotaAcrossTokens(t *testing.T) {
+	svc := newTokenPoolTestService(t, mockserver.Options{
+		BearerTokens: []string{"app-a", "app-b"},
+		RateLimits:   map[string]int{"/2/tweets/{id}": 2},
+	}, "app-a", "app-b")
+	ctx := context.Background()
+
+	// Mỗi app chỉ có 2 request: pool dùng hết quota của cả hai trước khi báo hết
+	for i := 0; i < 4; i++ {
+		if _, err := svc.GetTweetByID(ctx, testTweetID); err != nil {
+			t.Fatalf("request #%d: %v", i+1, err)
+		}
+	}
+	_, err := svc.GetTweetByID(ctx, testTweetID)
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Kind != ErrorKindRateLimited || apiErr.RetryAfter <= 0 {
+		t.Fatalf("request thứ 5 = %v, want RATE_LIMITED với Retry-After", err)
+	}
+
+	for _, status := range tokenStatuses(t, svc) {
+		if status.Requests != 2 || status.State != TokenActive || len(status.RateLimits) != 1 || status.RateLimits[0].Remaining != 0 {
+			t.Errorf("%s = %+v, want 2 requests và hết quota", status.Name, status)
+		}
+		if status.Fingerprint == "" || status.Fingerprint == "app-a" || status.Fingerprint == "app-b" {
+			t.Errorf("%s fingerprint = %q, không được lộ token", status.Name, status.Fingerprint)
+		}
+	}
+
+	limits, _ := svc.GetRateLimits(ctx)
+	if len(limits.RateLimits) != 1 {
+		t.Fatalf("rate limits = %+v, want 1 family", limits.RateLimits)
+	}
+	if got := limits.RateLimits[0]; got.Endpoint != "GET /2/tweets/{id}" || got.Limit != 4 || got.Remaining != 0 || !got.Exhausted {
+		t.Errorf("rate limit gộp = %+v, want limit 4, remaining 0, exhausted", got)
+	}
+}
+
+func TestTokenPoolQuarantinesUnauthorizedToken(t *testing.T) {
+	svc := newTokenPoolTestService(t, mockserver.Options{BearerToken: "good"}, "good", "revoked")
+	ctx := context.Background()
+
+	if _, err := svc.GetTweetByID(ctx, testTweetID); err != nil {
+		t.Fatalf("request đầu (token-1): %v", err)
+	}
+	// token-2 chưa dùng nên được chọn tiếp và bị X API từ chối
+	_, err := svc.GetTweetByID(ctx, testTweetID)
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Kind != ErrorKindUnauthorized {
+		t.Fatalf("request bằng token bị thu hồi = %v, want unauthorized", err)
+	}
+
+	// Sau khi bị cách ly, token-2 không được dùng cho endpoint nào nữa
+	for i := 0; i < 3; i++ {
+		if _, err := svc.GetTweetByID(ctx, testTweetID); err != nil {
+			t.Fatalf("GetTweetByID sau khi cách ly: %v", err)
+		}
+		if _, err := svc.SearchTweets(ctx, "golang", 10, "", TweetFilter{}); err != nil {
+			t.Fatalf("SearchTweets sau khi cách ly: %v", err)
+		}
+	}
+
+	statuses := tokenStatuses(t, svc)
+	if good := statuses[0]; good.State != TokenActive || good.Requests != 7 {
+		t.Errorf("token-1 = %+v, want active với 7 requests", good)
+	}
+	revoked := statuses[1]
+	if revoked.State != TokenQuarantined || revoked.Requests != 1 || revoked.Errors != 1 || revoked.LastStatus != 401 {
+		t.Errorf("token-2 = %+v, want quarantined sau 1 request 401", revoked)
+	}
+	if len(revoked.Quarantines) != 1 || revoked.Quarantines[0].Endpoint != "*" || !revoked.Quarantines[0].Until.After(time.Now()) {
+		t.Errorf("token-2 quarantines = %+v, want * tới tương lai", revoked.Quarantines)
+	}
+}
+
+func TestTokenPoolQuarantinesForbiddenEndpointOnly(t *testing.T) {
+	svc := newTokenPoolTestService(t, mockserver.Options{DenyFullArchive: true}, "app-a", "app-b")
+	ctx := context.Background()
+
+	// Cả hai app đều không có full-archive: mỗi token bị cách ly riêng cho search/all,
+	// khi mọi token đều bị cách ly thì request vẫn được gửi và trả lỗi của X API
+	for i := 0; i < 3; i++ {
+		_, err := svc.SearchAllTweets(ctx, "golang", 10, "", TweetFilter{})
+		if apiErr, ok := AsAPIError(err); !ok || apiErr.Code != CodeArchiveAccessRequired {
+			t.Fatalf("SearchAllTweets #%d = %v, want %s", i+1, err, CodeArchiveAccessRequired)
+		}
+	}
+
+	// Endpoint khác vẫn dùng được cả hai token
+	for i := 0; i < 2; i++ {
+		if _, err := svc.SearchTweets(ctx, "golang", 10, "", TweetFilter{}); err != nil {
+			t.Fatalf("SearchTweets: %v", err)
+		}
+	}
+
+	for _, status := range tokenStatuses(t, svc) {
+		if status.State != TokenDegraded || len(status.Quarantines) != 1 {
+			t.Fatalf("%s = %+v, want degraded", status.Name, status)
+		}
+		if q := status.Quarantines[0]; q.Endpoint != "GET /2/tweets/search/all" || q.Status != 403 || q.Reason != "client-not-enrolled" {
+			t.Errorf("%s quarantine = %+v", status.Name, q)
+		}
+	}
+}
+
+func TestTokenPoolIgnoresUnsupportedAuthentication(t *testing.T) {
+	svc := newTokenPoolTestService(t, mockserver.Options{}, "app-a", "app-b")
+
+	// users/me không nhận app-only auth: 403 là lỗi của request, không phải của token
+	for i := 0; i < 2; i++ {
+		_, err := svc.GetMe(context.Background())
+		if apiErr, ok := AsAPIError(err); !ok || apiErr.Code != CodeUserContextRequired {
+			t.Fatalf("GetMe = %v, want %s", err, CodeUserContextRequired)
+		}
+	}
+
+	for _, status := range tokenStatuses(t, svc) {
+		if status.State != TokenActive || status.Requests != 1 || status.LastStatus != 403 {
+			t.Errorf("%s = %+v, want active sau 1 request 403", status.Name, status)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"x-twitter-backend/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultTwitterAPIBaseURL là host mà gotwi hard-code trong tất cả các endpoints
+const defaultTwitterAPIBaseURL = "https://api.twitter.com"
+
+// newHTTPClient tạo http.Client dùng cho gotwi.Client; rate limit của mọi response
+// được ghi vào rateLimits, trạng thái circuit breaker được giữ trong breakers
+func newHTTPClient(cfg *config.Config, rateLimits *RateLimitRegistry, breakers *CircuitBreakers) (*http.Client, error) {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	baseURL := strings.TrimRight(cfg.TwitterAPIBaseURL, "/")
+	if baseURL != "" && baseURL != defaultTwitterAPIBaseURL {
+		base, err := url.Parse(baseURL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("TWITTER_API_BASE_URL không hợp lệ: %q", cfg.TwitterAPIBaseURL)
+		}
+		transport = &baseURLTransport{base: base, next: transport}
+	}
+
+	switch cfg.TwitterHTTPMode {
+	case HTTPModeRecord, HTTPModeReplay:
+		secrets := append(cfg.BearerTokens(),
+			cfg.TwitterConsumerKey,
+			cfg.TwitterConsumerSecret,
+			cfg.TwitterAccessToken,
+			cfg.TwitterAccessTokenSecret,
+		)
+		cassettes, err := newCassetteTransport(cfg.TwitterHTTPMode, cfg.TwitterCassetteDir, secrets, transport)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"mode": cfg.TwitterHTTPMode,
+			"dir":  cfg.TwitterCassetteDir,
+		}).Info("📼 HTTP cassettes đã được bật")
+		transport = cassettes
+	}
+
+	transport = &rateLimitTransport{registry: rateLimits, next: transport}
+	transport = &retryTransport{
+		maxAttempts: cfg.TwitterRetryMaxAttempts,
+		baseDelay:   cfg.TwitterRetryBaseDelay,
+		maxDelay:    cfg.TwitterRetryMaxDelay,
+		next:        transport,
+	}
+	if breakers.Enabled() {
+		transport = &circuitBreakerTransport{breakers: breakers, next: transport}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}, nil
+}
+
+// baseURLTransport chuyển hướng requests tới api.twitter.com sang base URL khác,
+// ví dụ mock server khi chạy integration test offline
+type baseURLTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.base.Scheme
+	out.URL.Host = t.base.Host
+	out.URL.Path = strings.TrimRight(t.base.Path, "/") + req.URL.Path
+	out.URL.RawPath = ""
+	out.Host = t.base.Host
+
+	return t.next.RoundTrip(out)
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+	"x-twitter-backend/models"
+)
+
+// TwitterAPI là tập hợp các thao tác với X/Twitter API mà handlers sử dụng.
+// TwitterService là implementation thật (gọi X API qua gotwi), FakeTwitterService
+// là implementation in-memory dùng cho test và chạy offline.
+type TwitterAPI interface {
+	// Users
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID string) (*models.User, error)
+	ListUsers(ctx context.Context, userIDs []string) (*models.UsersListResponse, error)
+	GetMe(ctx context.Context) (*models.User, error)
+	SearchUsers(ctx context.Context, query string, maxResults int, paginationToken string) (*models.SearchUsersResponse, error)
+	GetUserFollowing(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowingResponse, error)
+	GetUserFollowers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.FollowersResponse, error)
+	GetBlockingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.BlockingUsersResponse, error)
+	GetMutingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.MutingUsersResponse, error)
+
+	// Timelines
+	GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error)
+	GetTweetsByUserID(ctx context.Context, userID string, maxResults int, paginationToken string) (*models.TweetsResponse, error)
+	GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int, paginationToken string) (*models.TweetsResponse, error)
+	GetUserMentions(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.MentionsResponse, error)
+	GetLikedTweets(ctx context.Context, username string, maxResults int, paginationToken string) (*models.LikedTweetsResponse, error)
+	GetRepostsOfMe(ctx context.Context, maxResults int, paginationToken string) (*models.RepostsResponse, error)
+
+	// Tweets
+	GetTweetByID(ctx context.Context, tweetID string) (*models.TweetDetailResponse, error)
+	ListTweets(ctx context.Context, tweetIDs []string) (*models.SearchTweetsResponse, error)
+	SearchTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error)
+	GetTweetCounts(ctx context.Context, query string, opts TweetCountsOptions) (*models.TweetCountsResponse, error)
+	// Full-archive (cần Bearer Token của gói Pro hoặc Academic Research)
+	SearchAllTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error)
+	GetAllTweetCounts(ctx context.Context, query string, opts TweetCountsOptions, paginationToken string) (*models.TweetCountsResponse, error)
+	GetLikingUsers(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.LikingUsersResponse, error)
+	GetRetweetedBy(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.RetweetedByResponse, error)
+	GetQuoteTweets(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.QuoteTweetsResponse, error)
+	HideTweet(ctx context.Context, tweetID string, hidden bool) (*models.HideTweetResponse, error)
+
+	// Trạng thái upstream
+	GetRateLimits(ctx context.Context) (*models.RateLimitsResponse, error)
+	GetCircuitBreakers(ctx context.Context) (*models.CircuitBreakersResponse, error)
+	GetTokenPool(ctx context.Context) (*models.TokenPoolResponse, error)
+}
+
+// Đảm bảo các implementation luôn thỏa mãn interface
+var (
+	_ TwitterAPI = (*TwitterService)(nil)
+	_ TwitterAPI = (*FakeTwitterService)(nil)
+	_ TwitterAPI = (*CachedTwitterService)(nil)
+	_ TwitterAPI = (*CoalescedTwitterService)(nil)
+)
+
+// TweetFilter giới hạn tweets theo khoảng thời gian [StartTime, EndTime) và khoảng ID
+// (SinceID, UntilID), không bao gồm chính hai ID này. Field rỗng là không giới hạn.
+type TweetFilter struct {
+	StartTime time.Time
+	EndTime   time.Time
+	SinceID   string
+	UntilID   string
+}
+
+// Match kiểm tra tweet có nằm trong filter không
+func (f TweetFilter) Match(tweet *models.Tweet) bool {
+	switch {
+	case !f.StartTime.IsZero() && tweet.CreatedAt.Before(f.StartTime):
+		return false
+	case !f.EndTime.IsZero() && !tweet.CreatedAt.Before(f.EndTime):
+		return false
+	case f.SinceID != "" && CompareTweetIDs(tweet.ID, f.SinceID) <= 0:
+		return false
+	case f.UntilID != "" && CompareTweetIDs(tweet.ID, f.UntilID) >= 0:
+		return false
+	}
+	return true
+}
+
+// key là phần cache key của filter, rỗng nếu không giới hạn gì
+func (f TweetFilter) key() string {
+	if f == (TweetFilter{}) {
+		return ""
+	}
+	return strings.Join([]string{formatFilterTime(f.StartTime), formatFilterTime(f.EndTime), f.SinceID, f.UntilID}, ",")
+}
+
+// startTime và endTime trả về nil nếu không giới hạn, theo kiểu tham số của gotwi
+func (f TweetFilter) startTime() *time.Time {
+	return optionalTime(f.StartTime)
+}
+
+func (f TweetFilter) endTime() *time.Time {
+	return optionalTime(f.EndTime)
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func formatFilterTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Granularity của tweet counts: độ dài mỗi bucket
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+)
+
+// GranularityStep trả về độ dài bucket của granularity, false nếu granularity không hợp lệ
+func GranularityStep(granularity string) (time.Duration, bool) {
+	switch granularity {
+	case GranularityMinute:
+		return time.Minute, true
+	case GranularityHour:
+		return time.Hour, true
+	case GranularityDay:
+		return 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// TweetCountsOptions là khoảng thời gian [StartTime, EndTime) và granularity của tweet counts.
+// Thời gian rỗng để X API dùng mặc định (7 ngày gần nhất, 30 ngày với full-archive),
+// Granularity rỗng là GranularityHour.
+type TweetCountsOptions struct {
+	StartTime   time.Time
+	EndTime     time.Time
+	Granularity string
+}
+
+// granularity trả về granularity đã áp dụng mặc định
+func (o TweetCountsOptions) granularity() string {
+	if o.Granularity == "" {
+		return GranularityHour
+	}
+	return o.Granularity
+}
+
+// key là phần cache key của options
+func (o TweetCountsOptions) key() string {
+	return strings.Join([]string{formatFilterTime(o.StartTime), formatFilterTime(o.EndTime), o.granularity()}, ",")
+}
+
+// newTweetCountsResponse tạo response từ các bucket, tính tổng và bucket cao điểm
+func newTweetCountsResponse(query, granularity string, counts []models.TweetCount) *models.TweetCountsResponse {
+	result := &models.TweetCountsResponse{
+		Query:       query,
+		Granularity: granularity,
+		Counts:      counts,
+		Meta:        &models.Meta{ResultCount: len(counts)},
+	}
+	for i := range counts {
+		result.TotalTweetCount += counts[i].TweetCount
+		if counts[i].TweetCount > 0 && (result.Peak == nil || counts[i].TweetCount > result.Peak.TweetCount) {
+			peak := counts[i]
+			result.Peak = &peak
+		}
+	}
+	return result
+}
+
+// CompareTweetIDs so sánh hai snowflake ID dạng chuỗi số (không có số 0 ở đầu) mà không cần parse
+func CompareTweetIDs(a, b string) int {
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -3,22 +3,23 @@ package services
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"x-twitter-backend/config"
 	"x-twitter-backend/models"
 
 	"strings"
-	"time"
 
 	"github.com/michimani/gotwi"
 	"github.com/michimani/gotwi/fields"
 	"github.com/michimani/gotwi/resources"
 	"github.com/michimani/gotwi/tweet/timeline"
 	timelineTypes "github.com/michimani/gotwi/tweet/timeline/types"
+	"github.com/michimani/gotwi/tweet/hidereply"
+	hidereplyTypes "github.com/michimani/gotwi/tweet/hidereply/types"
 	"github.com/michimani/gotwi/tweet/like"
 	likeTypes "github.com/michimani/gotwi/tweet/like/types"
 	"github.com/michimani/gotwi/tweet/quotetweet"
 	quotetweetTypes "github.com/michimani/gotwi/tweet/quotetweet/types"
-	"github.com/michimani/gotwi/tweet/retweet"
 	retweetTypes "github.com/michimani/gotwi/tweet/retweet/types"
 	"github.com/michimani/gotwi/tweet/searchtweet"
 	searchTypes "github.com/michimani/gotwi/tweet/searchtweet/types"
@@ -26,35 +27,88 @@ import (
 	tweetcountTypes "github.com/michimani/gotwi/tweet/tweetcount/types"
 	"github.com/michimani/gotwi/tweet/tweetlookup"
 	lookupTypes "github.com/michimani/gotwi/tweet/tweetlookup/types"
+	"github.com/michimani/gotwi/user/block"
+	blockTypes "github.com/michimani/gotwi/user/block/types"
 	"github.com/michimani/gotwi/user/follow"
 	followTypes "github.com/michimani/gotwi/user/follow/types"
+	"github.com/michimani/gotwi/user/mute"
+	muteTypes "github.com/michimani/gotwi/user/mute/types"
 	"github.com/michimani/gotwi/user/userlookup"
 	userlookupTypes "github.com/michimani/gotwi/user/userlookup/types"
 	log "github.com/sirupsen/logrus"
 )
 
+// max_results nhỏ nhất của timeline (tweets, mentions) và của search, liked tweets,
+// quote tweets. X API (và gotwi) bỏ qua max_results nhỏ hơn mức này và trả về số items
+// mặc định, nên giá trị nhỏ hơn được nâng lên; auto-pagination cắt phần dư để không
+// vượt max_items.
+const (
+	minTimelineResults = 5
+	minSearchResults   = 10
+)
+
 // TwitterService xử lý tất cả các tương tác với Twitter API
 type TwitterService struct {
-	client *gotwi.Client
-	config *config.Config
+	// tokens chứa một client cho mỗi Bearer Token của app
+	tokens *TokenPool
+	// httpClient dùng cho OAuth 1.0a và client theo token của end user
+	httpClient *http.Client
+	// userClient ký request bằng OAuth 1.0a user context; nil nếu chỉ cấu hình Bearer Token
+	userClient *gotwi.Client
+	config     *config.Config
+	// rateLimits là rate limit của OAuth 1.0a user context; rate limit của từng
+	// Bearer Token nằm trong tokens
+	rateLimits *RateLimitRegistry
+	breakers   *CircuitBreakers
+	users      *userCache
 }
 
 // NewTwitterService tạo một instance mới của TwitterService
 func NewTwitterService(cfg *config.Config) (*TwitterService, error) {
-	// Khởi tạo Twitter client với Bearer Token
-	client, err := gotwi.NewClientWithAccessToken(&gotwi.NewClientWithAccessTokenInput{
-		AccessToken: cfg.TwitterBearerToken,
-	})
+	rateLimits := NewRateLimitRegistry()
+	breakers := NewCircuitBreakers(cfg.TwitterBreakerThreshold, cfg.TwitterBreakerCooldown)
+	httpClient, err := newHTTPClient(cfg, rateLimits, breakers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Khởi tạo một Twitter client cho mỗi Bearer Token
+	tokens, err := newTokenPool(cfg, breakers)
 	if err != nil {
 		return nil, fmt.Errorf("không thể khởi tạo Twitter client: %w", err)
 	}
 
-	log.Info("Twitter client đã được khởi tạo thành công")
+	var userClient *gotwi.Client
+	if cfg.HasUserContext() {
+		userClient = newUserContextClient(cfg, httpClient)
+	}
 
-	return &TwitterService{
-		client: client,
-		config: cfg,
-	}, nil
+	log.WithFields(log.Fields{
+		"base_url":      cfg.TwitterAPIBaseURL,
+		"bearer_tokens": len(tokens.tokens),
+		"user_context":  userClient != nil,
+	}).Info("Twitter client đã được khởi tạo thành công")
+
+	s := &TwitterService{
+		tokens:     tokens,
+		httpClient: httpClient,
+		userClient: userClient,
+		config:     cfg,
+		rateLimits: rateLimits,
+		breakers:   breakers,
+		users:      newUserCache(cfg.UserCacheMaxEntries, cfg.UserCacheTTL, cfg.CacheTTLProfile),
+	}
+
+	registerRateLimitMetrics(s.rateLimitSnapshot)
+	breakers.registerMetrics()
+	tokens.registerMetrics()
+
+	return s, nil
+}
+
+// rateLimitSnapshot gộp rate limit của mọi Bearer Token và OAuth 1.0a theo endpoint family
+func (s *TwitterService) rateLimitSnapshot() []models.RateLimitStatus {
+	return mergeRateLimits(append(s.tokens.registries(), s.rateLimits)...)
 }
 
 // GetUserByUsername lấy thông tin user theo username
@@ -75,16 +129,17 @@ func (s *TwitterService) GetUserByUsername(ctx context.Context, username string)
 		},
 	}
 
-	resp, err := userlookup.GetByUsername(ctx, s.client, params)
+	resp, err := userlookup.GetByUsername(ctx, s.clientFor(ctx, "GET /2/users/by/username/{username}"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy thông tin user: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy thông tin user")
 	}
 
 	if resp.Data.ID == nil || gotwi.StringValue(resp.Data.ID) == "" {
-		return nil, fmt.Errorf("không tìm thấy user với username: %s", username)
+		return nil, lookupError(resp.Errors, CodeUserNotFound, fmt.Sprintf("không tìm thấy user với username: %s", username))
 	}
 
 	user := s.convertToUser(&resp.Data)
+	s.users.store(user)
 	log.WithFields(log.Fields{
 		"user_id":  user.ID,
 		"username": user.Username,
@@ -93,15 +148,34 @@ func (s *TwitterService) GetUserByUsername(ctx context.Context, username string)
 	return user, nil
 }
 
+// resolveUser lấy user theo username cho các method cần user ID, ưu tiên cache
+// để không tốn thêm một lần gọi users lookup mỗi request. User trả về luôn là profile
+// đầy đủ (dùng cho field user của response): khi profile đã quá CACHE_TTL_PROFILE,
+// profile được lấy lại từ X API.
+func (s *TwitterService) resolveUser(ctx context.Context, username string) (*models.User, error) {
+	if !cacheBypassed(ctx) {
+		if user, ok := s.users.byUsername(username); ok {
+			log.WithFields(log.Fields{
+				"username": username,
+				"user_id":  user.ID,
+			}).Debug("Lấy user từ cache")
+			return user, nil
+		}
+	}
+	return s.GetUserByUsername(ctx, username)
+}
+
 // GetUserTweets lấy tweets của một user theo username
-func (s *TwitterService) GetUserTweets(ctx context.Context, username string, maxResults int) (*models.TweetsResponse, error) {
+func (s *TwitterService) GetUserTweets(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.TweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
+		"filter":      filter.key(),
 	}).Info("Đang lấy tweets của user")
 
-	// Đầu tiên, lấy thông tin user để có user ID
-	user, err := s.GetUserByUsername(ctx, username)
+	// Đầu tiên, lấy thông tin user để có user ID (ưu tiên cache)
+	user, err := s.resolveUser(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +187,9 @@ func (s *TwitterService) GetUserTweets(ctx context.Context, username string, max
 	if maxResults > s.config.MaxTweetsPerRequest {
 		maxResults = s.config.MaxTweetsPerRequest
 	}
+	if maxResults < minTimelineResults {
+		maxResults = minTimelineResults
+	}
 
 	// Lấy tweets của user
 	params := &timelineTypes.ListTweetsInput{
@@ -133,11 +210,16 @@ func (s *TwitterService) GetUserTweets(ctx context.Context, username string, max
 			fields.UserFieldUsername,
 			fields.UserFieldProfileImageUrl,
 		},
+		PaginationToken: paginationToken,
+		StartTime:       filter.startTime(),
+		EndTime:         filter.endTime(),
+		SinceID:         filter.SinceID,
+		UntilID:         filter.UntilID,
 	}
 
-	resp, err := timeline.ListTweets(ctx, s.client, params)
+	resp, err := timeline.ListTweets(ctx, s.clientFor(ctx, "GET /2/users/{id}/tweets"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy tweets")
 	}
 
 	// Convert response sang models
@@ -171,7 +253,7 @@ func (s *TwitterService) GetUserFollowing(ctx context.Context, username string,
 		"page_token":  paginationToken,
 	}).Info("Đang lấy danh sách following của user")
 
-	user, err := s.GetUserByUsername(ctx, username)
+	user, err := s.resolveUser(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -202,9 +284,9 @@ func (s *TwitterService) GetUserFollowing(ctx context.Context, username string,
 		params.PaginationToken = paginationToken
 	}
 
-	resp, err := follow.ListFollowings(ctx, s.client, params)
+	resp, err := follow.ListFollowings(ctx, s.clientFor(ctx, "GET /2/users/{id}/following"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách following: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách following")
 	}
 
 	following := make([]models.User, 0, len(resp.Data))
@@ -227,10 +309,11 @@ func (s *TwitterService) GetUserFollowing(ctx context.Context, username string,
 }
 
 // GetTweetsByUserID lấy tweets theo user ID trực tiếp
-func (s *TwitterService) GetTweetsByUserID(ctx context.Context, userID string, maxResults int) ([]models.Tweet, error) {
+func (s *TwitterService) GetTweetsByUserID(ctx context.Context, userID string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"user_id":     userID,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
 	}).Info("Đang lấy tweets theo user ID")
 
 	// Validate và điều chỉnh maxResults
@@ -240,6 +323,9 @@ func (s *TwitterService) GetTweetsByUserID(ctx context.Context, userID string, m
 	if maxResults > s.config.MaxTweetsPerRequest {
 		maxResults = s.config.MaxTweetsPerRequest
 	}
+	if maxResults < minTimelineResults {
+		maxResults = minTimelineResults
+	}
 
 	params := &timelineTypes.ListTweetsInput{
 		ID:         userID,
@@ -253,11 +339,12 @@ func (s *TwitterService) GetTweetsByUserID(ctx context.Context, userID string, m
 			fields.TweetFieldEntities,
 			fields.TweetFieldReferencedTweets,
 		},
+		PaginationToken: paginationToken,
 	}
 
-	resp, err := timeline.ListTweets(ctx, s.client, params)
+	resp, err := timeline.ListTweets(ctx, s.clientFor(ctx, "GET /2/users/{id}/tweets"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy tweets")
 	}
 
 	tweets := make([]models.Tweet, 0)
@@ -273,7 +360,27 @@ func (s *TwitterService) GetTweetsByUserID(ctx context.Context, userID string, m
 		"tweets_count": len(tweets),
 	}).Info("Đã lấy tweets thành công")
 
-	return tweets, nil
+	return &models.TweetsResponse{
+		Tweets: tweets,
+		Meta:   buildMetaFromTimeline(resp.Meta, len(tweets)),
+	}, nil
+}
+
+// Endpoint của like.ListUsers và retweet.ListUsers; gọi trực tiếp qua client.CallAPI
+// vì output của gotwi cho hai API này không có field meta (mất next_token)
+const (
+	likingUsersEndpoint = "https://api.twitter.com/2/tweets/:id/liking_users"
+	retweetedByEndpoint = "https://api.twitter.com/2/tweets/:id/retweeted_by"
+)
+
+type likingUsersOutput struct {
+	likeTypes.ListUsersOutput
+	Meta resources.PaginationMeta `json:"meta"`
+}
+
+type retweetedByOutput struct {
+	retweetTypes.ListUsersOutput
+	Meta resources.PaginationMeta `json:"meta"`
 }
 
 func buildMetaFromTimeline(meta resources.TweetTimelineMeta, fallbackCount int) *models.Meta {
@@ -283,8 +390,9 @@ func buildMetaFromTimeline(meta resources.TweetTimelineMeta, fallbackCount int)
 	}
 
 	return &models.Meta{
-		ResultCount: resultCount,
-		NextToken:   gotwi.StringValue(meta.NextToken),
+		ResultCount:   resultCount,
+		NextToken:     gotwi.StringValue(meta.NextToken),
+		PreviousToken: gotwi.StringValue(meta.PreviousToken),
 	}
 }
 
@@ -314,8 +422,9 @@ func buildMetaFromQuoteTweets(meta resources.QuoteTweetsMeta, fallbackCount int)
 	}
 
 	return &models.Meta{
-		ResultCount: resultCount,
-		NextToken:   gotwi.StringValue(meta.NextToken),
+		ResultCount:   resultCount,
+		NextToken:     gotwi.StringValue(meta.NextToken),
+		PreviousToken: gotwi.StringValue(meta.PreviousToken),
 	}
 }
 
@@ -437,7 +546,7 @@ func (s *TwitterService) GetUserFollowers(ctx context.Context, username string,
 		"page_token":  paginationToken,
 	}).Info("Đang lấy danh sách followers của user")
 
-	user, err := s.GetUserByUsername(ctx, username)
+	user, err := s.resolveUser(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -468,9 +577,9 @@ func (s *TwitterService) GetUserFollowers(ctx context.Context, username string,
 		params.PaginationToken = paginationToken
 	}
 
-	resp, err := follow.ListFollowers(ctx, s.client, params)
+	resp, err := follow.ListFollowers(ctx, s.clientFor(ctx, "GET /2/users/{id}/followers"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách followers: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách followers")
 	}
 
 	followers := make([]models.User, 0, len(resp.Data))
@@ -493,10 +602,12 @@ func (s *TwitterService) GetUserFollowers(ctx context.Context, username string,
 }
 
 // SearchTweets tìm kiếm tweets theo keyword
-func (s *TwitterService) SearchTweets(ctx context.Context, query string, maxResults int) (*models.SearchTweetsResponse, error) {
+func (s *TwitterService) SearchTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"query":       query,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
+		"filter":      filter.key(),
 	}).Info("Đang tìm kiếm tweets")
 
 	if maxResults <= 0 {
@@ -505,6 +616,9 @@ func (s *TwitterService) SearchTweets(ctx context.Context, query string, maxResu
 	if maxResults > 100 {
 		maxResults = 100
 	}
+	if maxResults < minSearchResults {
+		maxResults = minSearchResults
+	}
 
 	params := &searchTypes.ListRecentInput{
 		Query:      query,
@@ -518,11 +632,17 @@ func (s *TwitterService) SearchTweets(ctx context.Context, query string, maxResu
 			fields.TweetFieldEntities,
 			fields.TweetFieldReferencedTweets,
 		},
+		// Search API gọi pagination token là next_token
+		NextToken: paginationToken,
+		StartTime: filter.startTime(),
+		EndTime:   filter.endTime(),
+		SinceID:   filter.SinceID,
+		UntilID:   filter.UntilID,
 	}
 
-	resp, err := searchtweet.ListRecent(ctx, s.client, params)
+	resp, err := searchtweet.ListRecent(ctx, s.clientFor(ctx, "GET /2/tweets/search/recent"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể tìm kiếm tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể tìm kiếm tweets")
 	}
 
 	tweets := make([]models.Tweet, 0, len(resp.Data))
@@ -572,9 +692,13 @@ func (s *TwitterService) GetTweetByID(ctx context.Context, tweetID string) (*mod
 		},
 	}
 
-	resp, err := tweetlookup.Get(ctx, s.client, params)
+	resp, err := tweetlookup.Get(ctx, s.clientFor(ctx, "GET /2/tweets/{id}"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy tweet: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy tweet")
+	}
+
+	if resp.Data.ID == nil || gotwi.StringValue(resp.Data.ID) == "" {
+		return nil, lookupError(resp.Errors, CodeTweetNotFound, fmt.Sprintf("không tìm thấy tweet với ID: %s", tweetID))
 	}
 
 	tweet := s.convertToTweet(&resp.Data)
@@ -594,13 +718,14 @@ func (s *TwitterService) GetTweetByID(ctx context.Context, tweetID string) (*mod
 }
 
 // GetLikedTweets lấy danh sách tweets mà user đã like
-func (s *TwitterService) GetLikedTweets(ctx context.Context, username string, maxResults int) (*models.LikedTweetsResponse, error) {
+func (s *TwitterService) GetLikedTweets(ctx context.Context, username string, maxResults int, paginationToken string) (*models.LikedTweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
 	}).Info("Đang lấy liked tweets")
 
-	user, err := s.GetUserByUsername(ctx, username)
+	user, err := s.resolveUser(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -611,6 +736,9 @@ func (s *TwitterService) GetLikedTweets(ctx context.Context, username string, ma
 	if maxResults > 100 {
 		maxResults = 100
 	}
+	if maxResults < minSearchResults {
+		maxResults = minSearchResults
+	}
 
 	params := &likeTypes.ListInput{
 		ID:         user.ID,
@@ -623,11 +751,12 @@ func (s *TwitterService) GetLikedTweets(ctx context.Context, username string, ma
 			fields.TweetFieldPublicMetrics,
 			fields.TweetFieldEntities,
 		},
+		PaginationToken: paginationToken,
 	}
 
-	resp, err := like.List(ctx, s.client, params)
+	resp, err := like.List(ctx, s.clientFor(ctx, "GET /2/users/{id}/liked_tweets"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy liked tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy liked tweets")
 	}
 
 	tweets := make([]models.Tweet, 0, len(resp.Data))
@@ -651,10 +780,11 @@ func (s *TwitterService) GetLikedTweets(ctx context.Context, username string, ma
 }
 
 // SearchUsers tìm kiếm users theo query
-func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResults int) (*models.SearchUsersResponse, error) {
+func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResults int, paginationToken string) (*models.SearchUsersResponse, error) {
 	log.WithFields(log.Fields{
 		"query":       query,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
 	}).Info("Đang tìm kiếm users")
 
 	if maxResults <= 0 {
@@ -663,6 +793,9 @@ func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResul
 	if maxResults > 100 {
 		maxResults = 100
 	}
+	if maxResults < minSearchResults {
+		maxResults = minSearchResults
+	}
 
 	// Twitter API v2 không hỗ trợ user search trực tiếp với Bearer token
 	// Thay vào đó, chúng ta sẽ tìm kiếm tweets với query và lấy unique authors
@@ -685,11 +818,12 @@ func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResul
 			fields.UserFieldCreatedAt,
 			fields.UserFieldPublicMetrics,
 		},
+		NextToken: paginationToken,
 	}
 
-	resp, err := searchtweet.ListRecent(ctx, s.client, searchParams)
+	resp, err := searchtweet.ListRecent(ctx, s.clientFor(ctx, "GET /2/tweets/search/recent"), searchParams)
 	if err != nil {
-		return nil, fmt.Errorf("không thể tìm kiếm users: %w", err)
+		return nil, wrapUpstreamError(err, "không thể tìm kiếm users")
 	}
 
 	// Extract unique users from includes
@@ -706,10 +840,12 @@ func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResul
 		users = append(users, *user)
 	}
 
+	// Trang tiếp theo là trang tweets tiếp theo của search, nên có thể lặp lại authors
 	result := &models.SearchUsersResponse{
 		Users: users,
 		Meta: &models.Meta{
 			ResultCount: len(users),
+			NextToken:   gotwi.StringValue(resp.Meta.NextToken),
 		},
 	}
 
@@ -722,13 +858,15 @@ func (s *TwitterService) SearchUsers(ctx context.Context, query string, maxResul
 }
 
 // GetUserMentions lấy danh sách tweets có mention đến user
-func (s *TwitterService) GetUserMentions(ctx context.Context, username string, maxResults int) (*models.MentionsResponse, error) {
+func (s *TwitterService) GetUserMentions(ctx context.Context, username string, maxResults int, paginationToken string, filter TweetFilter) (*models.MentionsResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
+		"filter":      filter.key(),
 	}).Info("Đang lấy mentions của user")
 
-	user, err := s.GetUserByUsername(ctx, username)
+	user, err := s.resolveUser(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -739,6 +877,9 @@ func (s *TwitterService) GetUserMentions(ctx context.Context, username string, m
 	if maxResults > 100 {
 		maxResults = 100
 	}
+	if maxResults < minTimelineResults {
+		maxResults = minTimelineResults
+	}
 
 	params := &timelineTypes.ListMentionsInput{
 		ID:         user.ID,
@@ -751,11 +892,16 @@ func (s *TwitterService) GetUserMentions(ctx context.Context, username string, m
 			fields.TweetFieldPublicMetrics,
 			fields.TweetFieldEntities,
 		},
+		PaginationToken: paginationToken,
+		StartTime:       filter.startTime(),
+		EndTime:         filter.endTime(),
+		SinceID:         filter.SinceID,
+		UntilID:         filter.UntilID,
 	}
 
-	resp, err := timeline.ListMentions(ctx, s.client, params)
+	resp, err := timeline.ListMentions(ctx, s.clientFor(ctx, "GET /2/users/{id}/mentions"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy mentions: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy mentions")
 	}
 
 	tweets := make([]models.Tweet, 0, len(resp.Data))
@@ -804,9 +950,9 @@ func (s *TwitterService) ListTweets(ctx context.Context, tweetIDs []string) (*mo
 		},
 	}
 
-	resp, err := tweetlookup.List(ctx, s.client, params)
+	resp, err := tweetlookup.List(ctx, s.clientFor(ctx, "GET /2/tweets"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách tweets")
 	}
 
 	tweets := make([]models.Tweet, 0, len(resp.Data))
@@ -861,13 +1007,14 @@ func (s *TwitterService) GetLikingUsers(ctx context.Context, tweetID string, max
 		params.PaginationToken = paginationToken
 	}
 
-	resp, err := like.ListUsers(ctx, s.client, params)
-	if err != nil {
-		// Kiểm tra nếu là lỗi 403, trả về thông báo rõ ràng hơn
-		if errStr := err.Error(); contains(errStr, "403") || contains(errStr, "Forbidden") {
-			return nil, fmt.Errorf("API liking users có thể yêu cầu OAuth 1.0a hoặc tweet không public. Bearer Token có giới hạn với API này. Lỗi: %w", err)
+	resp := &likingUsersOutput{}
+	if err := s.clientFor(ctx, "GET /2/tweets/{id}/liking_users").CallAPI(ctx, likingUsersEndpoint, "GET", params, resp); err != nil {
+		wrapped := wrapUpstreamError(err, "không thể lấy danh sách liking users")
+		// Lỗi 403: trả về thông báo rõ ràng hơn
+		if apiErr, ok := AsAPIError(wrapped); ok && apiErr.Kind == ErrorKindForbidden {
+			apiErr.Message = "API liking users có thể yêu cầu OAuth 1.0a hoặc tweet không public. Bearer Token có giới hạn với API này"
 		}
-		return nil, fmt.Errorf("không thể lấy danh sách liking users: %w", err)
+		return nil, wrapped
 	}
 
 	users := make([]models.User, 0, len(resp.Data))
@@ -878,9 +1025,7 @@ func (s *TwitterService) GetLikingUsers(ctx context.Context, tweetID string, max
 	result := &models.LikingUsersResponse{
 		TweetID: tweetID,
 		Users:   users,
-		Meta: &models.Meta{
-			ResultCount: len(users),
-		},
+		Meta:    buildMetaFromPagination(resp.Meta, len(users)),
 	}
 
 	log.WithFields(log.Fields{
@@ -892,10 +1037,11 @@ func (s *TwitterService) GetLikingUsers(ctx context.Context, tweetID string, max
 }
 
 // GetQuoteTweets lấy danh sách quote tweets của một tweet
-func (s *TwitterService) GetQuoteTweets(ctx context.Context, tweetID string, maxResults int) (*models.QuoteTweetsResponse, error) {
+func (s *TwitterService) GetQuoteTweets(ctx context.Context, tweetID string, maxResults int, paginationToken string) (*models.QuoteTweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"tweet_id":    tweetID,
 		"max_results": maxResults,
+		"page_token":  paginationToken,
 	}).Info("Đang lấy danh sách quote tweets")
 
 	if maxResults <= 0 {
@@ -904,6 +1050,9 @@ func (s *TwitterService) GetQuoteTweets(ctx context.Context, tweetID string, max
 	if maxResults > 100 {
 		maxResults = 100
 	}
+	if maxResults < minSearchResults {
+		maxResults = minSearchResults
+	}
 
 	params := &quotetweetTypes.ListInput{
 		ID:         tweetID,
@@ -917,11 +1066,12 @@ func (s *TwitterService) GetQuoteTweets(ctx context.Context, tweetID string, max
 			fields.TweetFieldEntities,
 			fields.TweetFieldReferencedTweets,
 		},
+		PaginationToken: paginationToken,
 	}
 
-	resp, err := quotetweet.List(ctx, s.client, params)
+	resp, err := quotetweet.List(ctx, s.clientFor(ctx, "GET /2/tweets/{id}/quote_tweets"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách quote tweets: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách quote tweets")
 	}
 
 	tweets := make([]models.Tweet, 0, len(resp.Data))
@@ -978,9 +1128,9 @@ func (s *TwitterService) GetRetweetedBy(ctx context.Context, tweetID string, max
 		params.PaginationToken = paginationToken
 	}
 
-	resp, err := retweet.ListUsers(ctx, s.client, params)
-	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách retweeted by: %w", err)
+	resp := &retweetedByOutput{}
+	if err := s.clientFor(ctx, "GET /2/tweets/{id}/retweeted_by").CallAPI(ctx, retweetedByEndpoint, "GET", params, resp); err != nil {
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách retweeted by")
 	}
 
 	users := make([]models.User, 0, len(resp.Data))
@@ -991,9 +1141,7 @@ func (s *TwitterService) GetRetweetedBy(ctx context.Context, tweetID string, max
 	result := &models.RetweetedByResponse{
 		TweetID: tweetID,
 		Users:   users,
-		Meta: &models.Meta{
-			ResultCount: len(users),
-		},
+		Meta:    buildMetaFromPagination(resp.Meta, len(users)),
 	}
 
 	log.WithFields(log.Fields{
@@ -1004,68 +1152,166 @@ func (s *TwitterService) GetRetweetedBy(ctx context.Context, tweetID string, max
 	return result, nil
 }
 
-// GetTweetCounts lấy số lượng tweets theo query và time range
-func (s *TwitterService) GetTweetCounts(ctx context.Context, query string, startTime, endTime string) (*models.TweetCountsResponse, error) {
+// GetTweetCounts lấy số lượng tweets theo query, time range và granularity
+func (s *TwitterService) GetTweetCounts(ctx context.Context, query string, opts TweetCountsOptions) (*models.TweetCountsResponse, error) {
 	log.WithFields(log.Fields{
-		"query":      query,
-		"start_time": startTime,
-		"end_time":   endTime,
+		"query":       query,
+		"start_time":  formatFilterTime(opts.StartTime),
+		"end_time":    formatFilterTime(opts.EndTime),
+		"granularity": opts.granularity(),
 	}).Info("Đang lấy tweet counts")
 
 	params := &tweetcountTypes.ListRecentInput{
-		Query: query,
+		Query:       query,
+		StartTime:   optionalTime(opts.StartTime),
+		EndTime:     optionalTime(opts.EndTime),
+		Granularity: tweetcountTypes.TweetCountsGranularity(opts.granularity()),
 	}
 
-	if startTime != "" {
-		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
-			params.StartTime = &t
-		}
+	resp, err := tweetcount.ListRecent(ctx, s.clientFor(ctx, "GET /2/tweets/counts/recent"), params)
+	if err != nil {
+		return nil, wrapUpstreamError(err, "không thể lấy tweet counts")
 	}
-	if endTime != "" {
-		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
-			params.EndTime = &t
-		}
+
+	counts := convertTweetCounts(resp.Data)
+	result := newTweetCountsResponse(query, opts.granularity(), counts)
+
+	log.WithFields(log.Fields{
+		"query":        query,
+		"counts_count": len(counts),
+		"total":        result.TotalTweetCount,
+	}).Info("Đã lấy tweet counts thành công")
+
+	return result, nil
+}
+
+// SearchAllTweets tìm kiếm tweets trong toàn bộ lịch sử (full-archive search)
+func (s *TwitterService) SearchAllTweets(ctx context.Context, query string, maxResults int, paginationToken string, filter TweetFilter) (*models.SearchTweetsResponse, error) {
+	log.WithFields(log.Fields{
+		"query":       query,
+		"max_results": maxResults,
+		"page_token":  paginationToken,
+		"filter":      filter.key(),
+	}).Info("Đang tìm kiếm tweets (full-archive)")
+
+	if maxResults <= 0 {
+		maxResults = s.config.DefaultTweetsCount
+	}
+	if maxResults > 500 {
+		maxResults = 500
+	}
+	if maxResults < minSearchResults {
+		maxResults = minSearchResults
+	}
+
+	params := &searchTypes.ListAllInput{
+		Query:      query,
+		MaxResults: searchTypes.ListMaxResults(maxResults),
+		TweetFields: fields.TweetFieldList{
+			fields.TweetFieldID,
+			fields.TweetFieldText,
+			fields.TweetFieldAuthorID,
+			fields.TweetFieldCreatedAt,
+			fields.TweetFieldPublicMetrics,
+			fields.TweetFieldEntities,
+			fields.TweetFieldReferencedTweets,
+		},
+		NextToken: paginationToken,
+		StartTime: filter.startTime(),
+		EndTime:   filter.endTime(),
+		SinceID:   filter.SinceID,
+		UntilID:   filter.UntilID,
 	}
 
-	resp, err := tweetcount.ListRecent(ctx, s.client, params)
+	resp, err := searchtweet.ListAll(ctx, s.clientFor(ctx, "GET /2/tweets/search/all"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy tweet counts: %w", err)
+		return nil, wrapArchiveError(err, "không thể tìm kiếm tweets full-archive")
 	}
 
-	counts := make([]models.TweetCount, 0, len(resp.Data))
+	tweets := make([]models.Tweet, 0, len(resp.Data))
 	for i := range resp.Data {
-		count := models.TweetCount{
-			TweetCount: gotwi.IntValue(resp.Data[i].TweetCount),
-		}
-		if resp.Data[i].Start != nil {
-			count.Start = gotwi.TimeValue(resp.Data[i].Start)
-		}
-		if resp.Data[i].End != nil {
-			count.End = gotwi.TimeValue(resp.Data[i].End)
-		}
-		counts = append(counts, count)
+		tweets = append(tweets, s.convertToTweet(&resp.Data[i]))
 	}
 
-	result := &models.TweetCountsResponse{
-		Query:   query,
-		Counts:  counts,
-		Meta: &models.Meta{
-			ResultCount: len(counts),
-		},
+	result := &models.SearchTweetsResponse{
+		Tweets: tweets,
+		Meta:   buildMetaFromPagination(resp.Meta, len(tweets)),
+	}
+
+	log.WithFields(log.Fields{
+		"query":        query,
+		"tweets_count": len(tweets),
+	}).Info("Đã tìm kiếm tweets full-archive thành công")
+
+	return result, nil
+}
+
+// GetAllTweetCounts lấy số lượng tweets trong toàn bộ lịch sử theo query, time range và
+// granularity. X API trả về tối đa 31 ngày mỗi trang, trang tiếp theo qua paginationToken.
+func (s *TwitterService) GetAllTweetCounts(ctx context.Context, query string, opts TweetCountsOptions, paginationToken string) (*models.TweetCountsResponse, error) {
+	log.WithFields(log.Fields{
+		"query":       query,
+		"start_time":  formatFilterTime(opts.StartTime),
+		"end_time":    formatFilterTime(opts.EndTime),
+		"granularity": opts.granularity(),
+		"page_token":  paginationToken,
+	}).Info("Đang lấy tweet counts (full-archive)")
+
+	params := &tweetcountTypes.ListAllInput{
+		Query:       query,
+		StartTime:   optionalTime(opts.StartTime),
+		EndTime:     optionalTime(opts.EndTime),
+		Granularity: tweetcountTypes.TweetCountsGranularity(opts.granularity()),
+		NextToken:   paginationToken,
+	}
+
+	resp, err := tweetcount.ListAll(ctx, s.clientFor(ctx, "GET /2/tweets/counts/all"), params)
+	if err != nil {
+		return nil, wrapArchiveError(err, "không thể lấy tweet counts full-archive")
 	}
 
+	counts := convertTweetCounts(resp.Data)
+	result := newTweetCountsResponse(query, opts.granularity(), counts)
+	result.Meta.NextToken = gotwi.StringValue(resp.Meta.NextToken)
+
 	log.WithFields(log.Fields{
 		"query":        query,
 		"counts_count": len(counts),
-	}).Info("Đã lấy tweet counts thành công")
+		"total":        result.TotalTweetCount,
+	}).Info("Đã lấy tweet counts full-archive thành công")
 
 	return result, nil
 }
 
+// convertTweetCounts chuyển các bucket của gotwi sang models.TweetCount
+func convertTweetCounts(data []resources.TweetCount) []models.TweetCount {
+	counts := make([]models.TweetCount, 0, len(data))
+	for i := range data {
+		count := models.TweetCount{
+			TweetCount: gotwi.IntValue(data[i].TweetCount),
+		}
+		if data[i].Start != nil {
+			count.Start = gotwi.TimeValue(data[i].Start)
+		}
+		if data[i].End != nil {
+			count.End = gotwi.TimeValue(data[i].End)
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
 // GetUserByID lấy thông tin user theo ID
 func (s *TwitterService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	log.WithField("user_id", userID).Info("Đang lấy thông tin user theo ID")
 
+	if !cacheBypassed(ctx) {
+		if user, ok := s.users.byID(userID); ok {
+			log.WithField("user_id", userID).Debug("Lấy thông tin user từ cache")
+			return user, nil
+		}
+	}
+
 	params := &userlookupTypes.GetInput{
 		ID: userID,
 		UserFields: fields.UserFieldList{
@@ -1080,16 +1326,17 @@ func (s *TwitterService) GetUserByID(ctx context.Context, userID string) (*model
 		},
 	}
 
-	resp, err := userlookup.Get(ctx, s.client, params)
+	resp, err := userlookup.Get(ctx, s.clientFor(ctx, "GET /2/users/{id}"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy thông tin user: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy thông tin user")
 	}
 
 	if resp.Data.ID == nil || gotwi.StringValue(resp.Data.ID) == "" {
-		return nil, fmt.Errorf("không tìm thấy user với ID: %s", userID)
+		return nil, lookupError(resp.Errors, CodeUserNotFound, fmt.Sprintf("không tìm thấy user với ID: %s", userID))
 	}
 
 	user := s.convertToUser(&resp.Data)
+	s.users.store(user)
 	log.WithFields(log.Fields{
 		"user_id":  user.ID,
 		"username": user.Username,
@@ -1125,14 +1372,16 @@ func (s *TwitterService) ListUsers(ctx context.Context, userIDs []string) (*mode
 		},
 	}
 
-	resp, err := userlookup.List(ctx, s.client, params)
+	resp, err := userlookup.List(ctx, s.clientFor(ctx, "GET /2/users"), params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy danh sách users: %w", err)
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách users")
 	}
 
 	users := make([]models.User, 0, len(resp.Data))
 	for i := range resp.Data {
-		users = append(users, *s.convertToUser(&resp.Data[i]))
+		user := s.convertToUser(&resp.Data[i])
+		s.users.store(user)
+		users = append(users, *user)
 	}
 
 	result := &models.UsersListResponse{
@@ -1146,7 +1395,9 @@ func (s *TwitterService) ListUsers(ctx context.Context, userIDs []string) (*mode
 	return result, nil
 }
 
-// GetMe lấy thông tin user hiện tại (authenticated user)
+// GetMe lấy thông tin user hiện tại (authenticated user). Khi có OAuth 1.0a user
+// context, request được ký bằng access token của user; nếu không, dùng Bearer Token
+// (chỉ thành công khi đó là OAuth 2.0 user access token).
 func (s *TwitterService) GetMe(ctx context.Context) (*models.User, error) {
 	log.Info("Đang lấy thông tin authenticated user")
 
@@ -1163,16 +1414,37 @@ func (s *TwitterService) GetMe(ctx context.Context) (*models.User, error) {
 		},
 	}
 
-	resp, err := userlookup.GetMe(ctx, s.client, params)
+	// Ưu tiên token của end user đang đăng nhập, sau đó OAuth 1.0a, cuối cùng Bearer Token
+	_, hasUserToken := userTokenFromContext(ctx)
+	client := s.clientFor(ctx, "GET /2/users/me")
+	if !hasUserToken && s.userClient != nil {
+		client = s.userClient
+	}
+	appOnly := !hasUserToken && s.userClient == nil
+
+	resp, err := userlookup.GetMe(ctx, client, params)
 	if err != nil {
-		return nil, fmt.Errorf("không thể lấy thông tin authenticated user: %w", err)
+		wrapped := wrapUpstreamError(err, "không thể lấy thông tin authenticated user")
+		// Với Bearer Token (app-only), X API trả 403 vì /users/me cần user context.
+		// Với user context, 403 là lỗi thật của X API (ví dụ app bị hạn chế) nên giữ nguyên.
+		if apiErr, ok := AsAPIError(wrapped); ok && apiErr.Kind == ErrorKindForbidden && appOnly {
+			apiErr.Code = CodeUserContextRequired
+			apiErr.Message = "API users/me yêu cầu OAuth user context, Bearer Token (app-only) không có authenticated user"
+		}
+		return nil, wrapped
 	}
 
+	// Authenticated user luôn tồn tại, payload rỗng là lỗi phía X API chứ không phải not found
 	if resp.Data.ID == nil || gotwi.StringValue(resp.Data.ID) == "" {
-		return nil, fmt.Errorf("không thể lấy thông tin authenticated user")
+		detail := ""
+		if len(resp.Errors) > 0 {
+			detail = gotwi.StringValue(resp.Errors[0].Detail)
+		}
+		return nil, &APIError{Kind: ErrorKindUpstream, Code: CodeUpstreamError, Message: "X API không trả về thông tin authenticated user", Detail: detail}
 	}
 
 	user := s.convertToUser(&resp.Data)
+	s.users.store(user)
 	log.WithFields(log.Fields{
 		"user_id":  user.ID,
 		"username": user.Username,
@@ -1181,9 +1453,8 @@ func (s *TwitterService) GetMe(ctx context.Context) (*models.User, error) {
 	return user, nil
 }
 
-// GetBlockingUsers lấy danh sách users bị block
-// Lưu ý: API này yêu cầu OAuth 1.0a với authenticated user context
-// Chỉ có thể xem blocking list của chính authenticated user, không thể xem của user khác
+// GetBlockingUsers lấy danh sách users bị block, gọi bằng OAuth 1.0a user context.
+// X API chỉ cho xem blocking list của chính authenticated user, username khác trả về 403.
 func (s *TwitterService) GetBlockingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.BlockingUsersResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
@@ -1191,15 +1462,63 @@ func (s *TwitterService) GetBlockingUsers(ctx context.Context, username string,
 		"page_token":  paginationToken,
 	}).Info("Đang lấy danh sách blocking users")
 
-	// Blocking API chỉ hoạt động với authenticated user (OAuth 1.0a)
-	// Với Bearer Token, chúng ta chỉ có thể lấy blocking list của authenticated user
-	// Tạm thời trả về lỗi thông báo rõ ràng
-	return nil, fmt.Errorf("API blocking users yêu cầu OAuth 1.0a với authenticated user context. Bearer Token chỉ hỗ trợ xem blocking list của chính authenticated user. Vui lòng sử dụng OAuth 1.0a để truy cập API này")
+	client, err := s.userContextClient(ctx, "blocking users")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxResults <= 0 {
+		maxResults = s.config.DefaultTweetsCount
+	}
+	if maxResults > 1000 {
+		maxResults = 1000
+	}
+
+	params := &blockTypes.ListInput{
+		ID:              user.ID,
+		MaxResults:      blockTypes.ListMaxResults(maxResults),
+		PaginationToken: paginationToken,
+		UserFields: fields.UserFieldList{
+			fields.UserFieldID,
+			fields.UserFieldName,
+			fields.UserFieldUsername,
+			fields.UserFieldDescription,
+			fields.UserFieldProfileImageUrl,
+			fields.UserFieldVerified,
+			fields.UserFieldCreatedAt,
+			fields.UserFieldPublicMetrics,
+		},
+	}
+
+	resp, err := block.List(ctx, client, params)
+	if err != nil {
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách blocking users")
+	}
+
+	users := make([]models.User, 0, len(resp.Data))
+	for i := range resp.Data {
+		users = append(users, *s.convertToUser(&resp.Data[i]))
+	}
+
+	log.WithFields(log.Fields{
+		"username":       username,
+		"blocking_count": len(users),
+	}).Info("Đã lấy danh sách blocking users thành công")
+
+	return &models.BlockingUsersResponse{
+		User:  user,
+		Users: users,
+		Meta:  buildMetaFromPagination(resp.Meta, len(users)),
+	}, nil
 }
 
-// GetMutingUsers lấy danh sách users bị mute
-// Lưu ý: API này yêu cầu OAuth 1.0a với authenticated user context
-// Chỉ có thể xem muting list của chính authenticated user, không thể xem của user khác
+// GetMutingUsers lấy danh sách users bị mute, gọi bằng OAuth 1.0a user context.
+// X API chỉ cho xem muting list của chính authenticated user, username khác trả về 403.
 func (s *TwitterService) GetMutingUsers(ctx context.Context, username string, maxResults int, paginationToken string) (*models.MutingUsersResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
@@ -1207,31 +1526,91 @@ func (s *TwitterService) GetMutingUsers(ctx context.Context, username string, ma
 		"page_token":  paginationToken,
 	}).Info("Đang lấy danh sách muting users")
 
-	// Muting API chỉ hoạt động với authenticated user (OAuth 1.0a)
-	// Với Bearer Token, chúng ta chỉ có thể lấy muting list của authenticated user
-	// Tạm thời trả về lỗi thông báo rõ ràng
-	return nil, fmt.Errorf("API muting users yêu cầu OAuth 1.0a với authenticated user context. Bearer Token chỉ hỗ trợ xem muting list của chính authenticated user. Vui lòng sử dụng OAuth 1.0a để truy cập API này")
+	client, err := s.userContextClient(ctx, "muting users")
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxResults <= 0 {
+		maxResults = s.config.DefaultTweetsCount
+	}
+	if maxResults > 1000 {
+		maxResults = 1000
+	}
+
+	params := &muteTypes.ListsInput{
+		ID:              user.ID,
+		MaxResults:      muteTypes.ListMaxResults(maxResults),
+		PaginationToken: paginationToken,
+		UserFields: fields.UserFieldList{
+			fields.UserFieldID,
+			fields.UserFieldName,
+			fields.UserFieldUsername,
+			fields.UserFieldDescription,
+			fields.UserFieldProfileImageUrl,
+			fields.UserFieldVerified,
+			fields.UserFieldCreatedAt,
+			fields.UserFieldPublicMetrics,
+		},
+	}
+
+	resp, err := mute.Lists(ctx, client, params)
+	if err != nil {
+		return nil, wrapUpstreamError(err, "không thể lấy danh sách muting users")
+	}
+
+	users := make([]models.User, 0, len(resp.Data))
+	for i := range resp.Data {
+		users = append(users, *s.convertToUser(&resp.Data[i]))
+	}
+
+	log.WithFields(log.Fields{
+		"username":     username,
+		"muting_count": len(users),
+	}).Info("Đã lấy danh sách muting users thành công")
+
+	return &models.MutingUsersResponse{
+		User:  user,
+		Users: users,
+		Meta:  buildMetaFromPagination(resp.Meta, len(users)),
+	}, nil
 }
 
-// HideTweet ẩn/hiện một tweet (chỉ áp dụng cho authenticated user's tweets)
-// Lưu ý: Twitter API v2 với Bearer Token không hỗ trợ hide tweet trực tiếp
-// Endpoint này yêu cầu OAuth 1.0a với write permissions
+// HideTweet ẩn/hiện một reply trong conversation do authenticated user bắt đầu,
+// gọi bằng OAuth 1.0a user context (app cần quyền write)
 func (s *TwitterService) HideTweet(ctx context.Context, tweetID string, hidden bool) (*models.HideTweetResponse, error) {
 	log.WithFields(log.Fields{
 		"tweet_id": tweetID,
 		"hidden":   hidden,
 	}).Info("Đang thay đổi trạng thái hidden của tweet")
 
-	// Twitter API v2 không hỗ trợ hide tweet với Bearer Token
-	// Cần OAuth 1.0a với write permissions
-	// Tạm thời trả về thông báo lỗi
-	return nil, fmt.Errorf("API hide tweet không được hỗ trợ với Bearer Token. Cần OAuth 1.0a với write permissions")
+	client, err := s.userContextClient(ctx, "hide tweet")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := hidereply.Update(ctx, client, &hidereplyTypes.UpdateInput{ID: tweetID, Hidden: hidden})
+	if err != nil {
+		return nil, wrapUpstreamError(err, "không thể thay đổi trạng thái hidden của tweet")
+	}
+
+	log.WithFields(log.Fields{
+		"tweet_id": tweetID,
+		"hidden":   resp.Data.Hidden,
+	}).Info("Đã thay đổi trạng thái hidden của tweet thành công")
+
+	return &models.HideTweetResponse{TweetID: tweetID, Hidden: resp.Data.Hidden}, nil
 }
 
 // GetUserTimelineReverseChronological lấy timeline reverse chronological của user
 // Lưu ý: API này yêu cầu OAuth 1.0a với authenticated user context cho reverse chronological
 // Với Bearer Token, chúng ta sử dụng GetUserTweets (cũng là reverse chronological)
-func (s *TwitterService) GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int) (*models.TweetsResponse, error) {
+func (s *TwitterService) GetUserTimelineReverseChronological(ctx context.Context, username string, maxResults int, paginationToken string) (*models.TweetsResponse, error) {
 	log.WithFields(log.Fields{
 		"username":    username,
 		"max_results": maxResults,
@@ -1240,15 +1619,92 @@ func (s *TwitterService) GetUserTimelineReverseChronological(ctx context.Context
 	// Sử dụng cùng method với GetUserTweets vì đó là reverse chronological
 	// Timeline reverse chronological endpoint yêu cầu OAuth 1.0a
 	// Nhưng GetUserTweets cũng trả về reverse chronological nên có thể dùng được
-	return s.GetUserTweets(ctx, username, maxResults)
+	return s.GetUserTweets(ctx, username, maxResults, paginationToken, TweetFilter{})
+}
+
+// GetRepostsOfMe lấy các tweets của authenticated user đã được người khác repost,
+// gọi bằng OAuth 1.0a user context. User của response lấy từ author của tweets
+// (expansion author_id), nên là nil khi không có tweet nào.
+func (s *TwitterService) GetRepostsOfMe(ctx context.Context, maxResults int, paginationToken string) (*models.RepostsResponse, error) {
+	log.WithFields(log.Fields{
+		"max_results": maxResults,
+		"page_token":  paginationToken,
+	}).Info("Đang lấy reposts của authenticated user")
+
+	client, err := s.userContextClient(ctx, "reposts_of_me")
+	if err != nil {
+		return nil, err
+	}
+
+	if maxResults <= 0 {
+		maxResults = s.config.DefaultTweetsCount
+	}
+	if maxResults > 100 {
+		maxResults = 100
+	}
+
+	params := &repostsOfMeInput{
+		MaxResults:      maxResults,
+		PaginationToken: paginationToken,
+		TweetFields: fields.TweetFieldList{
+			fields.TweetFieldID,
+			fields.TweetFieldText,
+			fields.TweetFieldAuthorID,
+			fields.TweetFieldCreatedAt,
+			fields.TweetFieldPublicMetrics,
+			fields.TweetFieldEntities,
+			fields.TweetFieldReferencedTweets,
+		},
+		Expansions: fields.ExpansionList{
+			fields.ExpansionAuthorID,
+		},
+		UserFields: fields.UserFieldList{
+			fields.UserFieldID,
+			fields.UserFieldName,
+			fields.UserFieldUsername,
+			fields.UserFieldDescription,
+			fields.UserFieldProfileImageUrl,
+			fields.UserFieldVerified,
+			fields.UserFieldCreatedAt,
+			fields.UserFieldPublicMetrics,
+		},
+	}
+
+	resp := &repostsOfMeOutput{}
+	if err := client.CallAPI(ctx, repostsOfMeEndpoint, "GET", params, resp); err != nil {
+		return nil, wrapUpstreamError(err, "không thể lấy reposts của authenticated user")
+	}
+
+	tweets := make([]models.Tweet, 0, len(resp.Data))
+	for i := range resp.Data {
+		tweets = append(tweets, s.convertToTweet(&resp.Data[i]))
+	}
+
+	result := &models.RepostsResponse{
+		Tweets: tweets,
+		Meta:   buildMetaFromPagination(resp.Meta, len(tweets)),
+	}
+	if len(resp.Includes.Users) > 0 {
+		result.User = s.convertToUser(&resp.Includes.Users[0])
+		s.users.store(result.User)
+	}
+
+	log.WithField("tweets_count", len(tweets)).Info("Đã lấy reposts của authenticated user thành công")
+	return result, nil
+}
+
+// GetRateLimits trả về quota còn lại của X API theo endpoint family (cộng dồn mọi
+// Bearer Token), dựa trên x-rate-limit-* headers của các response gần nhất
+func (s *TwitterService) GetRateLimits(ctx context.Context) (*models.RateLimitsResponse, error) {
+	return &models.RateLimitsResponse{RateLimits: s.rateLimitSnapshot()}, nil
 }
 
-// GetRepostsOfMe lấy danh sách reposts của authenticated user
-// Lưu ý: API này yêu cầu OAuth 1.0a với authenticated user context
-func (s *TwitterService) GetRepostsOfMe(ctx context.Context, maxResults int) (*models.RepostsResponse, error) {
-	log.WithField("max_results", maxResults).Info("Đang lấy reposts của authenticated user")
+// GetTokenPool trả về mức sử dụng, rate limit và trạng thái cách ly của từng Bearer Token
+func (s *TwitterService) GetTokenPool(ctx context.Context) (*models.TokenPoolResponse, error) {
+	return &models.TokenPoolResponse{Tokens: s.tokens.Snapshot()}, nil
+}
 
-	// Reposts Of Me API yêu cầu OAuth 1.0a với authenticated user context
-	// Bearer Token không hỗ trợ API này
-	return nil, fmt.Errorf("API reposts_of_me yêu cầu OAuth 1.0a với authenticated user context. Bearer Token không hỗ trợ API này. Vui lòng sử dụng OAuth 1.0a để truy cập")
+// GetCircuitBreakers trả về trạng thái circuit breaker theo endpoint family
+func (s *TwitterService) GetCircuitBreakers(ctx context.Context) (*models.CircuitBreakersResponse, error) {
+	return &models.CircuitBreakersResponse{CircuitBreakers: s.breakers.Snapshot()}, nil
 }
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/mockserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Chạy `go test ./services -run Cassette -record-cassettes` để ghi lại cassettes từ
+// mock server (mockserver/fixtures); mặc định tests replay testdata/cassettes.
+var recordCassettes = flag.Bool("record-cassettes", false, "ghi lại testdata/cassettes từ mock X API server")
+
+const (
+	testCassetteDir  = "testdata/cassettes"
+	testBearerToken  = "test-bearer-token"
+	testAccessToken  = "test-access-token"
+	testTokenSecret  = "test-access-token-secret"
+	testAliceID      = "1000000000000000002"
+	testDevelopersID = "2244994945"
+)
+
+func init() {
+	log.SetOutput(io.Discard)
+}
+
+// newCassetteTwitterService tạo TwitterService chạy trên cassettes; ở chế độ record,
+// requests được gửi tới mock server in-process
+func newCassetteTwitterService(t *testing.T) *TwitterService {
+	t.Helper()
+	return newCassetteTwitterServiceWith(t, mockserver.Options{})
+}
+
+// newCassetteTwitterServiceWith như newCassetteTwitterService, opts cấu hình mock server
+// khi record (BearerToken luôn là testBearerToken). opts.AccessToken khác rỗng thì
+// service được cấu hình thêm OAuth 1.0a user context với access token đó.
+func newCassetteTwitterServiceWith(t *testing.T, opts mockserver.Options) *TwitterService {
+	t.Helper()
+
+	cfg := &config.Config{
+		TwitterBearerToken:      testBearerToken,
+		TwitterHTTPMode:         HTTPModeReplay,
+		TwitterCassetteDir:      testCassetteDir,
+		TwitterRetryMaxAttempts: 1,
+		MaxTweetsPerRequest:     100,
+		DefaultTweetsCount:      10,
+	}
+	if opts.AccessToken != "" {
+		cfg.TwitterConsumerKey = "test-consumer-key"
+		cfg.TwitterConsumerSecret = "test-consumer-secret"
+		cfg.TwitterAccessToken = opts.AccessToken
+		cfg.TwitterAccessTokenSecret = testTokenSecret
+	}
+
+	if *recordCassettes {
+		fixtures, err := mockserver.LoadFixtures("../mockserver/fixtures")
+		if err != nil {
+			t.Fatalf("không load được fixtures: %v", err)
+		}
+		opts.BearerToken = testBearerToken
+		server := httptest.NewServer(mockserver.New(fixtures, opts))
+		t.Cleanup(server.Close)
+
+		cfg.TwitterHTTPMode = HTTPModeRecord
+		cfg.TwitterAPIBaseURL = server.URL
+	}
+
+	svc, err := NewTwitterService(cfg)
+	if err != nil {
+		t.Fatalf("NewTwitterService: %v", err)
+	}
+	return svc
+}
+
+func TestCassetteConvertToUser(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+
+	user, err := svc.GetUserByUsername(context.Background(), "XDevelopers")
+	if err != nil {
+		t.Fatalf("GetUserByUsername: %v", err)
+	}
+
+	if user.ID != testDevelopersID || user.Username != "XDevelopers" || user.Name != "X Developers" {
+		t.Errorf("user = %+v", user)
+	}
+	if !user.Verified {
+		t.Error("Verified = false, want true")
+	}
+	if user.Description != "The voice of the X Developer Platform" {
+		t.Errorf("Description = %q", user.Description)
+	}
+	if user.ProfileImageURL == "" {
+		t.Error("ProfileImageURL rỗng")
+	}
+	if want := time.Date(2013, 12, 14, 4, 35, 55, 0, time.UTC); !user.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", user.CreatedAt, want)
+	}
+	if user.Metrics == nil || user.Metrics.FollowersCount != 583000 || user.Metrics.FollowingCount != 2000 ||
+		user.Metrics.TweetCount != 4200 || user.Metrics.ListedCount != 1900 {
+		t.Errorf("Metrics = %+v", user.Metrics)
+	}
+}
+
+func TestCassetteConvertToTweet(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	ctx := context.Background()
+
+	// Tweet có URL entity
+	detail, err := svc.GetTweetByID(ctx, "1792000000498498000")
+	if err != nil {
+		t.Fatalf("GetTweetByID: %v", err)
+	}
+	tweet := detail.Tweet
+	if tweet.AuthorID != testDevelopersID || tweet.Text == "" {
+		t.Errorf("tweet = %+v", tweet)
+	}
+	if want := time.Date(2024, 5, 21, 13, 27, 0, 0, time.UTC); !tweet.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", tweet.CreatedAt, want)
+	}
+	if tweet.Metrics == nil || tweet.Metrics.RetweetCount != 30 || tweet.Metrics.ReplyCount != 52 ||
+		tweet.Metrics.LikeCount != 2316 || tweet.Metrics.QuoteCount != 7 {
+		t.Errorf("Metrics = %+v", tweet.Metrics)
+	}
+	if tweet.Entities == nil || len(tweet.Entities.URLs) != 1 ||
+		tweet.Entities.URLs[0].ExpandedURL != "https://developer.x.com/en/docs/x-api/tweets/counts" {
+		t.Errorf("Entities = %+v", tweet.Entities)
+	}
+	if detail.Author == nil || detail.Author.ID != testDevelopersID {
+		t.Errorf("Author = %+v", detail.Author)
+	}
+
+	// Tweet có hashtag, mention và referenced tweet
+	list, err := svc.ListTweets(ctx, []string{"1792000002433749000", "1792000009845233000"})
+	if err != nil {
+		t.Fatalf("ListTweets: %v", err)
+	}
+	if len(list.Tweets) != 2 {
+		t.Fatalf("len(Tweets) = %d, want 2", len(list.Tweets))
+	}
+
+	tagged := list.Tweets[0]
+	if tagged.Entities == nil || len(tagged.Entities.Hashtags) != 1 || tagged.Entities.Hashtags[0].Tag != "golang" {
+		t.Errorf("Hashtags = %+v", tagged.Entities)
+	}
+	// gotwi decode mentions thành TweetEntityTag (không có field username) nên chỉ kiểm tra số lượng
+	if tagged.Entities == nil || len(tagged.Entities.Mentions) != 1 {
+		t.Errorf("Mentions = %+v", tagged.Entities)
+	}
+
+	reply := list.Tweets[1]
+	if len(reply.ReferencedTweets) != 1 || reply.ReferencedTweets[0].Type != "replied_to" ||
+		reply.ReferencedTweets[0].ID != "1792000003148200000" {
+		t.Errorf("ReferencedTweets = %+v", reply.ReferencedTweets)
+	}
+}
+
+func TestCassetteBuildMetaFromTimeline(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+
+	resp, err := svc.GetUserTweets(context.Background(), "alice_dev", 5, "", TweetFilter{})
+	if err != nil {
+		t.Fatalf("GetUserTweets: %v", err)
+	}
+	if len(resp.Tweets) != 5 {
+		t.Fatalf("len(Tweets) = %d, want 5", len(resp.Tweets))
+	}
+	if resp.Meta == nil || resp.Meta.ResultCount != 5 || resp.Meta.NextToken == "" {
+		t.Errorf("Meta = %+v, want result_count 5 và next_token", resp.Meta)
+	}
+	for _, tweet := range resp.Tweets {
+		if tweet.AuthorID != resp.User.ID {
+			t.Errorf("tweet %s có author %s", tweet.ID, tweet.AuthorID)
+		}
+	}
+}
+
+func TestCassetteBuildMetaFromPagination(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	ctx := context.Background()
+
+	first, err := svc.GetUserFollowing(ctx, "alice_dev", 2, "")
+	if err != nil {
+		t.Fatalf("GetUserFollowing: %v", err)
+	}
+	if len(first.Following) != 2 || first.Meta == nil || first.Meta.ResultCount != 2 || first.Meta.NextToken == "" {
+		t.Fatalf("trang đầu: %d users, Meta = %+v", len(first.Following), first.Meta)
+	}
+	if first.Meta.PreviousToken != "" {
+		t.Errorf("trang đầu có PreviousToken = %q", first.Meta.PreviousToken)
+	}
+
+	second, err := svc.GetUserFollowing(ctx, "alice_dev", 2, first.Meta.NextToken)
+	if err != nil {
+		t.Fatalf("GetUserFollowing trang 2: %v", err)
+	}
+	if second.Meta == nil || second.Meta.PreviousToken == "" {
+		t.Errorf("trang 2: Meta = %+v, want previous_token", second.Meta)
+	}
+	if len(second.Following) > 0 && second.Following[0].ID == first.Following[0].ID {
+		t.Error("trang 2 trùng với trang đầu")
+	}
+}
+
+func TestCassetteBuildMetaFromQuoteTweets(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+
+	resp, err := svc.GetQuoteTweets(context.Background(), "1792000000340563000", 10, "")
+	if err != nil {
+		t.Fatalf("GetQuoteTweets: %v", err)
+	}
+	if len(resp.Tweets) != 2 || resp.Meta == nil || resp.Meta.ResultCount != 2 {
+		t.Fatalf("%d tweets, Meta = %+v, want 2", len(resp.Tweets), resp.Meta)
+	}
+	for _, tweet := range resp.Tweets {
+		if len(tweet.ReferencedTweets) != 1 || tweet.ReferencedTweets[0].Type != "quoted" {
+			t.Errorf("tweet %s: ReferencedTweets = %+v", tweet.ID, tweet.ReferencedTweets)
+		}
+	}
+}
+
+func TestCassettePaginationTimeline(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	ctx := context.Background()
+
+	first, err := svc.GetUserTweets(ctx, "alice_dev", 5, "", TweetFilter{})
+	if err != nil {
+		t.Fatalf("GetUserTweets: %v", err)
+	}
+	second, err := svc.GetUserTweets(ctx, "alice_dev", 5, first.Meta.NextToken, TweetFilter{})
+	if err != nil {
+		t.Fatalf("GetUserTweets trang 2: %v", err)
+	}
+	if len(second.Tweets) == 0 || second.Tweets[0].ID == first.Tweets[0].ID {
+		t.Fatalf("trang 2 = %+v, want tweets khác trang đầu", second.Tweets)
+	}
+	if second.Meta == nil || second.Meta.PreviousToken == "" {
+		t.Errorf("trang 2: Meta = %+v, want previous_token", second.Meta)
+	}
+}
+
+func TestCassettePaginationLikingUsers(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+
+	// gotwi bỏ field meta của liking_users; TwitterService phải giữ lại result_count
+	resp, err := svc.GetLikingUsers(context.Background(), "1792000000340563000", 10, "")
+	if err != nil {
+		t.Fatalf("GetLikingUsers: %v", err)
+	}
+	if resp.Meta == nil || resp.Meta.ResultCount != len(resp.Users) {
+		t.Errorf("Meta = %+v, %d users", resp.Meta, len(resp.Users))
+	}
+}
+
+func TestCassetteSearchAll(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	filter := TweetFilter{
+		StartTime: time.Date(2024, 5, 21, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 5, 25, 0, 0, 0, 0, time.UTC),
+	}
+
+	resp, err := svc.SearchAllTweets(context.Background(), "#golang", 10, "", filter)
+	if err != nil {
+		t.Fatalf("SearchAllTweets: %v", err)
+	}
+	if len(resp.Tweets) == 0 || resp.Meta == nil || resp.Meta.ResultCount != len(resp.Tweets) {
+		t.Fatalf("%d tweets, Meta = %+v", len(resp.Tweets), resp.Meta)
+	}
+	for _, tweet := range resp.Tweets {
+		if !filter.Match(&tweet) {
+			t.Errorf("tweet %s (%s) nằm ngoài khoảng thời gian", tweet.ID, tweet.CreatedAt)
+		}
+	}
+}
+
+func TestCassetteAllTweetCounts(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	opts := TweetCountsOptions{
+		StartTime:   time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC),
+		EndTime:     time.Date(2024, 5, 28, 0, 0, 0, 0, time.UTC),
+		Granularity: GranularityDay,
+	}
+
+	resp, err := svc.GetAllTweetCounts(context.Background(), "#golang", opts, "")
+	if err != nil {
+		t.Fatalf("GetAllTweetCounts: %v", err)
+	}
+	if len(resp.Counts) != 8 || resp.Granularity != GranularityDay || resp.TotalTweetCount == 0 {
+		t.Fatalf("%d buckets, granularity = %q, total = %d", len(resp.Counts), resp.Granularity, resp.TotalTweetCount)
+	}
+	if resp.Peak == nil || !resp.Peak.End.Equal(resp.Peak.Start.Add(24*time.Hour)) {
+		t.Errorf("Peak = %+v, want một bucket 1 ngày", resp.Peak)
+	}
+}
+
+func TestCassetteFullArchiveAccessRequired(t *testing.T) {
+	svc := newCassetteTwitterServiceWith(t, mockserver.Options{DenyFullArchive: true})
+
+	_, err := svc.SearchAllTweets(context.Background(), "from:XDevelopers", 10, "", TweetFilter{})
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Kind != ErrorKindForbidden || apiErr.Code != CodeArchiveAccessRequired || apiErr.UpstreamStatus != 403 {
+		t.Fatalf("err = %#v, want 403 %s", err, CodeArchiveAccessRequired)
+	}
+	if apiErr.Detail == "" {
+		t.Error("Detail rỗng, want title/detail từ X API")
+	}
+}
+
+func TestCassetteUserContext(t *testing.T) {
+	svc := newCassetteTwitterServiceWith(t, mockserver.Options{AccessToken: testAccessToken})
+	ctx := context.Background()
+
+	me, err := svc.GetMe(ctx)
+	if err != nil || me.ID != testAliceID {
+		t.Fatalf("GetMe = %+v, %v; want alice_dev", me, err)
+	}
+
+	blocking, err := svc.GetBlockingUsers(ctx, "alice_dev", 10, "")
+	if err != nil {
+		t.Fatalf("GetBlockingUsers: %v", err)
+	}
+	if blocking.User.ID != testAliceID || len(blocking.Users) != 1 || blocking.Users[0].Username != "statusbot" || blocking.Meta.ResultCount != 1 {
+		t.Errorf("blocking = %+v, users = %+v", blocking, blocking.Users)
+	}
+
+	muting, err := svc.GetMutingUsers(ctx, "alice_dev", 1, "")
+	if err != nil {
+		t.Fatalf("GetMutingUsers: %v", err)
+	}
+	if len(muting.Users) != 1 || muting.Users[0].Username != "carol_le" || muting.Meta.NextToken == "" {
+		t.Errorf("muting trang đầu = %+v, meta = %+v", muting.Users, muting.Meta)
+	}
+
+	reposts, err := svc.GetRepostsOfMe(ctx, 10, "")
+	if err != nil {
+		t.Fatalf("GetRepostsOfMe: %v", err)
+	}
+	if len(reposts.Tweets) != 1 || reposts.Tweets[0].ID != "1792000003148200000" || reposts.User == nil || reposts.User.ID != testAliceID {
+		t.Errorf("reposts = %+v, user = %+v", reposts.Tweets, reposts.User)
+	}
+
+	hidden, err := svc.HideTweet(ctx, "1792000009845233000", true)
+	if err != nil || !hidden.Hidden || hidden.TweetID != "1792000009845233000" {
+		t.Errorf("HideTweet = %+v, %v", hidden, err)
+	}
+}
+
+func TestCassetteUserContextForbidden(t *testing.T) {
+	svc := newCassetteTwitterServiceWith(t, mockserver.Options{AccessToken: testAccessToken})
+
+	// X API chỉ cho xem blocking list của chính authenticated user
+	_, err := svc.GetBlockingUsers(context.Background(), "XDevelopers", 10, "")
+	apiErr, ok := AsAPIError(err)
+	if !ok || apiErr.Kind != ErrorKindForbidden || apiErr.Code == CodeUserContextRequired || apiErr.UpstreamStatus != 403 {
+		t.Errorf("err = %#v, want 403 từ X API", err)
+	}
+
+	// Không phải reply trong conversation của authenticated user
+	_, err = svc.HideTweet(context.Background(), "1792000003148200000", true)
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Kind != ErrorKindForbidden {
+		t.Errorf("err = %#v, want 403 từ X API", err)
+	}
+}
+
+func TestBearerOnlyUserContextRequired(t *testing.T) {
+	svc := newCassetteTwitterService(t)
+	ctx := context.Background()
+
+	// Không có OAuth 1.0a credentials: trả lỗi ngay, không gọi X API (replay sẽ
+	// báo lỗi cassette nếu có request)
+	calls := map[string]func() error{
+		"GetBlockingUsers": func() error { _, err := svc.GetBlockingUsers(ctx, "alice_dev", 10, ""); return err },
+		"GetMutingUsers":   func() error { _, err := svc.GetMutingUsers(ctx, "alice_dev", 10, ""); return err },
+		"HideTweet":        func() error { _, err := svc.HideTweet(ctx, "1792000009845233000", true); return err },
+		"GetRepostsOfMe":   func() error { _, err := svc.GetRepostsOfMe(ctx, 10, ""); return err },
+	}
+	for name, call := range calls {
+		apiErr, ok := AsAPIError(call())
+		if !ok || apiErr.Code != CodeUserContextRequired || apiErr.Err != nil {
+			t.Errorf("%s: err = %#v, want %s", name, apiErr, CodeUserContextRequired)
+		}
+	}
+
+	// users/me vẫn được gửi với Bearer Token; 403 app-only được đổi thành USER_CONTEXT_REQUIRED
+	_, err := svc.GetMe(ctx)
+	if apiErr, ok := AsAPIError(err); !ok || apiErr.Code != CodeUserContextRequired || apiErr.UpstreamStatus != 403 {
+		t.Errorf("GetMe: err = %#v, want 403 %s", err, CodeUserContextRequired)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+	"x-twitter-backend/metrics"
+	"x-twitter-backend/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var userCacheRequests = metrics.NewCounter("x_user_cache_requests_total",
+	"Số lần tra cứu cache user theo loại (username, id) và kết quả (hit, miss)",
+	"lookup", "result")
+
+// userCacheEntriesPerUser là số entry LRU dùng cho một user: username -> user ID,
+// id -> username (để phát hiện đổi handle) và id -> profile
+const userCacheEntriesPerUser = 3
+
+// userCache lưu ánh xạ username -> user ID để các method theo username (timelines,
+// following, followers, ...) không phải gọi users lookup mỗi request. Username được
+// so khớp không phân biệt hoa thường.
+//
+// Ánh xạ username -> ID sống lâu (ttl), còn profile đầy đủ (metrics, bio, ...) chỉ
+// được giữ trong profileTTL để response không chứa profile quá cũ. Ánh xạ và profile
+// là hai entry riêng: cache chỉ trả về profile đầy đủ, không bao giờ trả về user chỉ
+// có ID.
+type userCache struct {
+	ttl        time.Duration
+	profileTTL time.Duration
+	lru        *lruCache
+}
+
+// newUserCache tạo cache cho tối đa maxEntries users; ttl <= 0 tắt cache.
+// profileTTL bị giới hạn không vượt quá ttl; profileTTL <= 0 tắt cache profile.
+func newUserCache(maxEntries int, ttl, profileTTL time.Duration) *userCache {
+	if profileTTL > ttl {
+		profileTTL = ttl
+	}
+	return &userCache{
+		ttl:        ttl,
+		profileTTL: profileTTL,
+		lru:        newLRUCache(maxEntries * userCacheEntriesPerUser),
+	}
+}
+
+func (c *userCache) enabled() bool {
+	return c.ttl > 0
+}
+
+// byUsername trả về profile đầy đủ còn trong profileTTL theo username. Profile đã hết
+// hạn là miss để caller lấy lại từ X API (ánh xạ username -> ID vẫn được giữ).
+func (c *userCache) byUsername(username string) (*models.User, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	name := normalizeUsername(username)
+	id, ok := c.userID(name)
+	if !ok {
+		userCacheRequests.Inc("username", "miss")
+		return nil, false
+	}
+	profile, ok := c.getUser("profile:" + id)
+	if !ok {
+		userCacheRequests.Inc("username", "miss")
+		return nil, false
+	}
+	userCacheRequests.Inc("username", "hit")
+	return profile, true
+}
+
+// userID trả về user ID đã cache của username (đã normalize); ánh xạ bị xóa nếu ánh
+// xạ ngược đã hết hạn hoặc user đã đổi username
+func (c *userCache) userID(name string) (string, bool) {
+	id, ok := c.lru.Get("username:" + name)
+	if !ok {
+		return "", false
+	}
+	if current, ok := c.lru.Get("id:" + string(id)); !ok || string(current) != name {
+		c.lru.Delete("username:" + name)
+		return "", false
+	}
+	return string(id), true
+}
+
+// byID trả về profile đầy đủ còn trong profileTTL theo user ID
+func (c *userCache) byID(id string) (*models.User, bool) {
+	if !c.enabled() || c.profileTTL <= 0 {
+		return nil, false
+	}
+
+	profile, ok := c.getUser("profile:" + id)
+	if !ok {
+		userCacheRequests.Inc("id", "miss")
+		return nil, false
+	}
+	userCacheRequests.Inc("id", "hit")
+	return profile, true
+}
+
+// getUser đọc và decode user đã cache theo key, không ghi metrics
+func (c *userCache) getUser(key string) (*models.User, bool) {
+	data, ok := c.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	var user models.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		c.lru.Delete(key)
+		return nil, false
+	}
+	return &user, true
+}
+
+// store lưu user vừa lấy từ X API (lookup theo username, theo ID, ListUsers, GetMe).
+// Nếu ID đã được cache với username khác (user đổi handle) thì ánh xạ username cũ
+// bị xóa để handle đó không còn trỏ tới user ID cũ.
+func (c *userCache) store(user *models.User) {
+	if !c.enabled() || user == nil || user.ID == "" || user.Username == "" {
+		return
+	}
+
+	name := normalizeUsername(user.Username)
+	if previous, ok := c.lru.Get("id:" + user.ID); ok {
+		if oldName := string(previous); oldName != name {
+			c.lru.Delete("username:" + oldName)
+			log.WithFields(log.Fields{
+				"user_id":      user.ID,
+				"old_username": oldName,
+				"new_username": user.Username,
+			}).Info("Phát hiện user đổi username, đã xóa ánh xạ cũ khỏi cache")
+		}
+	}
+
+	c.lru.Set("username:"+name, []byte(user.ID), c.ttl)
+	c.lru.Set("id:"+user.ID, []byte(name), c.ttl)
+
+	if c.profileTTL <= 0 {
+		return
+	}
+	profile, err := json.Marshal(user)
+	if err != nil {
+		log.WithError(err).Warn("Không thể encode user để lưu cache")
+		return
+	}
+	c.lru.Set("profile:"+user.ID, profile, c.profileTTL)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"x-twitter-backend/config"
+	"x-twitter-backend/mockserver"
+	"x-twitter-backend/models"
+)
+
+func newTestUserCache(ttl, profileTTL time.Duration, now *time.Time) *userCache {
+	cache := newUserCache(100, ttl, profileTTL)
+	cache.lru.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestUserCacheProfileTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestUserCache(time.Hour, 5*time.Minute, &now)
+	cache.store(&models.User{ID: "1", Username: "Alice", Name: "Alice", Description: "bio", Metrics: &models.UserMetrics{FollowersCount: 10}})
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		lookup  string
+		want    bool
+	}{
+		{"profile còn hạn, không phân biệt hoa thường", 0, "alice", true},
+		{"sát profileTTL", 5*time.Minute - time.Second, "ALICE", true},
+		// Ánh xạ username -> ID còn hạn nhưng không được trả về user thiếu profile
+		{"profile hết hạn", time.Second, "alice", false},
+		{"ánh xạ hết hạn", time.Hour, "alice", false},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		user, ok := cache.byUsername(tt.lookup)
+		if ok != tt.want {
+			t.Fatalf("%s: byUsername ok = %v, want %v", tt.name, ok, tt.want)
+		}
+		if ok && (user.Description != "bio" || user.Metrics == nil || user.Metrics.FollowersCount != 10) {
+			t.Errorf("%s: user = %+v, want profile đầy đủ", tt.name, user)
+		}
+		if _, ok := cache.byID("1"); ok != tt.want {
+			t.Errorf("%s: byID ok = %v, want %v", tt.name, ok, tt.want)
+		}
+	}
+}
+
+func TestUserCacheRenameInvalidatesOldUsername(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestUserCache(time.Hour, 5*time.Minute, &now)
+	cache.store(&models.User{ID: "1", Username: "alice", Name: "Alice"})
+
+	// Lookup theo ID cho thấy user 1 đã đổi handle
+	cache.store(&models.User{ID: "1", Username: "alice_new", Name: "Alice"})
+	if user, ok := cache.byUsername("alice"); ok {
+		t.Errorf("handle cũ vẫn trỏ tới %+v", user)
+	}
+	if user, ok := cache.byUsername("Alice_New"); !ok || user.ID != "1" {
+		t.Errorf("handle mới = %+v, %v; want user 1", user, ok)
+	}
+
+	// Handle cũ được user khác lấy lại
+	cache.store(&models.User{ID: "2", Username: "alice", Name: "Another Alice"})
+	if user, ok := cache.byUsername("alice"); !ok || user.ID != "2" {
+		t.Errorf("handle cũ = %+v, %v; want user 2", user, ok)
+	}
+	if user, ok := cache.byUsername("alice_new"); !ok || user.ID != "1" {
+		t.Errorf("handle mới = %+v, %v; want user 1", user, ok)
+	}
+}
+
+func TestResolveUserRefetchesStaleProfile(t *testing.T) {
+	fixtures, err := mockserver.LoadFixtures("../mockserver/fixtures")
+	if err != nil {
+		t.Fatalf("không load được fixtures: %v", err)
+	}
+	server := httptest.NewServer(mockserver.New(fixtures, mockserver.Options{BearerToken: testBearerToken}))
+	t.Cleanup(server.Close)
+
+	svc, err := NewTwitterService(&config.Config{
+		TwitterBearerToken:      testBearerToken,
+		TwitterAPIBaseURL:       server.URL,
+		TwitterHTTPMode:         "live",
+		TwitterRetryMaxAttempts: 1,
+		MaxTweetsPerRequest:     100,
+		DefaultTweetsCount:      10,
+		UserCacheMaxEntries:     100,
+		UserCacheTTL:            24 * time.Hour,
+		CacheTTLProfile:         5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTwitterService: %v", err)
+	}
+	now := time.Now()
+	svc.users.lru.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	requests := func() int64 {
+		return tokenStatuses(t, svc)[0].Requests
+	}
+	getTweets := func() {
+		t.Helper()
+		resp, err := svc.GetUserTweets(ctx, "alice_dev", 10, "", TweetFilter{})
+		if err != nil {
+			t.Fatalf("GetUserTweets: %v", err)
+		}
+		if resp.User == nil || resp.User.ID != testAliceID || resp.User.Metrics == nil || resp.User.Description == "" {
+			t.Fatalf("User = %+v, want profile đầy đủ của alice_dev", resp.User)
+		}
+	}
+
+	getTweets()
+	if got := requests(); got != 2 {
+		t.Fatalf("lần đầu: %d requests, want 2 (lookup + timeline)", got)
+	}
+	getTweets()
+	if got := requests(); got != 3 {
+		t.Fatalf("profile còn hạn: %d requests, want 3 (chỉ timeline)", got)
+	}
+
+	// Profile hết CACHE_TTL_PROFILE: lấy lại profile thay vì trả về user thiếu field
+	now = now.Add(5 * time.Minute)
+	getTweets()
+	if got := requests(); got != 5 {
+		t.Errorf("profile hết hạn: %d requests, want 5 (lookup + timeline)", got)
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"x-twitter-backend/config"
+
+	"github.com/michimani/gotwi"
+	"github.com/michimani/gotwi/fields"
+	"github.com/michimani/gotwi/resources"
+)
+
+// repostsOfMeEndpoint chưa có trong gotwi nên được gọi qua gotwi.Client.CallAPI
+const repostsOfMeEndpoint = defaultTwitterAPIBaseURL + "/2/users/reposts_of_me"
+
+// newUserContextClient tạo gotwi.Client ký request bằng OAuth 1.0a user context.
+// Không dùng gotwi.NewClient vì hàm đó đọc consumer key/secret từ env GOTWI_API_KEY*.
+// httpClient dùng chung với client Bearer Token nên rate limit, retry, circuit
+// breaker và cassettes áp dụng cho cả hai.
+func newUserContextClient(cfg *config.Config, httpClient *http.Client) *gotwi.Client {
+	client := &gotwi.Client{Client: httpClient}
+	client.SetAuthenticationMethod(gotwi.AuthenMethodOAuth1UserContext)
+	client.SetOAuthConsumerKey(cfg.TwitterConsumerKey)
+	client.SetOAuthToken(cfg.TwitterAccessToken)
+	client.SetSigningKey(url.QueryEscape(cfg.TwitterConsumerSecret) + "&" + url.QueryEscape(cfg.TwitterAccessTokenSecret))
+	return client
+}
+
+// UserToken là OAuth 2.0 access token của end user đã đăng nhập (/auth/login)
+type UserToken struct {
+	AccessToken string
+	// UserID rỗng khi chưa biết user (ngay sau khi đổi code lấy token)
+	UserID string
+}
+
+type userTokenKey struct{}
+
+// WithUserToken gắn token của end user vào context: TwitterService gọi X API bằng
+// token này thay cho Bearer Token của app, cache và coalescing tách riêng theo user
+func WithUserToken(ctx context.Context, token UserToken) context.Context {
+	return context.WithValue(ctx, userTokenKey{}, token)
+}
+
+func userTokenFromContext(ctx context.Context) (UserToken, bool) {
+	token, ok := ctx.Value(userTokenKey{}).(UserToken)
+	return token, ok && token.AccessToken != ""
+}
+
+// scope là tiền tố của cache/coalesce key cho response của user này. Khi chưa
+// biết user ID thì dùng hash của token, không đưa token vào key.
+func (t UserToken) scope() string {
+	if t.UserID != "" {
+		return "user:" + t.UserID
+	}
+	sum := sha256.Sum256([]byte(t.AccessToken))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// userScopedKey thêm phạm vi user vào key nếu request chạy bằng token của end user
+func userScopedKey(ctx context.Context, key string) string {
+	if token, ok := userTokenFromContext(ctx); ok {
+		return token.scope() + "|" + key
+	}
+	return key
+}
+
+// clientFor trả về client Bearer bằng token của end user nếu context có, nếu không
+// thì client của Bearer Token còn nhiều quota nhất cho endpoint family (ví dụ
+// "GET /2/users/{id}/tweets"). Client theo user dùng chung httpClient nên rate
+// limit, retry, circuit breaker và cassettes vẫn áp dụng.
+func (s *TwitterService) clientFor(ctx context.Context, family string) *gotwi.Client {
+	token, ok := userTokenFromContext(ctx)
+	if !ok {
+		return s.tokens.client(family)
+	}
+	client, err := gotwi.NewClientWithAccessToken(&gotwi.NewClientWithAccessTokenInput{
+		HTTPClient:  s.httpClient,
+		AccessToken: token.AccessToken,
+	})
+	if err != nil {
+		// Chỉ xảy ra khi token rỗng, đã được loại trừ ở userTokenFromContext
+		return s.tokens.client(family)
+	}
+	return client
+}
+
+// userContextClient trả về client có user context: token OAuth 2.0 của end user
+// nếu request có session, nếu không thì client OAuth 1.0a; lỗi USER_CONTEXT_REQUIRED
+// (không gọi X API) khi không có cả hai
+func (s *TwitterService) userContextClient(ctx context.Context, api string) (*gotwi.Client, error) {
+	if _, ok := userTokenFromContext(ctx); ok {
+		return s.clientFor(ctx, ""), nil
+	}
+	if s.userClient == nil {
+		return nil, newUserContextRequiredError(fmt.Sprintf("API %s yêu cầu user context: đăng nhập qua /auth/login hoặc cấu hình TWITTER_CONSUMER_KEY, TWITTER_CONSUMER_SECRET, TWITTER_ACCESS_TOKEN và TWITTER_ACCESS_TOKEN_SECRET", api))
+	}
+	return s.userClient, nil
+}
+
+// repostsOfMeInput là parameters của GET /2/users/reposts_of_me
+type repostsOfMeInput struct {
+	accessToken string
+
+	MaxResults      int
+	PaginationToken string
+	Expansions      fields.ExpansionList
+	TweetFields     fields.TweetFieldList
+	UserFields      fields.UserFieldList
+}
+
+func (p *repostsOfMeInput) SetAccessToken(token string) {
+	p.accessToken = token
+}
+
+func (p *repostsOfMeInput) AccessToken() string {
+	return p.accessToken
+}
+
+func (p *repostsOfMeInput) ResolveEndpoint(endpointBase string) string {
+	query := url.Values{}
+	for key, value := range p.ParameterMap() {
+		query.Set(key, value)
+	}
+	if len(query) == 0 {
+		return endpointBase
+	}
+	return endpointBase + "?" + query.Encode()
+}
+
+func (p *repostsOfMeInput) Body() (io.Reader, error) {
+	return nil, nil
+}
+
+// ParameterMap trả về query parameters, cũng được dùng để ký OAuth 1.0a
+func (p *repostsOfMeInput) ParameterMap() map[string]string {
+	m := map[string]string{}
+	if p.MaxResults > 0 {
+		m["max_results"] = fmt.Sprint(p.MaxResults)
+	}
+	if p.PaginationToken != "" {
+		m["pagination_token"] = p.PaginationToken
+	}
+	return fields.SetFieldsParams(m, p.Expansions, p.TweetFields, p.UserFields)
+}
+
+// repostsOfMeOutput là response của GET /2/users/reposts_of_me
+type repostsOfMeOutput struct {
+	Data     []resources.Tweet `json:"data"`
+	Includes struct {
+		Users []resources.User `json:"users,omitempty"`
+	} `json:"includes,omitempty"`
+	Meta   resources.PaginationMeta `json:"meta"`
+	Errors []resources.PartialError `json:"errors"`
+}
+
+func (r *repostsOfMeOutput) HasPartialError() bool {
+	return len(r.Errors) > 0
+}